@@ -10,9 +10,7 @@ import (
 	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
 	"html/template"
 	"log"
-	"math"
 	"net/http"
-	"reflect"
 	"sort"
 	"strconv"
 	"strings"
@@ -28,13 +26,57 @@ func init() {
 
 // httpUserTasks reports all tasks found in the trace.
 func httpUserTasks(w http.ResponseWriter, r *http.Request) {
-	res, err := analyzeAnnotations()
+	st := requestTrace(r)
+	res, err := st.analyzeAnnotations()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	tasks := res.tasks
+	stats := summarizeTasks(tasksDuring(res.tasks, win))
+	if serveExport(w, r, "usertasks", func() interface{} { return exportTaskTypes(stats) }) {
+		return
+	}
+
+	// Emit table.
+	err = templUserTaskTypes.Execute(w, stats)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+func httpUserRegions(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	res, err := st.analyzeAnnotations()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stats := summarizeRegions(regionsDuring(res.regions, win))
+	if serveExport(w, r, "userregions", func() interface{} { return exportRegionTypes(stats) }) {
+		return
+	}
+	// Emit table.
+	err = templUserRegionTypes.Execute(w, stats)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// summarizeTasks returns the statistics of every task type, sorted by type.
+func summarizeTasks(tasks allTasks) []taskStats {
 	summary := make(map[string]taskStats)
 	for _, task := range tasks {
 		stats, ok := summary[task.name]
@@ -54,23 +96,12 @@ func httpUserTasks(w http.ResponseWriter, r *http.Request) {
 	sort.Slice(userTasks, func(i, j int) bool {
 		return userTasks[i].Type < userTasks[j].Type
 	})
-
-	// Emit table.
-	err = templUserTaskTypes.Execute(w, userTasks)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
-		return
-	}
+	return userTasks
 }
 
-func httpUserRegions(w http.ResponseWriter, r *http.Request) {
-	res, err := analyzeAnnotations()
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-	allRegions := res.regions
-
+// summarizeRegions returns the statistics of every region type, sorted
+// by type and PC.
+func summarizeRegions(allRegions map[regionTypeID][]regionDesc) []regionStats {
 	summary := make(map[regionTypeID]regionStats)
 	for id, regions := range allRegions {
 		stats, ok := summary[id]
@@ -93,21 +124,17 @@ func httpUserRegions(w http.ResponseWriter, r *http.Request) {
 		}
 		return userRegions[i].Frame.PC < userRegions[j].Frame.PC
 	})
-	// Emit table.
-	err = templUserRegionTypes.Execute(w, userRegions)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
-		return
-	}
+	return userRegions
 }
 
 func httpUserRegion(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
 	filter, err := newRegionFilter(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	res, err := analyzeAnnotations()
+	res, err := st.analyzeAnnotations()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -130,18 +157,20 @@ func httpUserRegion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	sortby := r.FormValue("sortby")
-	_, ok := reflect.TypeOf(regionDesc{}).FieldByNameFunc(func(s string) bool {
-		return s == sortby
-	})
-	if !ok {
-		sortby = "TotalTime"
-	}
 	sort.Slice(data, func(i, j int) bool {
-		ival := reflect.ValueOf(data[i]).FieldByName(sortby).Int()
-		jval := reflect.ValueOf(data[j]).FieldByName(sortby).Int()
-		return ival > jval
+		return regionStat(data[i], sortby).Total > regionStat(data[j], sortby).Total
 	})
 
+	if serveExport(w, r, "userregion", func() interface{} {
+		list := make([]exportRegion, len(data))
+		for i, s := range data {
+			list[i] = newExportRegion(s, st.firstTimestamp())
+		}
+		return list
+	}) {
+		return
+	}
+
 	err = templUserRegionType.Execute(w, struct {
 		MaxTotal int64
 		Data     []regionDesc
@@ -157,15 +186,40 @@ func httpUserRegion(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// regionStat returns the statistic of s the regions are sorted by, the
+// total time unless sortby names another.
+func regionStat(s regionDesc, sortby string) *trace.GExecutionStatEntry {
+	switch sortby {
+	case "ExecTime":
+		return &s.ExecTime
+	case "IOTime":
+		return &s.IOTime
+	case "BlockTime":
+		return &s.BlockTime
+	case "SyscallTime":
+		return &s.SyscallTime
+	case "SchedWaitTime":
+		return &s.SchedWaitTime
+	case "SweepTime":
+		return &s.SweepTime
+	case "GCTime":
+		return &s.GCTime
+	case "MarkAssistTime":
+		return &s.MarkAssistTime
+	}
+	return &s.TotalTime
+}
+
 // httpUserTask presents the details of the selected tasks.
 func httpUserTask(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
 	filter, err := newTaskFilter(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	res, err := analyzeAnnotations()
+	res, err := st.analyzeAnnotations()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -187,9 +241,20 @@ func httpUserTask(w http.ResponseWriter, r *http.Request) {
 		Events     []event
 		Start, End time.Duration // Time since the beginning of the trace
 		GCTime     time.Duration
+		CritPath   *critPath // nil for incomplete tasks
+	}
+
+	base := time.Duration(st.firstTimestamp()) * time.Nanosecond // trace start
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	st.analyzeCritPaths(events)
 
-	base := time.Duration(firstTimestamp()) * time.Nanosecond // trace start
+	if serveExport(w, r, "usertask", func() interface{} { return st.exportTasks(tasks, filter, res.gcEvents) }) {
+		return
+	}
 
 	var data []entry
 
@@ -215,7 +280,7 @@ func httpUserTask(w http.ResponseWriter, r *http.Request) {
 				elapsed = 0
 			}
 
-			what := describeEvent(ev)
+			what := st.describeEvent(ev)
 			if what != "" {
 				events = append(events, event{
 					WhenString: fmt.Sprintf("%2.9f", when.Seconds()),
@@ -236,6 +301,7 @@ func httpUserTask(w http.ResponseWriter, r *http.Request) {
 			Start:      time.Duration(task.firstTimestamp()) * time.Nanosecond,
 			End:        time.Duration(task.endTimestamp()) * time.Nanosecond,
 			GCTime:     task.overlappingGCDuration(res.gcEvents),
+			CritPath:   st.critIndex.taskCritPath(task),
 		})
 	}
 	sort.Slice(data, func(i, j int) bool {
@@ -270,8 +336,8 @@ type regionTypeID struct {
 
 // analyzeAnnotations analyzes user annotation events and
 // returns the task descriptors keyed by internal task id.
-func analyzeAnnotations() (annotationAnalysisResult, error) {
-	res, err := parseTrace()
+func (st *traceState) analyzeAnnotations() (annotationAnalysisResult, error) {
+	res, err := st.parseTrace()
 	if err != nil {
 		return annotationAnalysisResult{}, fmt.Errorf("failed to parse trace: %v", err)
 	}
@@ -280,10 +346,17 @@ func analyzeAnnotations() (annotationAnalysisResult, error) {
 	if len(events) == 0 {
 		return annotationAnalysisResult{}, fmt.Errorf("empty trace")
 	}
+	st.analyzeGoroutines(events)
+	return analyzeAnnotationsOf(events, st.gs), nil
+}
 
+// analyzeAnnotationsOf is analyzeAnnotations for the events of any
+// trace, given the goroutine statistics computed from the events.
+func analyzeAnnotationsOf(events []*trace.Event, gs map[uint64]*trace.GDesc) annotationAnalysisResult {
 	tasks := allTasks{}
 	regions := map[regionTypeID][]regionDesc{}
 	var gcEvents []*trace.Event
+	bounds := &traceBounds{events[0].Ts, events[len(events)-1].Ts}
 
 	for _, ev := range events {
 		switch typ := ev.Type; typ {
@@ -308,28 +381,25 @@ func analyzeAnnotations() (annotationAnalysisResult, error) {
 		}
 	}
 	// combine region info.
-	analyzeGoroutines(events)
 	for goid, stats := range gs {
-		// gs is a global var defined in goroutines.go as a result
-		// of analyzeGoroutines. TODO(hyangah): fix this not to depend
-		// on a 'global' var.
 		for _, s := range stats.Regions {
 			if s.TaskID != 0 {
 				task := tasks.task(s.TaskID)
 				task.goroutines[goid] = struct{}{}
-				task.regions = append(task.regions, regionDesc{UserRegionDesc: s, G: goid})
+				task.regions = append(task.regions, regionDesc{UserRegionDesc: s, G: goid, bounds: bounds})
 			}
 			var frame trace.Frame
 			if s.Start != nil {
 				frame = *s.Start.Stk[0]
 			}
 			id := regionTypeID{Frame: frame, Type: s.Name}
-			regions[id] = append(regions[id], regionDesc{UserRegionDesc: s, G: goid})
+			regions[id] = append(regions[id], regionDesc{UserRegionDesc: s, G: goid, bounds: bounds})
 		}
 	}
 
 	// sort regions in tasks based on the timestamps.
 	for _, task := range tasks {
+		task.bounds = bounds
 		sort.SliceStable(task.regions, func(i, j int) bool {
 			si, sj := task.regions[i].firstTimestamp(), task.regions[j].firstTimestamp()
 			if si != sj {
@@ -338,7 +408,7 @@ func analyzeAnnotations() (annotationAnalysisResult, error) {
 			return task.regions[i].lastTimestamp() < task.regions[j].lastTimestamp()
 		})
 	}
-	return annotationAnalysisResult{tasks: tasks, regions: regions, gcEvents: gcEvents}, nil
+	return annotationAnalysisResult{tasks: tasks, regions: regions, gcEvents: gcEvents}
 }
 
 // taskDesc represents a task.
@@ -354,6 +424,15 @@ type taskDesc struct {
 
 	parent   *taskDesc
 	children []*taskDesc
+
+	bounds *traceBounds // for the missing create and end events
+}
+
+// traceBounds are the timestamps of the first and last events of a
+// trace, where the tasks and regions without start or end events in it
+// begin or end.
+type traceBounds struct {
+	first, last int64
 }
 
 func newTaskDesc(id uint64) *taskDesc {
@@ -389,7 +468,8 @@ func (task *taskDesc) String() string {
 // regionDesc represents a region.
 type regionDesc struct {
 	*trace.UserRegionDesc
-	G uint64 // id of goroutine where the region was defined
+	G      uint64       // id of goroutine where the region was defined
+	bounds *traceBounds // for the missing start and end events
 }
 
 type allTasks map[uint64]*taskDesc
@@ -412,6 +492,37 @@ func (tasks allTasks) task(taskID uint64) *taskDesc {
 	return t
 }
 
+// tasksDuring returns the tasks that started in the window w.
+func tasksDuring(tasks allTasks, w traceWindow) allTasks {
+	if w == wholeTrace {
+		return tasks
+	}
+	res := make(allTasks)
+	for id, t := range tasks {
+		if w.contains(t.firstTimestamp()) {
+			res[id] = t
+		}
+	}
+	return res
+}
+
+// regionsDuring returns the regions of allRegions that started in the
+// window w.
+func regionsDuring(allRegions map[regionTypeID][]regionDesc, w traceWindow) map[regionTypeID][]regionDesc {
+	if w == wholeTrace {
+		return allRegions
+	}
+	res := make(map[regionTypeID][]regionDesc)
+	for id, regions := range allRegions {
+		for _, s := range regions {
+			if w.contains(s.firstTimestamp()) {
+				res[id] = append(res[id], s)
+			}
+		}
+	}
+	return res
+}
+
 func (task *taskDesc) addEvent(ev *trace.Event) {
 	if task == nil {
 		return
@@ -460,7 +571,7 @@ func (task *taskDesc) firstTimestamp() int64 {
 	if task != nil && task.create != nil {
 		return task.create.Ts
 	}
-	return firstTimestamp()
+	return task.bounds.first
 }
 
 // lastTimestamp returns the last timestamp of this task in this
@@ -481,7 +592,7 @@ func (task *taskDesc) endTimestamp() int64 {
 	if task != nil && task.end != nil {
 		return task.end.Ts
 	}
-	return lastTimestamp()
+	return task.bounds.last
 }
 
 func (task *taskDesc) duration() time.Duration {
@@ -544,7 +655,7 @@ func (task *taskDesc) overlappingInstant(ev *trace.Event) bool {
 // as well.
 func (task *taskDesc) overlappingDuration(ev *trace.Event) (time.Duration, bool) {
 	start := ev.Ts
-	end := lastTimestamp()
+	end := task.bounds.last
 	if ev.Link != nil {
 		end = ev.Link.Ts
 	}
@@ -624,7 +735,7 @@ func (region *regionDesc) firstTimestamp() int64 {
 	if region.Start != nil {
 		return region.Start.Ts
 	}
-	return firstTimestamp()
+	return region.bounds.first
 }
 
 // lastTimestamp returns the timestamp of region end event.
@@ -634,7 +745,7 @@ func (region *regionDesc) lastTimestamp() int64 {
 	if region.End != nil {
 		return region.End.Ts
 	}
-	return lastTimestamp()
+	return region.bounds.last
 }
 
 // RelatedGoroutines returns IDs of goroutines related to the task. A goroutine
@@ -686,6 +797,7 @@ func (f *taskFilter) match(t *taskDesc) bool {
 }
 
 func newTaskFilter(r *http.Request) (*taskFilter, error) {
+	st := requestTrace(r)
 	if err := r.ParseForm(); err != nil {
 		return nil, err
 	}
@@ -729,6 +841,16 @@ func newTaskFilter(r *http.Request) (*taskFilter, error) {
 			return taskMatches(t, text)
 		})
 	}
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, err
+	}
+	if win != wholeTrace {
+		name = append(name, fmt.Sprintf("started in %s", st.formatWindow(win)))
+		conditions = append(conditions, func(t *taskDesc) bool {
+			return win.contains(t.firstTimestamp())
+		})
+	}
 
 	return &taskFilter{name: strings.Join(name, ","), cond: conditions}, nil
 }
@@ -762,6 +884,7 @@ func (f *regionFilter) match(id regionTypeID, s regionDesc) bool {
 }
 
 func newRegionFilter(r *http.Request) (*regionFilter, error) {
+	st := requestTrace(r)
 	if err := r.ParseForm(); err != nil {
 		return nil, err
 	}
@@ -795,40 +918,34 @@ func newRegionFilter(r *http.Request) (*regionFilter, error) {
 			return s.duration() <= lat
 		})
 	}
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, err
+	}
+	if win != wholeTrace {
+		name = append(name, fmt.Sprintf("started in %s", st.formatWindow(win)))
+		conditions = append(conditions, func(_ regionTypeID, s regionDesc) bool {
+			return win.contains(s.firstTimestamp())
+		})
+	}
 
 	return &regionFilter{name: strings.Join(name, ","), cond: conditions}, nil
 }
 
+// durationHistogram is the log-scale histogram of the region and task
+// durations, with the buckets of the goroutine statistics.
 type durationHistogram struct {
-	Count                int
-	Buckets              []int
-	MinBucket, MaxBucket int
+	Count int
+	trace.Histogram
 }
 
-// Five buckets for every power of 10.
-var logDiv = math.Log(math.Pow(10, 1.0/5))
-
 func (h *durationHistogram) add(d time.Duration) {
-	var bucket int
-	if d > 0 {
-		bucket = int(math.Log(float64(d)) / logDiv)
-	}
-	if len(h.Buckets) <= bucket {
-		h.Buckets = append(h.Buckets, make([]int, bucket-len(h.Buckets)+1)...)
-		h.Buckets = h.Buckets[:cap(h.Buckets)]
-	}
-	h.Buckets[bucket]++
-	if bucket < h.MinBucket || h.MaxBucket == 0 {
-		h.MinBucket = bucket
-	}
-	if bucket > h.MaxBucket {
-		h.MaxBucket = bucket
-	}
+	h.Histogram.Add(int64(d))
 	h.Count++
 }
 
 func (h *durationHistogram) BucketMin(bucket int) time.Duration {
-	return time.Duration(math.Exp(float64(bucket) * logDiv))
+	return time.Duration(h.Histogram.BucketMin(bucket))
 }
 
 func niceDuration(d time.Duration) string {
@@ -854,7 +971,8 @@ func (h *durationHistogram) ToHTML(urlmaker func(min, max time.Duration) string)
 
 	const barWidth = 400
 
-	maxCount := 0
+	minBucket, maxBucket := h.Span()
+	var maxCount int64
 	for _, count := range h.Buckets {
 		if count > maxCount {
 			maxCount = count
@@ -863,7 +981,7 @@ func (h *durationHistogram) ToHTML(urlmaker func(min, max time.Duration) string)
 
 	w := new(bytes.Buffer)
 	fmt.Fprintf(w, `<table>`)
-	for i := h.MinBucket; i <= h.MaxBucket; i++ {
+	for i := minBucket; i <= maxBucket; i++ {
 		// Tick label.
 		if h.Buckets[i] > 0 {
 			fmt.Fprintf(w, `<tr><td class="histoTime" align="right"><a href=%s>%s</a></td>`, urlmaker(h.BucketMin(i), h.BucketMin(i+1)), niceDuration(h.BucketMin(i)))
@@ -879,7 +997,7 @@ func (h *durationHistogram) ToHTML(urlmaker func(min, max time.Duration) string)
 
 	}
 	// Final tick label.
-	fmt.Fprintf(w, `<tr><td align="right">%s</td></tr>`, niceDuration(h.BucketMin(h.MaxBucket+1)))
+	fmt.Fprintf(w, `<tr><td align="right">%s</td></tr>`, niceDuration(h.BucketMin(maxBucket+1)))
 	fmt.Fprintf(w, `</table>`)
 	return template.HTML(w.String())
 }
@@ -887,10 +1005,11 @@ func (h *durationHistogram) ToHTML(urlmaker func(min, max time.Duration) string)
 func (h *durationHistogram) String() string {
 	const barWidth = 40
 
+	minBucket, maxBucket := h.Span()
 	labels := []string{}
 	maxLabel := 0
-	maxCount := 0
-	for i := h.MinBucket; i <= h.MaxBucket; i++ {
+	var maxCount int64
+	for i := minBucket; i <= maxBucket; i++ {
 		// TODO: This formatting is pretty awful.
 		label := fmt.Sprintf("[%-12s%-11s)", h.BucketMin(i).String()+",", h.BucketMin(i+1))
 		labels = append(labels, label)
@@ -904,10 +1023,10 @@ func (h *durationHistogram) String() string {
 	}
 
 	w := new(bytes.Buffer)
-	for i := h.MinBucket; i <= h.MaxBucket; i++ {
+	for i := minBucket; i <= maxBucket; i++ {
 		count := h.Buckets[i]
-		bar := count * barWidth / maxCount
-		fmt.Fprintf(w, "%*s %-*s %d\n", maxLabel, labels[i-h.MinBucket], barWidth, strings.Repeat("█", bar), count)
+		bar := int(count * barWidth / maxCount)
+		fmt.Fprintf(w, "%*s %-*s %d\n", maxLabel, labels[i-minBucket], barWidth, strings.Repeat("█", bar), count)
 	}
 	return w.String()
 }
@@ -915,6 +1034,7 @@ func (h *durationHistogram) String() string {
 type regionStats struct {
 	regionTypeID
 	Histogram durationHistogram
+	durations []time.Duration // Complete regions only
 }
 
 func (s *regionStats) UserRegionURL() func(min, max time.Duration) string {
@@ -925,6 +1045,9 @@ func (s *regionStats) UserRegionURL() func(min, max time.Duration) string {
 
 func (s *regionStats) add(region regionDesc) {
 	s.Histogram.add(region.duration())
+	if region.Start != nil && region.End != nil {
+		s.durations = append(s.durations, region.duration())
+	}
 }
 
 var templUserRegionTypes = template.Must(template.New("").Parse(`
@@ -951,6 +1074,7 @@ var templUserRegionTypes = template.Must(template.New("").Parse(`
   </tr>
 {{end}}
 </table>
+<script>` + windowScript + `</script>
 </body>
 </html>
 `))
@@ -959,6 +1083,7 @@ type taskStats struct {
 	Type      string
 	Count     int               // Complete + incomplete tasks
 	Histogram durationHistogram // Complete tasks only
+	durations []time.Duration   // Complete tasks only
 }
 
 func (s *taskStats) UserTaskURL(complete bool) func(min, max time.Duration) string {
@@ -971,6 +1096,7 @@ func (s *taskStats) add(task *taskDesc) {
 	s.Count++
 	if task.complete() {
 		s.Histogram.add(task.duration())
+		s.durations = append(s.durations, task.duration())
 	}
 }
 
@@ -999,6 +1125,7 @@ Search log text: <form action="/usertask"><input name="logtext" type="text"><inp
   </tr>
 {{end}}
 </table>
+<script>` + windowScript + `</script>
 </body>
 </html>
 `))
@@ -1007,6 +1134,12 @@ var templUserTaskType = template.Must(template.New("userTask").Funcs(template.Fu
 	"elapsed":       elapsed,
 	"asMillisecond": asMillisecond,
 	"trimSpace":     strings.TrimSpace,
+	"percent": func(d, total time.Duration) string {
+		if total == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.1f%%", float64(d)/float64(total)*100)
+	},
 }).Parse(`
 <html>
 <head> <title>User Task: {{.Name}} </title> </head>
@@ -1046,6 +1179,25 @@ var templUserTaskType = template.Must(template.New("userTask").Funcs(template.Fu
                         font-size: smaller;
                         margin-top: 5em;
                 }
+                .critpath {
+                        display: inline-block;
+                        width: 300px;
+                        height: 10px;
+                        white-space: nowrap;
+                        vertical-align: middle;
+                }
+                .critpath span {
+                        display: inline-block;
+                        height: 100%;
+                }
+                .crit0 { background-color: #d7191c; }
+                .crit1 { background-color: #2c7bb6; }
+                .crit2 { background-color: #fdae61; }
+                .crit3 { background-color: #d01c8b; }
+                .crit4 { background-color: #7b3294; }
+                .crit5 { background-color: #b8860b; }
+                .crit6 { background-color: #00b4b4; }
+                .crit7 { background-color: #636363; }
         </style>
 <body>
 
@@ -1077,7 +1229,19 @@ Search log text: <form onsubmit="window.location.search+='&logtext='+window.logt
 		<td></td>
 		<td></td>
 		<td>GC:{{$el.GCTime}}</td>
+	</tr>
+	{{with $el.CritPath}}
+	<tr>
+		<td></td>
+		<td></td>
+		<td></td>
+		<td><a href="/trace?taskid={{$el.ID}}&critpath=1#{{asMillisecond $el.Start}}:{{asMillisecond $el.End}}">Critical path</a>:
+		<div class="critpath">{{range .Breakdown}}<span class="crit{{printf "%d" .Kind}}" style="width:{{percent .Duration $el.Duration}}" title="{{.Kind}}"></span>{{end}}</div>
+		{{range .Breakdown}}{{.Kind}}:{{.Duration}} ({{percent .Duration $el.Duration}}) {{end}}</td>
+	</tr>
+	{{end}}
     {{end}}
+<script>` + windowScript + `</script>
 </body>
 </html>
 `))
@@ -1119,11 +1283,11 @@ func formatUserLog(ev *trace.Event) string {
 	return fmt.Sprintf("%v=%v", k, v)
 }
 
-func describeEvent(ev *trace.Event) string {
+func (st *traceState) describeEvent(ev *trace.Event) string {
 	switch ev.Type {
 	case trace.EvGoCreate:
 		goid := ev.Args[0]
-		return fmt.Sprintf("new goroutine %d: %s", goid, gs[goid].Name)
+		return fmt.Sprintf("new goroutine %d: %s", goid, st.gs[goid].Name)
 	case trace.EvGoEnd, trace.EvGoStop:
 		return "goroutine stopped"
 	case trace.EvUserLog:
@@ -1237,6 +1401,7 @@ function reloadTable(key, value) {
   params.set(key, value);
   window.location.search = params.toString();
 }
+` + windowScript + `
 </script>
 
 <h2>{{.Name}}</h2>
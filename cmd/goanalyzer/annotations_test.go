@@ -101,7 +101,7 @@ func TestAnalyzeAnnotations(t *testing.T) {
 		t.Fatalf("failed to trace the program: %v", err)
 	}
 
-	res, err := analyzeAnnotations()
+	res, err := mainTrace.analyzeAnnotations()
 	if err != nil {
 		t.Fatalf("failed to analyzeAnnotations: %v", err)
 	}
@@ -183,7 +183,7 @@ func TestAnalyzeAnnotationTaskTree(t *testing.T) {
 		t.Fatalf("failed to trace the program: %v", err)
 	}
 
-	res, err := analyzeAnnotations()
+	res, err := mainTrace.analyzeAnnotations()
 	if err != nil {
 		t.Fatalf("failed to analyzeAnnotations: %v", err)
 	}
@@ -271,7 +271,7 @@ func TestAnalyzeAnnotationGC(t *testing.T) {
 		t.Fatalf("failed to trace the program: %v", err)
 	}
 
-	res, err := analyzeAnnotations()
+	res, err := mainTrace.analyzeAnnotations()
 	if err != nil {
 		t.Fatalf("failed to analyzeAnnotations: %v", err)
 	}
@@ -372,14 +372,17 @@ func childrenNames(task *taskDesc) (ret []string) {
 
 func swapLoaderData(res traceparser.ParseResult, err error) {
 	// swap loader's data.
-	parseTrace() // fool loader.once.
+	mainTrace = newTraceState("", "")
+	mainTrace.parseTrace() // fool loader.once.
 
-	loader.res = res
-	loader.err = err
+	mainTrace.loader.res = res
+	mainTrace.loader.err = err
 
-	analyzeGoroutines(nil) // fool gsInit once.
-	gs = traceparser.GoroutineStats(res.Events)
+	mainTrace.analyzeGoroutines(nil) // fool gsInit once.
+	mainTrace.gs = traceparser.GoroutineStats(res.Events)
 
+	mainTrace.analyzeCritPaths(nil) // fool critPathInit once.
+	mainTrace.critIndex = newCritPathIndex(res.Events)
 }
 
 func saveTrace(buf *bytes.Buffer, name string) {
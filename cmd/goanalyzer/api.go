@@ -0,0 +1,479 @@
+// Versioned JSON API.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// apiPrefix is the path of version 1 of the API. The schemas of the
+// responses only change compatibly, by adding fields, within a
+// version. Durations are in nanoseconds and timestamps are nanoseconds
+// since the start of the trace.
+const apiPrefix = "/api/v1/"
+
+func init() {
+	http.HandleFunc(apiPrefix, httpAPI)
+}
+
+// apiEndpoint is an endpoint of the API below apiPrefix.
+type apiEndpoint struct {
+	Path   string
+	Doc    string
+	Params []string `json:",omitempty"`
+	// handle returns the response, or a *profile.Profile written in
+	// pprof format.
+	handle func(r *http.Request) (interface{}, error)
+}
+
+var apiEndpoints = []apiEndpoint{
+	{"summary", "Summary of the trace, as written by -report=json.", nil, apiSummary},
+	{"groups", "Goroutine groups by start PC, with execution statistics, by decreasing execution time.", []string{apiWindowParam}, apiGroups},
+	{"goroutines", "Goroutines with execution statistics, by ID.", []string{"group: start PC of the goroutine group", "id: goroutine ID", apiWindowParam}, apiGoroutines},
+	{"tasks", "User tasks, by duration.", []string{"type", "complete=1", "latmin", "latmax", "logtext", apiWindowParam}, apiTasks},
+	{"tasks/types", "Duration statistics of the user task types.", []string{apiWindowParam}, apiTaskTypes},
+	{"regions", "User regions, by start time.", []string{"type", "pc", "latmin", "latmax", apiWindowParam}, apiRegions},
+	{"regions/types", "Duration statistics of the user region types.", []string{apiWindowParam}, apiRegionTypes},
+	{"gc", "GC cycles, with their stop-the-world pauses.", nil, apiGC},
+	{"events", "Events of a time window, read alone from the index of the trace if it has one.", []string{apiWindowParam, "limit: maximum number of events, 10000 by default"}, apiEventWindow},
+	{"mmu", "Minimum mutator utilization by window size.", []string{"flags: like stw|background|assist, the default", "windows: comma-separated durations", apiWindowParam}, apiMMU},
+	{"profiles/io", "Network blocking profile.", apiProfileParams, apiProfile(pprofIOEvent)},
+	{"profiles/block", "Synchronization blocking profile.", apiProfileParams, apiProfile(pprofBlockEvent)},
+	{"profiles/syscall", "Syscall blocking profile.", apiProfileParams, apiProfile(pprofSyscallEvent)},
+	{"profiles/sched", "Scheduler latency profile.", apiProfileParams, apiProfile(pprofSchedEvent)},
+	{"profiles/assist", "GC mark assist profile.", apiProfileParams, apiProfile(pprofAssistEvent)},
+}
+
+// apiError is an error with the HTTP status of its response.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func apiErrorf(status int, format string, args ...interface{}) error {
+	return &apiError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// httpAPI serves the API. The index lists the endpoints. Errors are
+// responses like {"Error": {"Status": 404, "Message": "..."}}.
+func httpAPI(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, apiPrefix), "/")
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeAPIError(w, apiErrorf(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+		return
+	}
+	if path == "" {
+		writeAPI(w, http.StatusOK, struct{ Endpoints []apiEndpoint }{apiEndpoints})
+		return
+	}
+	for _, e := range apiEndpoints {
+		if e.Path != path {
+			continue
+		}
+		v, err := e.handle(r)
+		if err != nil {
+			writeAPIError(w, err)
+			return
+		}
+		if p, ok := v.(*profile.Profile); ok {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			if err := p.Write(w); err != nil {
+				writeAPIError(w, err)
+			}
+			return
+		}
+		writeAPI(w, http.StatusOK, v)
+		return
+	}
+	writeAPIError(w, apiErrorf(http.StatusNotFound, "unknown endpoint %s", r.URL.Path))
+}
+
+func writeAPI(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeAPIError writes err, with the status of an *apiError and 500
+// otherwise.
+func writeAPIError(w http.ResponseWriter, err error) {
+	e, ok := err.(*apiError)
+	if !ok {
+		e = &apiError{Status: http.StatusInternalServerError, Message: err.Error()}
+	}
+	writeAPI(w, e.Status, struct{ Error *apiError }{e})
+}
+
+// apiEvents returns the events of the non-empty trace.
+func (st *traceState) apiEvents() ([]*trace.Event, error) {
+	events, err := st.parseEvents()
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, errors.New("empty trace")
+	}
+	st.analyzeGoroutines(events)
+	return events, nil
+}
+
+func apiSummary(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	return st.buildReport()
+}
+
+func apiGroups(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	stats, err := st.goroutineStats(win)
+	if err != nil {
+		return nil, err
+	}
+	glist, _ := groupGoroutines(stats)
+	sortGoroutineGroups(glist, "ExecTime")
+	list := make([]exportGroup, len(glist))
+	for i, g := range glist {
+		list[i] = newExportGroup(g)
+	}
+	return list, nil
+}
+
+func apiGoroutines(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	filter := func(name string) (uint64, bool, error) {
+		s := r.FormValue(name)
+		if s == "" {
+			return 0, false, nil
+		}
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return 0, false, apiErrorf(http.StatusBadRequest, "invalid %s parameter %q", name, s)
+		}
+		return v, true, nil
+	}
+	pc, byPC, err := filter("group")
+	if err != nil {
+		return nil, err
+	}
+	id, byID, err := filter("id")
+	if err != nil {
+		return nil, err
+	}
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	stats, err := st.goroutineStats(win)
+	if err != nil {
+		return nil, err
+	}
+	list := []exportGoroutine{}
+	for _, g := range stats {
+		if byPC && g.PC != pc || byID && g.ID != id {
+			continue
+		}
+		list = append(list, newExportGoroutine(g, st.firstTimestamp()))
+	}
+	if byID && len(list) == 0 {
+		return nil, apiErrorf(http.StatusNotFound, "no goroutine %d", id)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+func apiTasks(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	filter, err := newTaskFilter(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	events, err := st.apiEvents()
+	if err != nil {
+		return nil, err
+	}
+	res, err := st.analyzeAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	st.analyzeCritPaths(events)
+	list := st.exportTasks(res.tasks, filter, res.gcEvents)
+	if list == nil {
+		list = []exportTask{}
+	}
+	return list, nil
+}
+
+func apiTaskTypes(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	res, err := st.analyzeAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	list := exportTaskTypes(summarizeTasks(tasksDuring(res.tasks, win)))
+	if list == nil {
+		list = []reportLatency{}
+	}
+	return list, nil
+}
+
+func apiRegions(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	filter, err := newRegionFilter(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	events, err := st.apiEvents()
+	if err != nil {
+		return nil, err
+	}
+	res, err := st.analyzeAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	list := []exportRegion{}
+	for id, regions := range res.regions {
+		for _, s := range regions {
+			if filter.match(id, s) {
+				list = append(list, newExportRegion(s, events[0].Ts))
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Start != list[j].Start {
+			return list[i].Start < list[j].Start
+		}
+		return list[i].G < list[j].G
+	})
+	return list, nil
+}
+
+func apiRegionTypes(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	res, err := st.analyzeAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	list := exportRegionTypes(summarizeRegions(regionsDuring(res.regions, win)))
+	if list == nil {
+		list = []reportLatency{}
+	}
+	return list, nil
+}
+
+func apiGC(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	events, err := st.apiEvents()
+	if err != nil {
+		return nil, err
+	}
+	cycles := gcCyclesDuring(events, win)
+	if cycles == nil {
+		cycles = []*gcCycle{}
+	}
+	return cycles, nil
+}
+
+// apiEventsLimit is the default maximum number of events of the events
+// endpoint.
+const apiEventsLimit = 10000
+
+// apiEvent is an event of the events endpoint.
+type apiEvent struct {
+	Type     string
+	Time     time.Duration
+	P        int
+	G        uint64
+	Args     [3]uint64
+	SArgs    []string      `json:",omitempty"`
+	Stack    []string      `json:",omitempty"` // functions, innermost first
+	Duration time.Duration `json:",omitempty"` // to the linked event
+}
+
+func apiEventWindow(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	limit := apiEventsLimit
+	if s := r.FormValue("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return nil, apiErrorf(http.StatusBadRequest, "invalid limit %q", s)
+		}
+		limit = n
+	}
+	events, err := st.loader.window(win.from, win.to)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	res := []apiEvent{}
+	for _, ev := range events {
+		e := apiEvent{Type: trace.EventDescriptions[ev.Type].Name, Time: time.Duration(ev.Ts), P: ev.P, G: ev.G, Args: ev.Args, SArgs: ev.SArgs}
+		for _, f := range ev.Stk {
+			e.Stack = append(e.Stack, f.Fn)
+		}
+		if ev.Link != nil {
+			e.Duration = time.Duration(ev.Link.Ts - ev.Ts)
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// apiMMUResponse is the response of the mmu endpoint.
+type apiMMUResponse struct {
+	Flags []string
+	MMU   []reportMMU
+}
+
+func apiMMU(r *http.Request) (interface{}, error) {
+	st := requestTrace(r)
+	res := apiMMUResponse{MMU: []reportMMU{}}
+	flags := reportMMUFlags
+	if s := r.FormValue("flags"); s != "" {
+		flags = 0
+		for _, name := range strings.Split(s, "|") {
+			f, ok := utilFlagNames[name]
+			if !ok {
+				return nil, apiErrorf(http.StatusBadRequest, "unknown flag %q", name)
+			}
+			flags |= f
+		}
+	}
+	for name, f := range utilFlagNames {
+		if flags&f != 0 {
+			res.Flags = append(res.Flags, name)
+		}
+	}
+	sort.Strings(res.Flags)
+	windows := reportMMUWindows
+	if s := r.FormValue("windows"); s != "" {
+		windows = nil
+		for _, w := range strings.Split(s, ",") {
+			d, err := time.ParseDuration(w)
+			if err != nil || d <= 0 {
+				return nil, apiErrorf(http.StatusBadRequest, "invalid window %q", w)
+			}
+			windows = append(windows, d)
+		}
+	}
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+	}
+	_, curve, err := st.getMMUCurveWindow(flags, win)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range windows {
+		res.MMU = append(res.MMU, reportMMU{Window: w, MMU: curve.MMU(w)})
+	}
+	return res, nil
+}
+
+// apiWindowParam documents the window parameters of parseWindow.
+const apiWindowParam = "from, to: time window, as timestamps in nanoseconds or durations since the start of the trace (before its end if negative)"
+
+var apiProfileParams = []string{"id: start PC of a goroutine group", "format=pprof: the profile in pprof format", "top: size of the top table", apiWindowParam}
+
+// apiProfileResponse is the response of the profile endpoints: the
+// functions with the largest flat delay and every sampled stack.
+type apiProfileResponse struct {
+	Count   int64
+	Total   time.Duration
+	Top     []profileTopEntry
+	Samples []apiSample // by decreasing delay
+}
+
+type apiSample struct {
+	Stack []string // function names, outermost first
+	Count int64
+	Delay time.Duration
+}
+
+func newAPIProfileResponse(p *profile.Profile, top int) *apiProfileResponse {
+	ci, di := profileValues(p)
+	res := &apiProfileResponse{Top: profileTop(p, top), Samples: []apiSample{}}
+	for _, s := range p.Sample {
+		sample := apiSample{Stack: sampleFuncs(s), Count: s.Value[ci], Delay: time.Duration(s.Value[di])}
+		res.Count += sample.Count
+		res.Total += sample.Delay
+		res.Samples = append(res.Samples, sample)
+	}
+	sort.SliceStable(res.Samples, func(i, j int) bool { return res.Samples[i].Delay > res.Samples[j].Delay })
+	return res
+}
+
+func apiProfile(match func(*trace.Event) bool) func(r *http.Request) (interface{}, error) {
+	return func(r *http.Request) (interface{}, error) {
+		st := requestTrace(r)
+		format := r.FormValue("format")
+		if format != "" && format != "pprof" {
+			return nil, apiErrorf(http.StatusBadRequest, "unknown format %q (want pprof)", format)
+		}
+		top := profileTopDefault
+		if s := r.FormValue("top"); s != "" {
+			v, err := strconv.Atoi(s)
+			if err != nil || v <= 0 {
+				return nil, apiErrorf(http.StatusBadRequest, "invalid top parameter %q", s)
+			}
+			top = v
+		}
+		goroutines, err := st.profileGoroutines()
+		if err != nil {
+			return nil, err
+		}
+		gToIntervals, err := st.pprofMatchingGoroutines(r.FormValue("id"), goroutines)
+		if err != nil {
+			return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+		}
+		win, err := parseWindow(r)
+		if err != nil {
+			return nil, apiErrorf(http.StatusBadRequest, "%v", err)
+		}
+		p, err := st.scanPprof(win.clip(gToIntervals, goroutines), win.to, match)
+		if err != nil {
+			return nil, err
+		}
+		if format == "pprof" {
+			return p, nil
+		}
+		return newAPIProfileResponse(p, top), nil
+	}
+}
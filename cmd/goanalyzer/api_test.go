@@ -0,0 +1,85 @@
+// +build !js
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestAPI(t *testing.T) {
+	if err := traceProgram(t, prog0, "TestAPI"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	get := func(method, url string, v interface{}) int {
+		t.Helper()
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		if v != nil {
+			if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+				t.Errorf("%s: %v:\n%s", url, err, w.Body)
+			}
+		}
+		return w.Code
+	}
+
+	var index struct{ Endpoints []apiEndpoint }
+	if code := get("GET", apiPrefix, &index); code != http.StatusOK || len(index.Endpoints) != len(apiEndpoints) {
+		t.Errorf("index: status %d, %d endpoints, want %d", code, len(index.Endpoints), len(apiEndpoints))
+	}
+	for _, e := range apiEndpoints {
+		var v interface{}
+		if code := get("GET", apiPrefix+e.Path, &v); code != http.StatusOK {
+			t.Errorf("%s: status %d: %v", e.Path, code, v)
+		}
+	}
+
+	var tasks []exportTask
+	get("GET", apiPrefix+"tasks?type=task0", &tasks)
+	if len(tasks) != 1 || tasks[0].Type != "task0" || !tasks[0].Complete || tasks[0].Regions == 0 {
+		t.Errorf("task0: %+v", tasks)
+	}
+	var mmu apiMMUResponse
+	get("GET", apiPrefix+"mmu?flags=stw&windows=1ms,1s", &mmu)
+	if len(mmu.Flags) != 1 || len(mmu.MMU) != 2 || mmu.MMU[0].MMU < 0 || mmu.MMU[0].MMU > 1 {
+		t.Errorf("mmu: %+v", mmu)
+	}
+	var events []apiEvent
+	get("GET", apiPrefix+"events?from=-1ms", &events)
+	for _, ev := range events {
+		if last := time.Duration(mainTrace.lastTimestamp()); ev.Time < last-time.Millisecond || ev.Time > last {
+			t.Errorf("event at %v outside of the last millisecond before %v", ev.Time, last)
+			break
+		}
+	}
+
+	w := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", apiPrefix+"profiles/block?format=pprof", nil))
+	if _, err := profile.Parse(w.Body); err != nil {
+		t.Errorf("block profile in pprof format: %v", err)
+	}
+
+	for _, tc := range []struct {
+		method, url string
+		status      int
+	}{
+		{"GET", apiPrefix + "nosuch", http.StatusNotFound},
+		{"POST", apiPrefix + "summary", http.StatusMethodNotAllowed},
+		{"GET", apiPrefix + "goroutines?id=x", http.StatusBadRequest},
+		{"GET", apiPrefix + "goroutines?id=123456789", http.StatusNotFound},
+		{"GET", apiPrefix + "mmu?windows=-1s", http.StatusBadRequest},
+		{"GET", apiPrefix + "events?from=2ms&to=1ms", http.StatusBadRequest},
+		{"GET", apiPrefix + "profiles/io?format=svg", http.StatusBadRequest},
+	} {
+		var res struct{ Error *apiError }
+		code := get(tc.method, tc.url, &res)
+		if code != tc.status || res.Error == nil || res.Error.Status != tc.status || res.Error.Message == "" {
+			t.Errorf("%s %s: status %d, error %+v, want status %d", tc.method, tc.url, code, res.Error, tc.status)
+		}
+	}
+}
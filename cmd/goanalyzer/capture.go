@@ -0,0 +1,90 @@
+// Capture of traces from net/http/pprof servers.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tracePath is the path of the trace handler of net/http/pprof.
+const tracePath = "/debug/pprof/trace"
+
+// captureURL returns the URL of a trace of the given seconds from the
+// server target, which is like http://host:port, with or without
+// tracePath.
+func captureURL(target string, seconds int) (*url.URL, error) {
+	if !strings.Contains(target, "://") {
+		target = "http://" + target
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("no host in %q", target)
+	}
+	if !strings.HasSuffix(u.Path, tracePath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + tracePath
+	}
+	q := u.Query()
+	q.Set("seconds", strconv.Itoa(seconds))
+	u.RawQuery = q.Encode()
+	return u, nil
+}
+
+// captureTrace captures a trace of the given seconds from the server
+// target and stores it in dir, under a name made of the host and the
+// time of the capture. It returns the path of the trace.
+func captureTrace(target string, seconds int, dir string) (string, error) {
+	if seconds <= 0 {
+		return "", fmt.Errorf("bad trace duration %ds", seconds)
+	}
+	u, err := captureURL(target, seconds)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Timeout: time.Duration(seconds)*time.Second + 30*time.Second}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%s: %s: %s", u, resp.Status, bytes.TrimSpace(msg))
+	}
+	// Check the content, in case a handler other than net/http/pprof's
+	// serves the path.
+	body := bufio.NewReader(resp.Body)
+	header, _ := body.Peek(16)
+	if _, err := trace.ReadVersion(bytes.NewReader(header)); err != nil {
+		return "", fmt.Errorf("%s did not return a trace: %v", u, err)
+	}
+
+	host := strings.NewReplacer(":", "_", "[", "", "]", "").Replace(u.Host)
+	f, name, err := createTrace(dir, fmt.Sprintf("%s-%s.trace", host, time.Now().Format("20060102-150405")))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(f, body)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("failed to store trace: %v", err)
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,98 @@
+// +build !js
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCaptureURL(t *testing.T) {
+	for _, tc := range []struct{ target, want string }{
+		{"http://localhost:6060", "http://localhost:6060/debug/pprof/trace?seconds=3"},
+		{"localhost:6060/", "http://localhost:6060/debug/pprof/trace?seconds=3"},
+		{"https://host/app/debug/pprof/trace?seconds=10", "https://host/app/debug/pprof/trace?seconds=3"},
+	} {
+		u, err := captureURL(tc.target, 3)
+		if err != nil || u.String() != tc.want {
+			t.Errorf("captureURL(%q) = %v, %v, want %s", tc.target, u, err, tc.want)
+		}
+	}
+	if _, err := captureURL("http://", 3); err == nil {
+		t.Errorf("captureURL without host succeeded")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	defer func(st *traceState) { mainTrace = st }(mainTrace)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(tracePath, pprof.Trace)
+	mux.HandleFunc("/text/debug/pprof/trace", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not a trace, but long enough"))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+	dir := t.TempDir()
+
+	file, err := captureTrace(srv.URL, 1, dir)
+	if err != nil {
+		t.Fatalf("captureTrace: %v", err)
+	}
+	host := strings.Replace(strings.TrimPrefix(srv.URL, "http://"), ":", "_", 1)
+	if name := filepath.Base(file); !strings.HasPrefix(name, host+"-") || !strings.HasSuffix(name, ".trace") {
+		t.Errorf("trace stored as %s, want %s-<time>.trace", name, host)
+	}
+	if _, err := traceVersion(file); err != nil {
+		t.Errorf("captured trace: %v", err)
+	}
+
+	for _, target := range []string{srv.URL + "/nosuch", srv.URL + "/text"} {
+		if _, err := captureTrace(target, 1, dir); err == nil {
+			t.Errorf("capture from %s succeeded", target)
+		}
+	}
+
+	// The capture form of the index stores the trace and opens it.
+	d := newTraceDir(dir, 1<<40)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/traces/capture", strings.NewReader(url.Values{"url": {srv.URL}, "seconds": {"1"}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	d.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("capture form: status %d: %s", w.Code, w.Body)
+	}
+	var opened string
+	for _, c := range w.Result().Cookies() {
+		if c.Name == traceCookie {
+			opened = c.Value
+		}
+	}
+	d.mu.Lock()
+	e := d.traces[opened]
+	n := len(d.traces)
+	d.mu.Unlock()
+	if e == nil || n != 2 {
+		t.Errorf("capture form opened %q, %d traces in the directory, want 2", opened, n)
+	}
+
+	// Other sites cannot make the server capture.
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("POST", "/traces/capture?url=x&seconds=1", nil)
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	d.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("cross-site capture: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	d.ServeHTTP(w, httptest.NewRequest("POST", "/traces/capture?url=x&seconds=0", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("capture of 0s: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
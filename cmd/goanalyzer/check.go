@@ -0,0 +1,346 @@
+// Regression checks of the summary report against thresholds.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkRule is a threshold on a value of the report, for example
+//
+//	p99 SchedWaitTime of group main.worker < 2ms
+//	MMU at 10ms window > 0.6
+//	user task 'request' p95 < 50ms
+//	STW max < 1ms
+type checkRule struct {
+	text     string // as written in the rules file
+	line     int
+	op       string
+	limit    float64
+	duration bool // value and limit are durations in nanoseconds
+
+	// values returns the value of every report entry the rule applies
+	// to, with a description of each.
+	values func(rep *report) ([]checkValue, error)
+}
+
+type checkValue struct {
+	desc  string
+	value float64
+}
+
+var (
+	checkRuleRE   = regexp.MustCompile(`^(.*?)\s*(<=|>=|<|>)\s*(\S+)$`)
+	checkGroupRE  = regexp.MustCompile(`^(count|total|min|avg|max|p50|p90|p99|p99\.9) (\w+) of group (.+)$`)
+	checkMMURE    = regexp.MustCompile(`^MMU at (\S+)(?: window)?$`)
+	checkLatRE    = regexp.MustCompile(`^user (task|region) ['"]([^'"]*)['"] (count|complete|min|p50|p90|p95|p99|max)$`)
+	checkGCFields = map[string]func(*report) time.Duration{
+		"GC time":   func(rep *report) time.Duration { return rep.GC.Time },
+		"STW total": func(rep *report) time.Duration { return rep.GC.STWTime },
+		"STW max":   func(rep *report) time.Duration { return rep.GC.STWMax },
+	}
+)
+
+// readCheckRules reads one rule per line. Blank lines, # comments and a
+// "rules:" line are skipped, and a leading "- " and quotes around the
+// rule are removed, so that a YAML list of rule strings is also read.
+// The file is not parsed as YAML: every other line must be a rule.
+func readCheckRules(r io.Reader) ([]*checkRule, error) {
+	var rules []*checkRule
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") || text == "rules:" {
+			continue
+		}
+		text = strings.TrimSpace(strings.TrimPrefix(text, "- "))
+		if len(text) >= 2 && (text[0] == '"' || text[0] == '\'') && text[len(text)-1] == text[0] {
+			text = text[1 : len(text)-1]
+		}
+		rule, err := parseCheckRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		rule.line = line
+		rules = append(rules, rule)
+	}
+	return rules, s.Err()
+}
+
+func parseCheckRule(text string) (*checkRule, error) {
+	m := checkRuleRE.FindStringSubmatch(text)
+	if m == nil {
+		return nil, fmt.Errorf("rule %q has no comparison", text)
+	}
+	subject, op, limit := m[1], m[2], m[3]
+	rule := &checkRule{text: text, op: op}
+
+	switch {
+	case checkGroupRE.MatchString(subject):
+		m := checkGroupRE.FindStringSubmatch(subject)
+		stat, category, name := m[1], m[2], m[3]
+		if _, ok := (reportGroup{}).stat(category); !ok {
+			return nil, fmt.Errorf("unknown goroutine category %q in rule %q", category, text)
+		}
+		rule.duration = stat != "count"
+		rule.values = func(rep *report) ([]checkValue, error) {
+			// Goroutines started by different go statements of
+			// the same function are separate groups with one name.
+			var groups []reportGroup
+			for _, g := range rep.Goroutines {
+				if g.Name == name {
+					groups = append(groups, g)
+				}
+			}
+			if groups == nil {
+				return nil, fmt.Errorf("no goroutine group %s in trace", name)
+			}
+			var vals []checkValue
+			for i, g := range groups {
+				desc := fmt.Sprintf("%s %s of group %s", stat, category, name)
+				if len(groups) > 1 {
+					desc += fmt.Sprintf(" (%d of %d)", i+1, len(groups))
+				}
+				s, _ := g.stat(category)
+				v, ok := s.value(stat)
+				if !ok {
+					return nil, fmt.Errorf("%s: unknown statistic %q", desc, stat)
+				}
+				vals = append(vals, checkValue{desc, v})
+			}
+			return vals, nil
+		}
+	case checkMMURE.MatchString(subject):
+		window, err := time.ParseDuration(checkMMURE.FindStringSubmatch(subject)[1])
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("bad MMU window in rule %q", text)
+		}
+		rule.values = func(rep *report) ([]checkValue, error) {
+			if window > rep.Duration {
+				return nil, fmt.Errorf("the %v window is longer than the trace (%v)", window, rep.Duration)
+			}
+			return []checkValue{{fmt.Sprintf("MMU at %v", window), rep.mmuCurve.MMU(window)}}, nil
+		}
+	case checkLatRE.MatchString(subject):
+		m := checkLatRE.FindStringSubmatch(subject)
+		kind, name, stat := m[1], m[2], m[3]
+		rule.duration = stat != "count" && stat != "complete"
+		rule.values = func(rep *report) ([]checkValue, error) {
+			list := rep.Tasks
+			if kind == "region" {
+				list = rep.Regions
+			}
+			var vals []checkValue
+			for _, l := range list {
+				if l.Type != name {
+					continue
+				}
+				desc := fmt.Sprintf("user %s '%s' %s", kind, name, stat)
+				if l.Func != "" {
+					desc += " in " + l.Func
+				}
+				if rule.duration && l.Complete == 0 {
+					// Durations of no tasks are zero, which
+					// would satisfy any upper bound.
+					return nil, fmt.Errorf("%s: no complete %ss in trace", desc, kind)
+				}
+				v, ok := l.value(stat)
+				if !ok {
+					return nil, fmt.Errorf("%s: unknown statistic %q", desc, stat)
+				}
+				vals = append(vals, checkValue{desc, v})
+			}
+			if vals == nil {
+				return nil, fmt.Errorf("no user %s '%s' in trace", kind, name)
+			}
+			return vals, nil
+		}
+	case checkGCFields[subject] != nil:
+		field := checkGCFields[subject]
+		rule.duration = true
+		rule.values = func(rep *report) ([]checkValue, error) {
+			return []checkValue{{subject, float64(field(rep))}}, nil
+		}
+	case subject == "GC cycles" || subject == "STW pauses":
+		rule.values = func(rep *report) ([]checkValue, error) {
+			n := rep.GC.Count
+			if subject == "STW pauses" {
+				n = rep.GC.STWCount
+			}
+			return []checkValue{{subject, float64(n)}}, nil
+		}
+	default:
+		return nil, fmt.Errorf("unknown value %q in rule %q", subject, text)
+	}
+
+	if rule.duration {
+		d, err := time.ParseDuration(limit)
+		if err != nil {
+			return nil, fmt.Errorf("bad duration %q in rule %q", limit, text)
+		}
+		rule.limit = float64(d)
+	} else {
+		f, err := strconv.ParseFloat(limit, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q in rule %q", limit, text)
+		}
+		rule.limit = f
+	}
+	return rule, nil
+}
+
+// holds reports whether v satisfies the rule.
+func (rule *checkRule) holds(v float64) bool {
+	switch rule.op {
+	case "<":
+		return v < rule.limit
+	case "<=":
+		return v <= rule.limit
+	case ">":
+		return v > rule.limit
+	default:
+		return v >= rule.limit
+	}
+}
+
+func (rule *checkRule) format(v float64) string {
+	if rule.duration {
+		return time.Duration(v).String()
+	}
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+// check evaluates the rules against rep, writes the outcome of every
+// rule to w and reports whether all rules hold.
+func check(w io.Writer, rep *report, rules []*checkRule) bool {
+	failed := 0
+	for _, rule := range rules {
+		vals, err := rule.values(rep)
+		if err != nil {
+			fmt.Fprintf(w, "FAIL  %s (line %d): %v\n", rule.text, rule.line, err)
+			failed++
+			continue
+		}
+		var bad []string
+		var measured []string
+		for _, v := range vals {
+			measured = append(measured, rule.format(v.value))
+			if !rule.holds(v.value) {
+				bad = append(bad, fmt.Sprintf("%s is %s, want %s %s", v.desc, rule.format(v.value), rule.op, rule.format(rule.limit)))
+			}
+		}
+		if len(bad) == 0 {
+			fmt.Fprintf(w, "ok    %s (%s)\n", rule.text, strings.Join(measured, ", "))
+			continue
+		}
+		fmt.Fprintf(w, "FAIL  %s (line %d): %s\n", rule.text, rule.line, strings.Join(bad, "; "))
+		failed++
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d rules failed\n", failed, len(rules))
+		return false
+	}
+	return true
+}
+
+// runCheck checks the trace against the rules in file. It returns an
+// error if the rules or the trace cannot be read.
+func (st *traceState) runCheck(w io.Writer, file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	rules, err := readCheckRules(f)
+	if err != nil {
+		return false, fmt.Errorf("%s: %v", file, err)
+	}
+	rep, err := st.summaryReport()
+	if err != nil {
+		return false, err
+	}
+	return check(w, rep, rules), nil
+}
+
+// stat returns the statistic of the goroutine group for a category,
+// named like the fields of trace.GExecutionStat.
+func (g reportGroup) stat(category string) (reportStat, bool) {
+	switch category {
+	case "TotalTime":
+		return g.TotalTime, true
+	case "ExecTime":
+		return g.ExecTime, true
+	case "IOTime":
+		return g.IOTime, true
+	case "BlockTime":
+		return g.BlockTime, true
+	case "SyscallTime":
+		return g.SyscallTime, true
+	case "SchedWaitTime":
+		return g.SchedWaitTime, true
+	case "SweepTime":
+		return g.SweepTime, true
+	case "GCTime":
+		return g.GCTime, true
+	case "MarkAssistTime":
+		return g.MarkAssistTime, true
+	}
+	return reportStat{}, false
+}
+
+// value returns the statistic of s named in a rule, such as p99, and
+// whether there is one.
+func (s reportStat) value(stat string) (float64, bool) {
+	switch stat {
+	case "count":
+		return float64(s.Count), true
+	case "total":
+		return float64(s.Total), true
+	case "min":
+		return float64(s.Min), true
+	case "avg":
+		return float64(s.Avg), true
+	case "max":
+		return float64(s.Max), true
+	case "p50":
+		return float64(s.P50), true
+	case "p90":
+		return float64(s.P90), true
+	case "p99":
+		return float64(s.P99), true
+	case "p99.9":
+		return float64(s.P999), true
+	}
+	return 0, false
+}
+
+// value returns the statistic of l named in a rule, such as p95, and
+// whether there is one.
+func (l reportLatency) value(stat string) (float64, bool) {
+	switch stat {
+	case "count":
+		return float64(l.Count), true
+	case "complete":
+		return float64(l.Complete), true
+	case "min":
+		return float64(l.Min), true
+	case "p50":
+		return float64(l.P50), true
+	case "p90":
+		return float64(l.P90), true
+	case "p95":
+		return float64(l.P95), true
+	case "p99":
+		return float64(l.P99), true
+	case "max":
+		return float64(l.Max), true
+	}
+	return 0, false
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+)
+
+func TestReadCheckRules(t *testing.T) {
+	rules, err := readCheckRules(strings.NewReader(`
+# Latency budget.
+rules:
+  - "p99 SchedWaitTime of group main.worker < 2ms"
+  - 'MMU at 10ms window > 0.6'
+  - user task 'request' p95 <= 50ms
+count ExecTime of group main.main >= 1
+STW max < 1ms
+`))
+	if err != nil {
+		t.Fatalf("failed to read rules: %v", err)
+	}
+	want := []struct {
+		text  string
+		line  int
+		op    string
+		limit float64
+	}{
+		{"p99 SchedWaitTime of group main.worker < 2ms", 4, "<", float64(2 * time.Millisecond)},
+		{"MMU at 10ms window > 0.6", 5, ">", 0.6},
+		{"user task 'request' p95 <= 50ms", 6, "<=", float64(50 * time.Millisecond)},
+		{"count ExecTime of group main.main >= 1", 7, ">=", 1},
+		{"STW max < 1ms", 8, "<", float64(time.Millisecond)},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %d rules, want %d", len(rules), len(want))
+	}
+	for i, w := range want {
+		r := rules[i]
+		if r.text != w.text || r.line != w.line || r.op != w.op || r.limit != w.limit {
+			t.Errorf("rule %d: got {%q %d %s %v}, want %+v", i, r.text, r.line, r.op, r.limit, w)
+		}
+	}
+
+	for _, bad := range []string{
+		"p99 SchedWaitTime of group main.worker",
+		"p99 NoSuchTime of group main.worker < 2ms",
+		"p99 SchedWaitTime of group main.worker < 2",
+		"MMU at 10ms window > high",
+		"user task 'request' p42 < 1ms",
+		"heap size < 1",
+	} {
+		if _, err := readCheckRules(strings.NewReader(bad)); err == nil {
+			t.Errorf("no error for rule %q", bad)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	// The mutator is stopped for 1ms at 5ms into a 100ms trace.
+	ms := int64(time.Millisecond)
+	util := [][]trace.MutatorUtil{{{Time: 0, Util: 1}, {Time: 5 * ms, Util: 0}, {Time: 6 * ms, Util: 1}, {Time: 100 * ms, Util: 1}}}
+	rep := &report{
+		Duration: 100 * time.Millisecond,
+		Goroutines: []reportGroup{{
+			Name:          "main.worker",
+			N:             4,
+			SchedWaitTime: reportStat{Count: 10, P99: 3 * time.Millisecond},
+		}, {
+			Name:          "main.handler",
+			N:             2,
+			SchedWaitTime: reportStat{Count: 4, P99: time.Millisecond},
+		}, {
+			Name:          "main.handler",
+			N:             1,
+			SchedWaitTime: reportStat{Count: 2, P99: 4 * time.Millisecond},
+		}},
+		Tasks: []reportLatency{
+			{Type: "request", Count: 5, Complete: 5, P95: 20 * time.Millisecond},
+			{Type: "stream", Count: 3},
+		},
+		Regions: []reportLatency{
+			{Type: "work", Func: "main.a", Complete: 1, Max: time.Millisecond},
+			{Type: "work", Func: "main.b", Complete: 1, Max: 5 * time.Millisecond},
+		},
+		mmuCurve: trace.NewMMUCurve(util),
+	}
+	for _, test := range []struct {
+		rule string
+		ok   bool
+		want string
+	}{
+		{"p99 SchedWaitTime of group main.worker < 2ms", false, "p99 SchedWaitTime of group main.worker is 3ms, want < 2ms"},
+		{"p99 SchedWaitTime of group main.worker < 5ms", true, "(3ms)"},
+		{"p99 SchedWaitTime of group main.idle < 5ms", false, "no goroutine group main.idle in trace"},
+		{"p99 SchedWaitTime of group main.handler < 2ms", false, "p99 SchedWaitTime of group main.handler (2 of 2) is 4ms, want < 2ms"},
+		{"p99 SchedWaitTime of group main.handler < 5ms", true, "(1ms, 4ms)"},
+		{"MMU at 10ms window > 0.95", false, "MMU at 10ms is 0.9, want > 0.95"},
+		{"MMU at 2ms > 0.6", false, "MMU at 2ms is 0.5, want > 0.6"},
+		{"MMU at 1s > 0.1", false, "the 1s window is longer than the trace"},
+		{"user task 'request' p95 < 50ms", true, "(20ms)"},
+		{"user task 'stream' p95 < 50ms", false, "no complete tasks"},
+		{"user task 'stream' count >= 3", true, "(3)"},
+		{"user region 'work' max < 2ms", false, "user region 'work' max in main.b is 5ms"},
+		{"GC cycles < 1", true, "(0)"},
+	} {
+		rules, err := readCheckRules(strings.NewReader(test.rule))
+		if err != nil {
+			t.Fatalf("failed to read rule %q: %v", test.rule, err)
+		}
+		var buf bytes.Buffer
+		if ok := check(&buf, rep, rules); ok != test.ok {
+			t.Errorf("%q: got %v, want %v:\n%s", test.rule, ok, test.ok, buf.String())
+		}
+		if !strings.Contains(buf.String(), test.want) {
+			t.Errorf("%q: output does not contain %q:\n%s", test.rule, test.want, buf.String())
+		}
+	}
+}
+
+func TestCheckValue(t *testing.T) {
+	// Every statistic accepted in a rule has a value, and others fail.
+	for _, stat := range []string{"count", "total", "min", "avg", "max", "p50", "p90", "p99", "p99.9"} {
+		if !checkGroupRE.MatchString(stat + " ExecTime of group main.main") {
+			t.Errorf("group rules do not accept %s", stat)
+		}
+		if _, ok := (reportStat{}).value(stat); !ok {
+			t.Errorf("no value for group statistic %s", stat)
+		}
+	}
+	for _, stat := range []string{"count", "complete", "min", "p50", "p90", "p95", "p99", "max"} {
+		if !checkLatRE.MatchString("user task 'request' " + stat) {
+			t.Errorf("task rules do not accept %s", stat)
+		}
+		if _, ok := (reportLatency{}).value(stat); !ok {
+			t.Errorf("no value for task statistic %s", stat)
+		}
+	}
+	if v, ok := (reportStat{P999: time.Second}).value("p99.9"); !ok || v != float64(time.Second) {
+		t.Errorf("p99.9 = %v, %v, want 1s", time.Duration(v), ok)
+	}
+	for _, stat := range []string{"p42", "stddev", ""} {
+		if _, ok := (reportStat{P999: time.Second}).value(stat); ok {
+			t.Errorf("group statistic %q has a value", stat)
+		}
+		if _, ok := (reportLatency{Max: time.Second}).value(stat); ok {
+			t.Errorf("task statistic %q has a value", stat)
+		}
+	}
+}
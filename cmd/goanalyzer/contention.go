@@ -0,0 +1,301 @@
+// Lock and channel contention by blocking stack.
+
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"net/http"
+	"sort"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/contention", httpContention)
+}
+
+// contentionKinds are the synchronization blocking events, as in the
+// synchronization blocking profile without the GC assist waits, with
+// the names of what the goroutines waited on. See contentionKind for
+// EvGoBlockSync.
+var contentionKinds = map[byte]string{
+	trace.EvGoBlockSend:   "chan send",
+	trace.EvGoBlockRecv:   "chan receive",
+	trace.EvGoBlockSelect: "select",
+	trace.EvGoBlockSync:   "mutex",
+	trace.EvGoBlockCond:   "sync.Cond",
+}
+
+// contentionKind returns the name of what the blocking event ev waited
+// on, or "" if it is not a synchronization wait. EvGoBlockSync covers
+// sync.WaitGroup waits as well as mutexes; they are told apart by
+// stack.
+func contentionKind(ev *trace.Event) string {
+	kind := contentionKinds[ev.Type]
+	if ev.Type == trace.EvGoBlockSync {
+		for _, f := range ev.Stk {
+			if f.Fn == "sync.(*WaitGroup).Wait" {
+				return "sync.WaitGroup"
+			}
+		}
+	}
+	return kind
+}
+
+// contentionUnblocker is a stack that unblocked the waits of a
+// contentionSite.
+type contentionUnblocker struct {
+	Stack []*trace.Frame
+	Count int
+	Total time.Duration // wait of the unblocked goroutines
+}
+
+// contentionSite is the waits of one kind at one blocking stack.
+type contentionSite struct {
+	Kind       string
+	Stack      []*trace.Frame
+	Waits      []time.Duration // sorted
+	Total      time.Duration
+	Waiters    map[uint64]bool
+	Unblockers []*contentionUnblocker // sorted by decreasing wait
+}
+
+// Percentile returns the p-th percentile (0 < p <= 100) of the waits
+// of s, by nearest rank like the latencies of the report.
+func (s *contentionSite) Percentile(p float64) time.Duration {
+	return durationQuantile(s.Waits, p/100)
+}
+
+// contentionKindTotal is the waits of one kind over all stacks.
+type contentionKindTotal struct {
+	Kind  string
+	Count int
+	Total time.Duration
+}
+
+// computeContention groups the synchronization waits of the goroutines
+// in gToIntervals, or of all goroutines if it is nil, by kind and
+// blocking stack. Only the part of the waits during the intervals is
+// counted. Waits still in progress at the end of the trace, and waits
+// without a stack, are left out. The sites are sorted by decreasing
+// total wait.
+func computeContention(gToIntervals map[uint64][]interval, events []*trace.Event) []*contentionSite {
+	type key struct {
+		typ byte
+		stk uint64
+	}
+	sites := make(map[key]*contentionSite)
+	unblockers := make(map[*contentionSite]map[uint64]*contentionUnblocker)
+	for _, ev := range events {
+		kind := contentionKind(ev)
+		if kind == "" || ev.Link == nil || ev.StkID == 0 || len(ev.Stk) == 0 {
+			continue
+		}
+		d := pprofOverlappingDuration(gToIntervals, ev)
+		if gToIntervals != nil && d == 0 {
+			continue
+		}
+		k := key{ev.Type, ev.StkID}
+		s := sites[k]
+		if s == nil {
+			s = &contentionSite{Kind: kind, Stack: ev.Stk, Waiters: make(map[uint64]bool)}
+			sites[k] = s
+			unblockers[s] = make(map[uint64]*contentionUnblocker)
+		}
+		s.Waits = append(s.Waits, d)
+		s.Total += d
+		s.Waiters[ev.G] = true
+
+		// The wait ends with the unblock, which has no stack if it
+		// came from outside a goroutine.
+		unblock := ev.Link
+		if unblock.StkID == 0 {
+			continue
+		}
+		u := unblockers[s][unblock.StkID]
+		if u == nil {
+			u = &contentionUnblocker{Stack: unblock.Stk}
+			unblockers[s][unblock.StkID] = u
+		}
+		u.Count++
+		u.Total += d
+	}
+
+	var list []*contentionSite
+	for _, s := range sites {
+		sort.Slice(s.Waits, func(i, j int) bool { return s.Waits[i] < s.Waits[j] })
+		for _, u := range unblockers[s] {
+			s.Unblockers = append(s.Unblockers, u)
+		}
+		sort.Slice(s.Unblockers, func(i, j int) bool {
+			if s.Unblockers[i].Total != s.Unblockers[j].Total {
+				return s.Unblockers[i].Total > s.Unblockers[j].Total
+			}
+			return s.Unblockers[i].Count > s.Unblockers[j].Count
+		})
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return len(list[i].Waits) > len(list[j].Waits)
+	})
+	return list
+}
+
+// contentionTotals returns the totals of sites by kind, sorted by
+// decreasing total wait.
+func contentionTotals(sites []*contentionSite) []contentionKindTotal {
+	byKind := make(map[string]*contentionKindTotal)
+	var totals []*contentionKindTotal
+	for _, s := range sites {
+		t := byKind[s.Kind]
+		if t == nil {
+			t = &contentionKindTotal{Kind: s.Kind}
+			byKind[s.Kind] = t
+			totals = append(totals, t)
+		}
+		t.Count += len(s.Waits)
+		t.Total += s.Total
+	}
+	sort.SliceStable(totals, func(i, j int) bool { return totals[i].Total > totals[j].Total })
+	list := make([]contentionKindTotal, len(totals))
+	for i, t := range totals {
+		list[i] = *t
+	}
+	return list
+}
+
+// httpContention serves the contention report. The id parameter
+// restricts it to a goroutine group, the kind parameter to a kind of
+// wait, and the from and to parameters to a window.
+func httpContention(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := r.FormValue("id")
+	st.analyzeGoroutines(events)
+	gToIntervals, err := st.pprofMatchingGoroutines(id, st.gs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sites := computeContention(win.clip(gToIntervals, st.gs), events)
+	totals := contentionTotals(sites)
+	kind := r.FormValue("kind")
+	if kind != "" {
+		var filtered []*contentionSite
+		for _, s := range sites {
+			if s.Kind == kind {
+				filtered = append(filtered, s)
+			}
+		}
+		sites = filtered
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	err = templContention.Execute(w, struct {
+		ID     string
+		Kind   string
+		Totals []contentionKindTotal
+		Sites  []*contentionSite
+	}{
+		ID:     id,
+		Kind:   kind,
+		Totals: totals,
+		Sites:  sites,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templContention = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+}).Parse(`
+<!DOCTYPE html>
+<title>Lock and channel contention</title>
+<style>
+th {
+  background-color: #050505;
+  color: #fff;
+}
+table {
+  border-collapse: collapse;
+}
+.details tr:hover {
+  background-color: #f2f2f2;
+}
+.details td {
+  border: 1px solid black;
+  vertical-align: top;
+  padding: 0.2em 0.4em;
+}
+.stack {
+  font-size: 85%;
+  white-space: nowrap;
+}
+.unblocker {
+  margin-bottom: 0.5em;
+}
+</style>
+<script>` + windowScript + `</script>
+<body>
+<h2>Lock and channel contention{{if .ID}} of goroutine group {{.ID}}{{end}}{{if .Kind}}: {{.Kind}}{{end}}</h2>
+<p>Waits of goroutines blocked on channels, selects, mutexes, wait groups and condition variables, by
+blocking stack, with the stacks that unblocked them. Waits still in progress at the end of
+the trace are not included.</p>
+<table class="details">
+<tr><th>Blocked on</th><th>Waits</th><th>Total</th></tr>
+<tr><td><a href="/contention?id={{.ID}}">all</a></td><td></td><td></td></tr>
+{{range .Totals}}
+<tr>
+<td><a href="/contention?id={{$.ID}}&kind={{.Kind}}">{{.Kind}}</a></td>
+<td>{{.Count}}</td>
+<td>{{niceDuration .Total}}</td>
+</tr>
+{{end}}
+</table>
+<br>
+<table class="details">
+<tr>
+<th> Blocked on</th>
+<th> Blocking stack</th>
+<th> Waits</th>
+<th> Waiters</th>
+<th> Total</th>
+<th> p50</th>
+<th> p90</th>
+<th> p99</th>
+<th> Max</th>
+<th> Unblocked by</th>
+</tr>
+{{range .Sites}}
+  <tr>
+    <td>{{.Kind}}</td>
+    <td class="stack">{{range .Stack}}{{.Fn}}<br>&nbsp;&nbsp;{{.File}}:{{.Line}}<br>{{end}}</td>
+    <td>{{len .Waits}}</td>
+    <td>{{len .Waiters}}</td>
+    <td>{{niceDuration .Total}}</td>
+    <td>{{niceDuration (.Percentile 50)}}</td>
+    <td>{{niceDuration (.Percentile 90)}}</td>
+    <td>{{niceDuration (.Percentile 99)}}</td>
+    <td>{{niceDuration (.Percentile 100)}}</td>
+    <td class="stack">{{range .Unblockers}}<div class="unblocker">{{.Count}} waits, {{niceDuration .Total}}:<br>{{range .Stack}}{{.Fn}}<br>&nbsp;&nbsp;{{.File}}:{{.Line}}<br>{{end}}</div>{{end}}</td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,112 @@
+// +build !js
+
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// contendedMutex waits for mu, held by its caller.
+func contendedMutex(mu *sync.Mutex, wg *sync.WaitGroup) {
+	defer wg.Done()
+	mu.Lock()
+	mu.Unlock()
+}
+
+// contendedRecv waits for a value on c.
+func contendedRecv(c chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	<-c
+}
+
+func TestComputeContention(t *testing.T) {
+	prog := func() {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		c := make(chan int)
+		mu.Lock()
+		for i := 0; i < 3; i++ {
+			wg.Add(2)
+			go contendedMutex(&mu, &wg)
+			go contendedRecv(c, &wg)
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Unlock()
+		for i := 0; i < 3; i++ {
+			c <- i
+		}
+		wg.Wait()
+	}
+	if err := traceProgram(t, prog, "TestComputeContention"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+
+	find := func(kind, fn string) *contentionSite {
+		for _, s := range computeContention(nil, events) {
+			if s.Kind != kind {
+				continue
+			}
+			for _, f := range s.Stack {
+				if f.Fn == "github.com/robaho/goanalyzer/cmd/goanalyzer."+fn {
+					return s
+				}
+			}
+		}
+		return nil
+	}
+	recv := find("chan receive", "contendedRecv")
+	if recv == nil {
+		t.Fatalf("no channel receive contention in contendedRecv")
+	}
+	if n := len(recv.Waiters); n != 3 {
+		t.Errorf("%d contendedRecv waiters, want 3", n)
+	}
+	if recv.Percentile(50) < 10*time.Millisecond || recv.Percentile(100) != recv.Waits[len(recv.Waits)-1] {
+		t.Errorf("contendedRecv waits %v, p50 %v, want about 20ms", recv.Waits, recv.Percentile(50))
+	}
+	var sender bool
+	for _, u := range recv.Unblockers {
+		for _, f := range u.Stack {
+			if strings.HasSuffix(f.Fn, "TestComputeContention.func1") {
+				sender = true
+			}
+		}
+	}
+	if !sender {
+		t.Errorf("contendedRecv waits not unblocked by the sending goroutine")
+	}
+
+	if mu := find("mutex", "contendedMutex"); mu == nil {
+		t.Errorf("no mutex contention in contendedMutex")
+	} else if mu.Total < 10*time.Millisecond {
+		t.Errorf("contendedMutex waited %v, want at least 10ms", mu.Total)
+	}
+}
+
+func TestContentionPercentile(t *testing.T) {
+	s := &contentionSite{}
+	for i := 1; i <= 10; i++ {
+		s.Waits = append(s.Waits, time.Duration(i)*time.Millisecond)
+	}
+	for _, tc := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{1, time.Millisecond},
+		{50, 5 * time.Millisecond},
+		{90, 9 * time.Millisecond},
+		{91, 10 * time.Millisecond},
+		{100, 10 * time.Millisecond},
+	} {
+		if got := s.Percentile(tc.p); got != tc.want {
+			t.Errorf("Percentile(%v) = %v, want %v", tc.p, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+// Critical path analysis of user tasks.
+
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"sort"
+	"time"
+)
+
+// critKind is what a goroutine on the critical path was doing.
+type critKind int
+
+const (
+	critExec critKind = iota
+	critSched
+	critNet
+	critSync
+	critSyscall
+	critGC
+	critSleep
+	critOther // other waits and the time before the first event of a goroutine
+	critKinds
+)
+
+var critKindNames = [critKinds]string{
+	critExec:    "Execution",
+	critSched:   "Scheduler wait",
+	critNet:     "Network wait",
+	critSync:    "Sync block",
+	critSyscall: "Blocking syscall",
+	critGC:      "GC",
+	critSleep:   "Sleep",
+	critOther:   "Other",
+}
+
+func (k critKind) String() string {
+	return critKindNames[k]
+}
+
+// critSegment is a part of the critical path spent in a single
+// state of a single goroutine.
+type critSegment struct {
+	G          uint64
+	Kind       critKind
+	Start, End int64 // nanoseconds
+}
+
+func (s critSegment) Duration() time.Duration {
+	return time.Duration(s.End - s.Start)
+}
+
+// critPath is the critical path of a task: the chain of goroutine
+// states, linked by unblock and creation edges, that ends with the
+// end of the task.
+type critPath struct {
+	Segments []critSegment // in time order
+	Totals   [critKinds]time.Duration
+	Duration time.Duration
+}
+
+// critTotal is the time spent on the critical path in a critKind.
+type critTotal struct {
+	Kind     critKind
+	Duration time.Duration
+}
+
+// Breakdown returns the non-zero totals of p.
+func (p *critPath) Breakdown() []critTotal {
+	var b []critTotal
+	for k, d := range p.Totals {
+		if d > 0 {
+			b = append(b, critTotal{critKind(k), d})
+		}
+	}
+	return b
+}
+
+// Goroutines returns the goroutines on p.
+func (p *critPath) Goroutines() map[uint64]bool {
+	gs := make(map[uint64]bool)
+	for _, s := range p.Segments {
+		gs[s.G] = true
+	}
+	return gs
+}
+
+// critPathIndex holds, for every goroutine, the events that change its
+// state and its mark assists, in time order.
+type critPathIndex struct {
+	events  map[uint64][]*trace.Event
+	assists map[uint64][]interval
+	n       int // number of indexed events
+}
+
+// analyzeCritPaths indexes the events for critical path computations
+// and stores the index in st.critIndex.
+func (st *traceState) analyzeCritPaths(events []*trace.Event) {
+	st.critPathInit.Do(func() {
+		st.critIndex = newCritPathIndex(events)
+	})
+}
+
+func newCritPathIndex(events []*trace.Event) *critPathIndex {
+	idx := &critPathIndex{
+		events:  make(map[uint64][]*trace.Event),
+		assists: make(map[uint64][]interval),
+	}
+	for _, ev := range events {
+		var g uint64
+		switch ev.Type {
+		case trace.EvGoStart, trace.EvGoStartLabel, trace.EvGoSched, trace.EvGoPreempt,
+			trace.EvGoSysBlock, trace.EvGoSysExit, trace.EvGoStop, trace.EvGoSleep, trace.EvGoBlock,
+			trace.EvGoBlockSend, trace.EvGoBlockRecv, trace.EvGoBlockSelect, trace.EvGoBlockSync,
+			trace.EvGoBlockCond, trace.EvGoBlockNet, trace.EvGoBlockGC, trace.EvGoWaiting, trace.EvGoInSyscall:
+			g = ev.G
+		case trace.EvGoUnblock, trace.EvGoCreate:
+			g = ev.Args[0]
+		case trace.EvGCMarkAssistStart:
+			end := events[len(events)-1].Ts
+			if ev.Link != nil {
+				end = ev.Link.Ts
+			}
+			idx.assists[ev.G] = append(idx.assists[ev.G], interval{ev.Ts, end})
+			continue
+		default:
+			continue
+		}
+		idx.events[g] = append(idx.events[g], ev)
+		idx.n++
+	}
+	return idx
+}
+
+// critBlockKinds maps the events blocking a goroutine to the kind of
+// its wait.
+var critBlockKinds = map[byte]critKind{
+	trace.EvGoBlockSend:   critSync,
+	trace.EvGoBlockRecv:   critSync,
+	trace.EvGoBlockSelect: critSync,
+	trace.EvGoBlockSync:   critSync,
+	trace.EvGoBlockCond:   critSync,
+	trace.EvGoBlockNet:    critNet,
+	trace.EvGoSysBlock:    critSyscall,
+	trace.EvGoInSyscall:   critSyscall,
+	trace.EvGoBlockGC:     critGC,
+	trace.EvGoSleep:       critSleep,
+}
+
+// taskCritPath returns the critical path of a complete task, nil if the
+// task is incomplete.
+func (idx *critPathIndex) taskCritPath(task *taskDesc) *critPath {
+	if !task.complete() {
+		return nil
+	}
+	return idx.path(task.create.Ts, task.end.Ts, task.end.G)
+}
+
+// path computes the critical path from start to end, which ends on
+// goroutine g. It walks back in time from end on g. A goroutine that
+// was made runnable by another goroutine, unblocking or creating it,
+// continues the path back on that goroutine; the time it was blocked
+// is not on the path. Otherwise the path continues back through the
+// blocked goroutine's own wait.
+func (idx *critPathIndex) path(start, end int64, g uint64) *critPath {
+	p := &critPath{Duration: time.Duration(end - start)}
+	var rev []critSegment // segments in reverse time order
+	add := func(g uint64, kind critKind, from, to int64) {
+		if from < start {
+			from = start
+		}
+		if to <= from {
+			return
+		}
+		if n := len(rev); n > 0 && rev[n-1].G == g && rev[n-1].Kind == kind && rev[n-1].Start == to {
+			rev[n-1].Start = from
+			return
+		}
+		rev = append(rev, critSegment{G: g, Kind: kind, Start: from, End: to})
+	}
+	addExec := func(g uint64, from, to int64) {
+		// Split out the mark assists, latest first.
+		assists := idx.assists[g]
+		for i := len(assists) - 1; i >= 0 && to > from; i-- {
+			a := assists[i]
+			if a.end <= from || a.begin >= to {
+				continue
+			}
+			if a.end < to {
+				add(g, critExec, a.end, to)
+				to = a.end
+			}
+			begin := a.begin
+			if begin < from {
+				begin = from
+			}
+			add(g, critGC, begin, to)
+			to = begin
+		}
+		add(g, critExec, from, to)
+	}
+
+	t := end
+	events := idx.events[g]
+	// last returns the index of the last event at or before t.
+	last := func() int {
+		return sort.Search(len(events), func(i int) bool { return events[i].Ts > t }) - 1
+	}
+	i := last()
+	follow := func(g1 uint64) bool {
+		if g1 == 0 || idx.events[g1] == nil {
+			return false
+		}
+		g, events = g1, idx.events[g1]
+		i = last()
+		return true
+	}
+walk:
+	for steps := 0; t > start && steps <= idx.n; steps++ {
+		if i < 0 {
+			break // g's state before its first event is unknown
+		}
+		ev := events[i]
+		switch ev.Type {
+		case trace.EvGoStart, trace.EvGoStartLabel:
+			addExec(g, ev.Ts, t)
+			t = ev.Ts
+			i--
+		case trace.EvGoUnblock:
+			add(g, critSched, ev.Ts, t)
+			t = ev.Ts
+			if !follow(ev.G) {
+				// Unblocked by the network poller or a timer: the
+				// path goes through g's wait.
+				i--
+			}
+		case trace.EvGoCreate:
+			add(g, critSched, ev.Ts, t)
+			t = ev.Ts
+			if !follow(ev.G) {
+				break walk
+			}
+		case trace.EvGoSched, trace.EvGoPreempt, trace.EvGoSysExit:
+			add(g, critSched, ev.Ts, t)
+			t = ev.Ts
+			i--
+		default:
+			kind, ok := critBlockKinds[ev.Type]
+			if !ok {
+				kind = critOther
+			}
+			add(g, kind, ev.Ts, t)
+			t = ev.Ts
+			i--
+		}
+	}
+	add(g, critOther, start, t)
+
+	for i := len(rev) - 1; i >= 0; i-- {
+		s := rev[i]
+		p.Segments = append(p.Segments, s)
+		p.Totals[s.Kind] += s.Duration()
+	}
+	return p
+}
+
+// critKindColors are the colors of the critical path segments in the
+// trace viewer.
+var critKindColors = [critKinds]string{
+	critExec:    colorSeafoamGreen,
+	critSched:   colorVistaBlue,
+	critNet:     colorOrange,
+	critSync:    colorLightMauve,
+	critSyscall: colorDeepMagenta,
+	critGC:      colorDarkGoldenrod,
+	critSleep:   colorIrisBlue,
+	critOther:   colorLightGrey,
+}
+
+// emitCritPath emits the critical path as a row of the tasks section.
+func (ctx *traceContext) emitCritPath(p *critPath, taskID uint64) {
+	const row = 0 // task IDs are never 0
+	ctx.emitFooter(&ViewerEvent{Name: "thread_name", Phase: "M", Pid: tasksSection, Tid: row, Arg: &NameArg{fmt.Sprintf("Critical path of T%d", taskID)}})
+	ctx.emitFooter(&ViewerEvent{Name: "thread_sort_index", Phase: "M", Pid: tasksSection, Tid: row, Arg: &SortIndexArg{-1}})
+	for _, s := range p.Segments {
+		type Arg struct {
+			G uint64
+		}
+		ctx.emit(&ViewerEvent{
+			Name:  s.Kind.String(),
+			Phase: "X",
+			Time:  float64(s.Start) / 1e3,
+			Dur:   float64(s.End-s.Start) / 1e3,
+			Pid:   tasksSection,
+			Tid:   row,
+			Cname: critKindColors[s.Kind],
+			Arg:   &Arg{G: s.G},
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"testing"
+	"time"
+)
+
+func TestCritPath(t *testing.T) {
+	ev := func(ts int64, typ byte, g uint64, args ...uint64) *trace.Event {
+		e := &trace.Event{Ts: ts, Type: typ, G: g}
+		copy(e.Args[:], args)
+		return e
+	}
+	assistDone := ev(90, trace.EvGCMarkAssistDone, 1)
+	assist := ev(85, trace.EvGCMarkAssistStart, 1)
+	assist.Link = assistDone
+	events := []*trace.Event{
+		ev(0, trace.EvGoStart, 1),
+		ev(1, trace.EvGoStart, 2),
+		ev(2, trace.EvGoSleep, 2),
+		ev(8, trace.EvGoUnblock, 0, 2), // by a timer
+		ev(9, trace.EvGoStart, 2),
+		ev(15, trace.EvGoBlockNet, 2),
+		ev(20, trace.EvGoBlockRecv, 1),
+		ev(40, trace.EvGoUnblock, 0, 2), // by the network poller
+		ev(50, trace.EvGoStart, 2),
+		ev(70, trace.EvGoUnblock, 2, 1), // g2 wakes g1
+		ev(75, trace.EvGoBlockSelect, 2),
+		ev(80, trace.EvGoStart, 1),
+		assist,
+		assistDone,
+		ev(110, trace.EvGoBlock, 1),
+	}
+	idx := newCritPathIndex(events)
+	p := idx.path(5, 100, 1)
+
+	var segs []string
+	for _, s := range p.Segments {
+		segs = append(segs, fmt.Sprintf("G%d %s %d-%d", s.G, s.Kind, s.Start, s.End))
+	}
+	want := "[G2 Sleep 5-8 G2 Scheduler wait 8-9 G2 Execution 9-15 G2 Network wait 15-40 G2 Scheduler wait 40-50 G2 Execution 50-70 G1 Scheduler wait 70-80 G1 Execution 80-85 G1 GC 85-90 G1 Execution 90-100]"
+	if got := fmt.Sprint(segs); got != want {
+		t.Errorf("critical path\n%s\nwant\n%s", got, want)
+	}
+	var sum time.Duration
+	for _, d := range p.Totals {
+		sum += d
+	}
+	if sum != p.Duration || p.Totals[critExec] != 41 || p.Totals[critNet] != 25 || p.Totals[critGC] != 5 || p.Totals[critSleep] != 3 {
+		t.Errorf("totals %v of %v, want 41ns execution, 25ns network, 5ns GC and 3ns sleep adding up to the duration", p.Totals, p.Duration)
+	}
+	if gs := p.Goroutines(); len(gs) != 2 || !gs[1] || !gs[2] {
+		t.Errorf("goroutines on the path %v, want 1 and 2", gs)
+	}
+}
@@ -0,0 +1,441 @@
+// Comparison of the trace against a base trace given with -base.
+
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/diff", httpDiff)
+}
+
+// baseLoader holds the base trace, if any.
+var baseLoader traceLoader
+
+var baseReport struct {
+	once sync.Once
+	rep  *report
+	err  error
+}
+
+// buildBaseReport is buildReport for the base trace. Only the report
+// of the base trace is used, so it is streamed without keeping its
+// events.
+func buildBaseReport() (*report, error) {
+	baseReport.once.Do(func() {
+		baseReport.rep, baseReport.err = streamReport(baseLoader.file, baseLoader.bin)
+	})
+	return baseReport.rep, baseReport.err
+}
+
+const (
+	// diffMinChange is the smallest relative change of a mean that is
+	// highlighted, however significant: smaller changes rarely matter.
+	diffMinChange = 0.05
+	// diffMinMMUChange is the smallest highlighted change of an MMU.
+	diffMinMMUChange = 0.05
+)
+
+// diffCategories are the goroutine statistics compared, named like the
+// fields of trace.GExecutionStat, with their column titles.
+var diffCategories = []struct{ name, title string }{
+	{"ExecTime", "Execution"},
+	{"IOTime", "Network wait"},
+	{"BlockTime", "Sync block"},
+	{"SyscallTime", "Blocking syscall"},
+	{"SchedWaitTime", "Scheduler wait"},
+	{"GCTime", "GC pause"},
+	{"MarkAssistTime", "GC mark assist"},
+}
+
+// traceDiff is the comparison of two reports.
+type traceDiff struct {
+	BaseFile, File string
+	Base, New      *report
+	Categories     []string // titles of diffCategories
+	Groups         []diffGroup
+	Tasks          []diffLatency
+	Regions        []diffLatency
+	MMU            []diffMMU
+}
+
+// diffGroup compares a goroutine group. A group missing from one of
+// the traces compares against zero statistics.
+type diffGroup struct {
+	Name        string
+	BaseN, NewN int
+	Stats       []diffStat // in diffCategories order
+}
+
+type diffStat struct {
+	Base, New reportStat
+}
+
+// diffLatency compares a user task or region type.
+type diffLatency struct {
+	Type, Func string
+	Base, New  reportLatency
+}
+
+type diffMMU struct {
+	Window    time.Duration
+	Base, New float64
+}
+
+func newTraceDiff(base, cur *report) *traceDiff {
+	d := &traceDiff{Base: base, New: cur}
+	for _, c := range diffCategories {
+		d.Categories = append(d.Categories, c.title)
+	}
+
+	// Groups in the order of the new trace, then those only in the base.
+	// Groups are the same if they start at the same PC, as in traces of
+	// the same binary, or else have the same name, as in traces of
+	// different builds.
+	type pcKey struct {
+		pc   uint64
+		name string
+	}
+	byPC := make(map[pcKey]int)
+	byName := make(map[string][]int)
+	for i, g := range base.Goroutines {
+		byPC[pcKey{g.PC, g.Name}] = i
+		byName[g.Name] = append(byName[g.Name], i)
+	}
+	matched := make([]bool, len(base.Goroutines))
+	match := make([]int, len(cur.Goroutines))
+	for i, g := range cur.Goroutines {
+		match[i] = -1
+		if j, ok := byPC[pcKey{g.PC, g.Name}]; ok {
+			match[i], matched[j] = j, true
+		}
+	}
+	for i, g := range cur.Goroutines {
+		if match[i] >= 0 {
+			continue
+		}
+		for _, j := range byName[g.Name] {
+			if !matched[j] {
+				match[i], matched[j] = j, true
+				break
+			}
+		}
+	}
+	addGroup := func(b, n reportGroup, name string) {
+		dg := diffGroup{Name: name, BaseN: b.N, NewN: n.N}
+		for _, c := range diffCategories {
+			bs, _ := b.stat(c.name)
+			ns, _ := n.stat(c.name)
+			dg.Stats = append(dg.Stats, diffStat{bs, ns})
+		}
+		d.Groups = append(d.Groups, dg)
+	}
+	for i, g := range cur.Goroutines {
+		var b reportGroup
+		if j := match[i]; j >= 0 {
+			b = base.Goroutines[j]
+		}
+		addGroup(b, g, g.Name)
+	}
+	for j, g := range base.Goroutines {
+		if !matched[j] {
+			addGroup(g, reportGroup{}, g.Name)
+		}
+	}
+
+	d.Tasks = diffLatencies(base.Tasks, cur.Tasks)
+	d.Regions = diffLatencies(base.Regions, cur.Regions)
+
+	for _, window := range reportMMUWindows {
+		var m diffMMU
+		var inBase, inNew bool
+		for _, bm := range base.GC.MMU {
+			if bm.Window == window {
+				m.Base, inBase = bm.MMU, true
+			}
+		}
+		for _, nm := range cur.GC.MMU {
+			if nm.Window == window {
+				m.New, inNew = nm.MMU, true
+			}
+		}
+		if inBase && inNew {
+			m.Window = window
+			d.MMU = append(d.MMU, m)
+		}
+	}
+	return d
+}
+
+// diffLatencies joins the latencies by type and function, in the order
+// of the new trace and then of those only in the base.
+func diffLatencies(base, cur []reportLatency) []diffLatency {
+	type key struct{ typ, fn string }
+	baseByKey := make(map[key]reportLatency)
+	for _, l := range base {
+		baseByKey[key{l.Type, l.Func}] = l
+	}
+	var diffs []diffLatency
+	for _, l := range cur {
+		k := key{l.Type, l.Func}
+		diffs = append(diffs, diffLatency{Type: l.Type, Func: l.Func, Base: baseByKey[k], New: l})
+		delete(baseByKey, k)
+	}
+	for _, l := range base {
+		if _, ok := baseByKey[key{l.Type, l.Func}]; ok {
+			diffs = append(diffs, diffLatency{Type: l.Type, Func: l.Func, Base: l})
+		}
+	}
+	return diffs
+}
+
+// significantChange reports whether the means of two samples, given by
+// their size, mean and standard deviation, differ meaningfully: by at
+// least diffMinChange of the base mean, which any change from a zero
+// mean is, and by Welch's t-test at the 95% confidence level.
+func significantChange(n1 int64, m1, sd1 float64, n2 int64, m2, sd2 float64) bool {
+	if n1 < 2 || n2 < 2 || m1 == m2 {
+		return false
+	}
+	if m1 != 0 && math.Abs(m2-m1)/m1 < diffMinChange {
+		return false
+	}
+	v1, v2 := sd1*sd1/float64(n1), sd2*sd2/float64(n2)
+	if v1+v2 == 0 {
+		return true // constant samples with different values
+	}
+	t := math.Abs(m2-m1) / math.Sqrt(v1+v2)
+	// Welch–Satterthwaite degrees of freedom.
+	df := (v1 + v2) * (v1 + v2) / (v1*v1/float64(n1-1) + v2*v2/float64(n2-1))
+	return t > tCritical95(df)
+}
+
+// tCritical95 returns the two-sided 95% critical value of Student's
+// t distribution with df degrees of freedom, rounded down to a
+// tabulated df, which errs on the side of fewer highlights.
+func tCritical95(df float64) float64 {
+	table := []struct {
+		df float64
+		t  float64
+	}{
+		{1, 12.71}, {2, 4.30}, {3, 3.18}, {4, 2.78}, {5, 2.57},
+		{6, 2.45}, {7, 2.36}, {8, 2.31}, {9, 2.26}, {10, 2.23},
+		{15, 2.13}, {20, 2.09}, {30, 2.04}, {60, 2.00}, {120, 1.98},
+	}
+	t := table[0].t
+	for _, e := range table {
+		if df < e.df {
+			return t
+		}
+		t = e.t
+	}
+	return 1.96
+}
+
+// Change is 1 if the statistic got significantly worse (the mean
+// increased), -1 if it got better and 0 otherwise.
+func (d diffStat) Change() int {
+	return changeOf(significantChange(d.Base.Count, float64(d.Base.Avg), float64(d.Base.Stddev),
+		d.New.Count, float64(d.New.Avg), float64(d.New.Stddev)), d.New.Avg-d.Base.Avg)
+}
+
+func (d diffLatency) Change() int {
+	return changeOf(significantChange(int64(d.Base.Complete), float64(d.Base.Mean), float64(d.Base.Stddev),
+		int64(d.New.Complete), float64(d.New.Mean), float64(d.New.Stddev)), d.New.Mean-d.Base.Mean)
+}
+
+// Change is 1 if the MMU dropped by diffMinMMUChange or more, -1 if it
+// rose by as much and 0 otherwise.
+func (d diffMMU) Change() int {
+	switch {
+	case d.New <= d.Base-diffMinMMUChange:
+		return 1
+	case d.New >= d.Base+diffMinMMUChange:
+		return -1
+	}
+	return 0
+}
+
+func (d diffMMU) Delta() float64 {
+	return d.New - d.Base
+}
+
+func changeOf(significant bool, delta time.Duration) int {
+	switch {
+	case !significant:
+		return 0
+	case delta > 0:
+		return 1
+	}
+	return -1
+}
+
+// durationDelta formats the change from base to cur, for example
+// "+1.2ms (+15%)".
+func durationDelta(base, cur time.Duration) string {
+	d := cur - base
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	s := sign + niceDuration(d)
+	if base != 0 {
+		s += fmt.Sprintf(" (%+.0f%%)", float64(cur-base)/float64(base)*100)
+	}
+	return s
+}
+
+func countDelta(base, cur int) string {
+	return fmt.Sprintf("%+d", cur-base)
+}
+
+// httpDiff serves the comparison with the base trace.
+func httpDiff(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	if baseLoader.file == "" {
+		http.Error(w, "no base trace, start with -base=file", http.StatusNotFound)
+		return
+	}
+	base, err := buildBaseReport()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	cur, err := st.buildReport()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	d := newTraceDiff(base, cur)
+	d.BaseFile, d.File = baseLoader.file, st.loader.file
+	if err := templDiff.Execute(w, d); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templDiff = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration":  niceDuration,
+	"durationDelta": durationDelta,
+	"countDelta":    countDelta,
+	"changeClass": func(c int) string {
+		switch c {
+		case 1:
+			return "worse"
+		case -1:
+			return "better"
+		}
+		return ""
+	},
+}).Parse(`
+<html>
+<head>
+<title>Comparison with base trace</title>
+<style>
+table {
+  border-collapse: collapse;
+  margin-bottom: 2em;
+}
+td, th {
+  border: 1px solid black;
+  padding: 0.3em 0.5em;
+  vertical-align: top;
+}
+th {
+  background-color: #050505;
+  color: #fff;
+}
+.delta {
+  color: #555;
+  font-size: 85%;
+}
+.worse { background-color: #f4c7c3; }
+.better { background-color: #b7e1cd; }
+</style>
+</head>
+<body>
+<h2>Comparison of {{.File}} with base {{.BaseFile}}</h2>
+<p>Each cell shows the value in the trace and its change from the base trace.
+Statistics are total [min/avg/max]. Cells are highlighted when the mean changed
+by at least 5% and the change is significant at the 95% level (Welch's t-test),
+red if it got worse and green if it got better.</p>
+<p>Trace duration: {{niceDuration .New.Duration}} <span class="delta">{{durationDelta .Base.Duration .New.Duration}}</span></p>
+
+<h3>Goroutines</h3>
+<table>
+<tr>
+<th>Goroutine</th>
+<th>Count</th>
+{{range .Categories}}<th>{{.}}</th>{{end}}
+</tr>
+{{range .Groups}}
+<tr>
+<td>{{.Name}}</td>
+<td>{{.NewN}} <span class="delta">{{countDelta .BaseN .NewN}}</span></td>
+{{range .Stats}}
+<td class="{{changeClass .Change}}">
+{{niceDuration .New.Total}} <span class="delta">{{durationDelta .Base.Total .New.Total}}</span><br>
+{{if .New.Count}}[{{niceDuration .New.Min}}/{{niceDuration .New.Avg}}/{{niceDuration .New.Max}}]{{end}}
+<span class="delta">[{{durationDelta .Base.Min .New.Min}}/{{durationDelta .Base.Avg .New.Avg}}/{{durationDelta .Base.Max .New.Max}}]</span>
+</td>
+{{end}}
+</tr>
+{{end}}
+</table>
+
+{{define "latencies"}}<th>Count</th><th>Complete</th><th>Mean</th><th>p50</th><th>p95</th><th>p99</th><th>Max</th>{{end}}
+
+<h3>User tasks</h3>
+<table>
+<tr><th>Task type</th>{{template "latencies"}}</tr>
+{{range .Tasks}}
+<tr class="{{changeClass .Change}}">
+<td>{{.Type}}</td>
+{{template "latency" .}}
+</tr>
+{{end}}
+</table>
+
+<h3>User regions</h3>
+<table>
+<tr><th>Region type</th><th>Function</th>{{template "latencies"}}</tr>
+{{range .Regions}}
+<tr class="{{changeClass .Change}}">
+<td>{{.Type}}</td>
+<td>{{.Func}}</td>
+{{template "latency" .}}
+</tr>
+{{end}}
+</table>
+
+{{define "latency"}}
+<td>{{.New.Count}} <span class="delta">{{countDelta .Base.Count .New.Count}}</span></td>
+<td>{{.New.Complete}} <span class="delta">{{countDelta .Base.Complete .New.Complete}}</span></td>
+<td>{{niceDuration .New.Mean}} <span class="delta">{{durationDelta .Base.Mean .New.Mean}}</span></td>
+<td>{{niceDuration .New.P50}} <span class="delta">{{durationDelta .Base.P50 .New.P50}}</span></td>
+<td>{{niceDuration .New.P95}} <span class="delta">{{durationDelta .Base.P95 .New.P95}}</span></td>
+<td>{{niceDuration .New.P99}} <span class="delta">{{durationDelta .Base.P99 .New.P99}}</span></td>
+<td>{{niceDuration .New.Max}} <span class="delta">{{durationDelta .Base.Max .New.Max}}</span></td>
+{{end}}
+
+<h3>Minimum mutator utilization</h3>
+<table>
+<tr><th>Window</th><th>Base</th><th>Trace</th><th>Change</th></tr>
+{{range .MMU}}
+<tr class="{{changeClass .Change}}">
+<td>{{.Window}}</td>
+<td>{{printf "%.3f" .Base}}</td>
+<td>{{printf "%.3f" .New}}</td>
+<td>{{printf "%+.3f" .Delta}}</td>
+</tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSignificantChange(t *testing.T) {
+	for _, test := range []struct {
+		n1      int64
+		m1, sd1 float64
+		n2      int64
+		m2, sd2 float64
+		want    bool
+	}{
+		{100, 1000, 100, 100, 1500, 100, true},    // large shift, low noise
+		{100, 1000, 5000, 100, 1500, 5000, false}, // same shift, lost in noise
+		{100, 1000, 1, 100, 1030, 1, false},       // significant but under diffMinChange
+		{1, 1000, 0, 100, 5000, 10, false},        // single sample
+		{3, 1000, 10, 3, 1200, 10, true},          // few samples, clear shift
+		{10, 1000, 0, 10, 2000, 0, true},          // constant samples
+		{10, 0, 0, 10, 500, 50, true},             // from a zero mean
+		{10, 0, 0, 10, 1, 100, false},             // from a zero mean, lost in noise
+		{10, 0, 0, 10, 0, 0, false},               // zero means
+	} {
+		if got := significantChange(test.n1, test.m1, test.sd1, test.n2, test.m2, test.sd2); got != test.want {
+			t.Errorf("significantChange(%+v) = %v, want %v", test, got, test.want)
+		}
+	}
+}
+
+func TestTraceDiff(t *testing.T) {
+	stat := func(avg time.Duration) reportStat {
+		return reportStat{Count: 50, Total: 50 * avg, Avg: avg, Stddev: avg / 10}
+	}
+	base := &report{
+		Goroutines: []reportGroup{
+			{Name: "main.worker", N: 4, ExecTime: stat(time.Millisecond)},
+			{Name: "main.gone", N: 1, ExecTime: stat(time.Millisecond)},
+		},
+		Tasks:   []reportLatency{{Type: "request", Complete: 20, Mean: 10 * time.Millisecond, Stddev: time.Millisecond}},
+		Regions: []reportLatency{{Type: "work", Func: "main.a", Complete: 1}},
+		GC:      reportGC{MMU: []reportMMU{{Window: time.Millisecond, MMU: 0.9}, {Window: 10 * time.Millisecond, MMU: 0.95}}},
+	}
+	cur := &report{
+		Goroutines: []reportGroup{
+			{Name: "main.new", N: 2, ExecTime: stat(time.Millisecond)},
+			{Name: "main.worker", N: 4, ExecTime: stat(2 * time.Millisecond)},
+		},
+		Tasks:   []reportLatency{{Type: "request", Complete: 20, Mean: 5 * time.Millisecond, Stddev: time.Millisecond}},
+		Regions: []reportLatency{{Type: "work", Func: "main.b", Complete: 1}},
+		GC:      reportGC{MMU: []reportMMU{{Window: time.Millisecond, MMU: 0.5}}},
+	}
+	d := newTraceDiff(base, cur)
+
+	var names []string
+	for _, g := range d.Groups {
+		names = append(names, g.Name)
+	}
+	if want := "[main.new main.worker main.gone]"; fmt.Sprint(names) != want {
+		t.Errorf("groups %v, want %s", names, want)
+	}
+	if c := d.Groups[1].Stats[0].Change(); c != 1 {
+		t.Errorf("main.worker execution change %d, want 1 (worse)", c)
+	}
+	if g := d.Groups[2]; g.BaseN != 1 || g.NewN != 0 || g.Stats[0].New.Count != 0 {
+		t.Errorf("main.gone: got %+v, want a group missing from the trace", g)
+	}
+	if len(d.Tasks) != 1 || d.Tasks[0].Change() != -1 {
+		t.Errorf("tasks %+v, want one improved request task", d.Tasks)
+	}
+	if len(d.Regions) != 2 || d.Regions[0].Func != "main.b" || d.Regions[1].Func != "main.a" {
+		t.Errorf("regions %+v, want main.b then main.a", d.Regions)
+	}
+	if len(d.MMU) != 1 || d.MMU[0].Change() != 1 {
+		t.Errorf("MMU %+v, want a single worse 1ms window", d.MMU)
+	}
+	if got, want := durationDelta(time.Millisecond, 1500*time.Microsecond), "+500µs (+50%)"; got != want {
+		t.Errorf("durationDelta = %q, want %q", got, want)
+	}
+}
+
+func TestTraceDiffGroups(t *testing.T) {
+	// Groups match by start PC, and else by name.
+	base := &report{Goroutines: []reportGroup{
+		{Name: "main.f", PC: 10, N: 1},
+		{Name: "main.f", PC: 20, N: 2},
+	}}
+	cur := &report{Goroutines: []reportGroup{
+		{Name: "main.f", PC: 20, N: 3},
+		{Name: "main.f", PC: 30, N: 4},
+		{Name: "main.g", PC: 10, N: 5},
+	}}
+	d := newTraceDiff(base, cur)
+	var got []string
+	for _, g := range d.Groups {
+		got = append(got, fmt.Sprintf("%s %d->%d", g.Name, g.BaseN, g.NewN))
+	}
+	if want := "[main.f 2->3 main.f 1->4 main.g 0->5]"; fmt.Sprint(got) != want {
+		t.Errorf("groups %v, want %s", got, want)
+	}
+}
@@ -23,6 +23,7 @@ Supported profile types are:
 	- sync: synchronization blocking profile
 	- syscall: syscall blocking profile
 	- sched: scheduler latency profile
+	- assist: GC mark assist profile
 
 Then, you can use the pprof tool to analyze the profile:
 	go tool pprof TYPE.pprof
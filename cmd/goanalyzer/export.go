@@ -0,0 +1,304 @@
+// CSV and JSON export of the goroutine and user annotation pages.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The records below are the exported forms of the data behind the
+// pages. Durations are in nanoseconds, and timestamps are nanoseconds
+// since the start of the trace.
+
+// exportStat is a trace.GExecutionStat.
+type exportStat struct {
+	TotalTime      reportStat
+	ExecTime       reportStat
+	IOTime         reportStat
+	BlockTime      reportStat
+	SyscallTime    reportStat
+	SchedWaitTime  reportStat
+	SweepTime      reportStat
+	GCTime         reportStat
+	MarkAssistTime reportStat
+}
+
+func newExportStat(s trace.GExecutionStat) exportStat {
+	return exportStat{
+		TotalTime:      newReportStat(s.TotalTime),
+		ExecTime:       newReportStat(s.ExecTime),
+		IOTime:         newReportStat(s.IOTime),
+		BlockTime:      newReportStat(s.BlockTime),
+		SyscallTime:    newReportStat(s.SyscallTime),
+		SchedWaitTime:  newReportStat(s.SchedWaitTime),
+		SweepTime:      newReportStat(s.SweepTime),
+		GCTime:         newReportStat(s.GCTime),
+		MarkAssistTime: newReportStat(s.MarkAssistTime),
+	}
+}
+
+// exportGroup is a goroutine group of /goroutines, see gtype.
+type exportGroup struct {
+	ID         uint64 // start PC
+	Name       string
+	N          int
+	Goroutines []uint64
+	exportStat
+}
+
+func newExportGroup(g gtype) exportGroup {
+	e := exportGroup{ID: g.ID, Name: g.Name, N: g.N, exportStat: newExportStat(g.GExecutionStat)}
+	for id := range g.GIDS {
+		e.Goroutines = append(e.Goroutines, id)
+	}
+	sort.Slice(e.Goroutines, func(i, j int) bool { return e.Goroutines[i] < e.Goroutines[j] })
+	return e
+}
+
+// exportGoroutine is a goroutine of /goroutine, see trace.GDesc.
+type exportGoroutine struct {
+	ID           uint64
+	Name         string
+	PC           uint64
+	ParentID     uint64
+	CreationTime time.Duration
+	StartTime    time.Duration
+	EndTime      time.Duration
+	exportStat
+}
+
+func newExportGoroutine(g *trace.GDesc, base int64) exportGoroutine {
+	rel := func(ts int64) time.Duration {
+		if ts == 0 {
+			return 0
+		}
+		return time.Duration(ts - base)
+	}
+	return exportGoroutine{
+		ID:           g.ID,
+		Name:         g.Name,
+		PC:           g.PC,
+		ParentID:     g.ParentID,
+		CreationTime: rel(g.CreationTime),
+		StartTime:    rel(g.StartTime),
+		EndTime:      rel(g.EndTime),
+		exportStat:   newExportStat(g.GExecutionStat),
+	}
+}
+
+// exportTask is a task of /usertask, see taskDesc.
+type exportTask struct {
+	ID         uint64
+	Type       string
+	ParentID   uint64
+	Complete   bool
+	Start, End time.Duration
+	Duration   time.Duration
+	GCTime     time.Duration
+	Goroutines int
+	Regions    int
+	// Breakdown of the critical path of complete tasks.
+	CritPath map[string]time.Duration `json:",omitempty"`
+}
+
+// exportTasks returns the tasks matching filter, sorted by duration as
+// on the page.
+func (st *traceState) exportTasks(tasks allTasks, filter *taskFilter, gcEvents []*trace.Event) []exportTask {
+	base := st.firstTimestamp()
+	var list []exportTask
+	for _, task := range tasks {
+		if !filter.match(task) {
+			continue
+		}
+		e := exportTask{
+			ID:         task.id,
+			Type:       task.name,
+			Complete:   task.complete(),
+			Start:      time.Duration(task.firstTimestamp() - base),
+			End:        time.Duration(task.endTimestamp() - base),
+			Duration:   task.duration(),
+			GCTime:     task.overlappingGCDuration(gcEvents),
+			Goroutines: len(task.goroutines),
+			Regions:    len(task.regions),
+		}
+		if task.parent != nil {
+			e.ParentID = task.parent.id
+		}
+		if p := st.critIndex.taskCritPath(task); p != nil {
+			e.CritPath = make(map[string]time.Duration)
+			for _, t := range p.Breakdown() {
+				e.CritPath[t.Kind.String()] = t.Duration
+			}
+		}
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Duration != list[j].Duration {
+			return list[i].Duration < list[j].Duration
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// exportRegion is a region of /userregion, see regionDesc.
+type exportRegion struct {
+	Type       string
+	G          uint64
+	TaskID     uint64
+	Complete   bool
+	Start, End time.Duration
+	Duration   time.Duration
+	exportStat
+}
+
+func newExportRegion(r regionDesc, base int64) exportRegion {
+	return exportRegion{
+		Type:       r.Name,
+		G:          r.G,
+		TaskID:     r.TaskID,
+		Complete:   r.Start != nil && r.End != nil,
+		Start:      time.Duration(r.firstTimestamp() - base),
+		End:        time.Duration(r.lastTimestamp() - base),
+		Duration:   r.duration(),
+		exportStat: newExportStat(r.GExecutionStat),
+	}
+}
+
+// exportTaskTypes returns the task types of /usertasks.
+func exportTaskTypes(stats []taskStats) []reportLatency {
+	var list []reportLatency
+	for _, s := range stats {
+		l := newReportLatency(s.durations)
+		l.Type = s.Type
+		l.Count = s.Count
+		list = append(list, l)
+	}
+	return list
+}
+
+// exportRegionTypes returns the region types of /userregions.
+func exportRegionTypes(stats []regionStats) []reportLatency {
+	var list []reportLatency
+	for _, s := range stats {
+		l := newReportLatency(s.durations)
+		l.Type = s.Type
+		l.Func = s.Frame.Fn
+		l.Count = s.Histogram.Count
+		list = append(list, l)
+	}
+	return list
+}
+
+// serveExport serves the records returned by records, a slice of
+// structs, if the format parameter of r is csv or json, and reports
+// whether it handled the request. Pages serve HTML otherwise. The
+// records are not computed for HTML.
+func serveExport(w http.ResponseWriter, r *http.Request, name string, records func() interface{}) bool {
+	var err error
+	switch format := r.FormValue("format"); format {
+	case "":
+		return false
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".csv"))
+		err = writeCSV(w, records())
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(records())
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q (want csv or json)", format), http.StatusBadRequest)
+		return true
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to export: %v", err), http.StatusInternalServerError)
+	}
+	return true
+}
+
+// writeCSV writes records, a slice of structs, as CSV with a header
+// row. Nested and embedded structs are flattened into columns, with
+// the names of nested fields prefixed by the name of their struct,
+// like ExecTime.Total. Durations are in nanoseconds, and slices and
+// maps are written as space-separated lists.
+func writeCSV(w io.Writer, records interface{}) error {
+	v := reflect.ValueOf(records)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot export %T as CSV", records)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader(v.Type().Elem(), ""))
+	for i := 0; i < v.Len(); i++ {
+		cw.Write(csvRow(v.Index(i), nil))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func csvHeader(t reflect.Type, prefix string) []string {
+	var header []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Anonymous:
+			header = append(header, csvHeader(f.Type, prefix)...)
+		case f.PkgPath != "":
+			// unexported
+		case f.Type.Kind() == reflect.Struct:
+			header = append(header, csvHeader(f.Type, prefix+f.Name+".")...)
+		default:
+			header = append(header, prefix+f.Name)
+		}
+	}
+	return header
+}
+
+func csvRow(v reflect.Value, row []string) []string {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.Anonymous, f.PkgPath == "" && f.Type.Kind() == reflect.Struct:
+			row = csvRow(v.Field(i), row)
+		case f.PkgPath != "":
+		default:
+			row = append(row, csvValue(v.Field(i)))
+		}
+	}
+	return row
+}
+
+func csvValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		var parts []string
+		for i := 0; i < v.Len(); i++ {
+			parts = append(parts, csvValue(v.Index(i)))
+		}
+		return strings.Join(parts, " ")
+	case reflect.Map:
+		var parts []string
+		for _, k := range v.MapKeys() {
+			parts = append(parts, fmt.Sprintf("%v=%s", k, csvValue(v.MapIndex(k))))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, " ")
+	}
+	if v.Type() == durationType {
+		return fmt.Sprint(v.Int())
+	}
+	return fmt.Sprint(v.Interface())
+}
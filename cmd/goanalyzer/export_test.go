@@ -0,0 +1,151 @@
+// +build !js
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	rtrace "runtime/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	if err := traceProgram(t, prog0, "TestExport"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+	mainTrace.analyzeGoroutines(events)
+	var pc string
+	for _, g := range mainTrace.gs {
+		if strings.HasSuffix(g.Name, "prog0.func1") {
+			pc = fmt.Sprint(g.PC)
+		}
+	}
+
+	for _, tc := range []struct {
+		url    string
+		column string // in the CSV header
+		value  string // in some CSV row
+	}{
+		{"/goroutines", "ExecTime.Total", "prog0.func1"},
+		{"/goroutine?id=" + pc, "SchedWaitTime.P99", "prog0.func1"},
+		{"/usertasks", "P50", "task0"},
+		{"/usertask?type=task0", "CritPath", "task0"},
+		{"/userregions", "Func", "task0.region1"},
+		{"/userregion?type=task0.region1", "BlockTime.Count", "task0.region1"},
+	} {
+		for _, format := range []string{"csv", "json"} {
+			sep := "?"
+			if strings.Contains(tc.url, "?") {
+				sep = "&"
+			}
+			url := tc.url + sep + "format=" + format
+			w := httptest.NewRecorder()
+			http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+			if w.Code != http.StatusOK {
+				t.Errorf("%s: status %d: %s", url, w.Code, w.Body)
+				continue
+			}
+			switch format {
+			case "csv":
+				rows, err := csv.NewReader(w.Body).ReadAll()
+				if err != nil {
+					t.Errorf("%s: %v", url, err)
+					continue
+				}
+				if len(rows) < 2 {
+					t.Errorf("%s: %d rows, want a header and records", url, len(rows))
+					continue
+				}
+				if !contains(rows[0], tc.column) {
+					t.Errorf("%s: no column %s in %v", url, tc.column, rows[0])
+				}
+				var found bool
+				for _, row := range rows[1:] {
+					if len(row) != len(rows[0]) {
+						t.Errorf("%s: row %v does not match the header", url, row)
+					}
+					for _, v := range row {
+						found = found || strings.HasSuffix(v, tc.value)
+					}
+				}
+				if !found {
+					t.Errorf("%s: no %s in %v", url, tc.value, rows)
+				}
+			case "json":
+				var records []map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil {
+					t.Errorf("%s: %v", url, err)
+				} else if len(records) == 0 {
+					t.Errorf("%s: no records", url)
+				}
+			}
+		}
+	}
+
+	w := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", "/goroutines?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("unknown format: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestExportRegions(t *testing.T) {
+	prog := func() {
+		for _, d := range []time.Duration{1, 3, 2} {
+			rtrace.WithRegion(context.Background(), "exportRegion", func() {
+				time.Sleep(d * time.Millisecond)
+			})
+		}
+	}
+	if err := traceProgram(t, prog, "TestExportRegions"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+
+	for _, url := range []string{
+		"/userregion?type=exportRegion&format=json",
+		"/userregion?type=exportRegion&format=json&sortby=BlockTime",
+		"/userregion?type=exportRegion&format=csv",
+		"/userregion?type=exportRegion",
+	} {
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d: %s", url, w.Code, w.Body)
+		}
+	}
+
+	// The regions are sorted by decreasing total time by default.
+	w := httptest.NewRecorder()
+	http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", "/userregion?type=exportRegion&format=json", nil))
+	var regions []exportRegion
+	if err := json.Unmarshal(w.Body.Bytes(), &regions); err != nil {
+		t.Fatalf("failed to decode regions: %v", err)
+	}
+	if len(regions) != 3 {
+		t.Fatalf("%d regions, want 3", len(regions))
+	}
+	for i := 1; i < len(regions); i++ {
+		if regions[i].Duration > regions[i-1].Duration {
+			t.Errorf("region of %v after one of %v, want decreasing total time", regions[i].Duration, regions[i-1].Duration)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
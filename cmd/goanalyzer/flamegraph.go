@@ -0,0 +1,345 @@
+// In-process rendering of pprof-like profiles as a flame graph and a
+// table of the top functions.
+
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/pprof/profile"
+)
+
+// profileTopDefault is the number of functions in the top table.
+const profileTopDefault = 30
+
+// flameNode is a function in the flame graph: the samples whose stacks
+// start with the path from the root to the node.
+type flameNode struct {
+	Name     string       `json:"n"`
+	Value    int64        `json:"v"` // delay in nanoseconds
+	Count    int64        `json:"c"` // events
+	Children []*flameNode `json:"ch,omitempty"`
+
+	children map[string]*flameNode
+}
+
+func (n *flameNode) child(name string) *flameNode {
+	c := n.children[name]
+	if c == nil {
+		if n.children == nil {
+			n.children = make(map[string]*flameNode)
+		}
+		c = &flameNode{Name: name}
+		n.children[name] = c
+		n.Children = append(n.Children, c)
+	}
+	return c
+}
+
+// sort orders the children of every node by name, as in other flame
+// graphs, so that the same stacks are in the same place across profiles.
+func (n *flameNode) sort() {
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
+
+// profileValues returns the index of the count and the delay values
+// of the samples of p, as built by buildProfile.
+func profileValues(p *profile.Profile) (count, delay int) {
+	count, delay = 0, len(p.SampleType)-1
+	for i, st := range p.SampleType {
+		switch st.Type {
+		case "contentions":
+			count = i
+		case "delay":
+			delay = i
+		}
+	}
+	return count, delay
+}
+
+// sampleFuncs returns the functions of the stack of s, outermost first,
+// with the functions inlined at a location after the one they are
+// inlined into.
+func sampleFuncs(s *profile.Sample) []string {
+	var funcs []string
+	for i := len(s.Location) - 1; i >= 0; i-- {
+		lines := s.Location[i].Line
+		for j := len(lines) - 1; j >= 0; j-- {
+			name := "?"
+			if fn := lines[j].Function; fn != nil {
+				name = fn.Name
+			}
+			funcs = append(funcs, name)
+		}
+	}
+	return funcs
+}
+
+// newFlameGraph returns the root of the flame graph of p.
+func newFlameGraph(p *profile.Profile) *flameNode {
+	ci, di := profileValues(p)
+	root := &flameNode{Name: "total"}
+	for _, s := range p.Sample {
+		count, delay := s.Value[ci], s.Value[di]
+		n := root
+		n.Count += count
+		n.Value += delay
+		for _, fn := range sampleFuncs(s) {
+			n = n.child(fn)
+			n.Count += count
+			n.Value += delay
+		}
+	}
+	root.sort()
+	return root
+}
+
+// profileTopEntry is a row of the top table.
+type profileTopEntry struct {
+	Func      string
+	Flat, Cum time.Duration
+	Count     int64 // events with the function on the stack
+}
+
+// profileTop returns the n functions with the largest flat delay, that
+// is, the delay of the events blocked directly in the function.
+func profileTop(p *profile.Profile, n int) []profileTopEntry {
+	ci, di := profileValues(p)
+	byFunc := make(map[string]*profileTopEntry)
+	entry := func(fn string) *profileTopEntry {
+		e := byFunc[fn]
+		if e == nil {
+			e = &profileTopEntry{Func: fn}
+			byFunc[fn] = e
+		}
+		return e
+	}
+	for _, s := range p.Sample {
+		funcs := sampleFuncs(s)
+		if len(funcs) == 0 {
+			continue
+		}
+		delay := time.Duration(s.Value[di])
+		entry(funcs[len(funcs)-1]).Flat += delay
+		// Count recursive functions once per sample.
+		seen := make(map[string]bool)
+		for _, fn := range funcs {
+			if !seen[fn] {
+				seen[fn] = true
+				e := entry(fn)
+				e.Cum += delay
+				e.Count += s.Value[ci]
+			}
+		}
+	}
+	top := make([]profileTopEntry, 0, len(byFunc))
+	for _, e := range byFunc {
+		top = append(top, *e)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Flat != top[j].Flat {
+			return top[i].Flat > top[j].Flat
+		}
+		if top[i].Cum != top[j].Cum {
+			return top[i].Cum > top[j].Cum
+		}
+		return top[i].Func < top[j].Func
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// serveProfileHTML serves the flame graph and top table of p.
+func serveProfileHTML(w http.ResponseWriter, r *http.Request, title string, p *profile.Profile) {
+	n := profileTopDefault
+	if s := r.FormValue("top"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			http.Error(w, fmt.Sprintf("invalid top parameter %q", s), http.StatusBadRequest)
+			return
+		}
+		n = v
+	}
+	link := func(key string) string {
+		q := url.Values{}
+		for k, v := range r.URL.Query() {
+			q[k] = v
+		}
+		q.Set(key, "1")
+		return r.URL.Path + "?" + q.Encode()
+	}
+	flame := newFlameGraph(p)
+	data := struct {
+		Title   string
+		Total   time.Duration
+		Count   int64
+		Flame   *flameNode
+		Top     []profileTopEntry
+		RawURL  string
+		SVGURL  string
+		TopSize int
+	}{
+		Title:   title,
+		Total:   time.Duration(flame.Value),
+		Count:   flame.Count,
+		Flame:   flame,
+		Top:     profileTop(p, n),
+		RawURL:  link("raw"),
+		SVGURL:  link("svg"),
+		TopSize: n,
+	}
+	if err := templProfile.Execute(w, data); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templProfile = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+	"percent": func(d, total time.Duration) string {
+		return fmt.Sprintf("%.1f%%", percentOf(d, total))
+	},
+}).Parse(`
+<html>
+<head>
+<title>{{.Title}}</title>
+<style>
+body {
+  font-family: sans-serif;
+}
+#flame {
+  position: relative;
+  width: 100%;
+  margin-bottom: 2em;
+}
+.frame {
+  position: absolute;
+  height: 17px;
+  box-sizing: border-box;
+  border: 1px solid white;
+  font-size: 12px;
+  line-height: 15px;
+  padding: 0 2px;
+  overflow: hidden;
+  white-space: nowrap;
+  text-overflow: ellipsis;
+  cursor: pointer;
+}
+.frame:hover {
+  border-color: black;
+}
+table {
+  border-collapse: collapse;
+}
+td, th {
+  border: 1px solid black;
+  padding: 0.2em 0.5em;
+  text-align: right;
+}
+td.fn {
+  text-align: left;
+  font-family: monospace;
+}
+th {
+  background-color: #050505;
+  color: #fff;
+}
+</style>
+</head>
+<body>
+<h2>{{.Title}}</h2>
+<p>Total delay {{niceDuration .Total}} in {{.Count}} events.
+<a href="{{.RawURL}}" download="profile.pb.gz">Download profile</a> |
+<a href="{{.SVGURL}}">Graph</a> (requires go tool pprof and Graphviz)</p>
+
+{{if .Count}}
+<h3>Flame graph</h3>
+<p>Click a function to zoom in, and <a href="#" onclick="render(data); return false;">reset zoom</a>.</p>
+<div id="flame"></div>
+{{end}}
+
+<h3>Top {{.TopSize}} functions</h3>
+<table>
+<tr><th>Flat</th><th>Flat%</th><th>Cum</th><th>Cum%</th><th>Events</th><th>Function</th></tr>
+{{range .Top}}
+<tr>
+<td>{{niceDuration .Flat}}</td>
+<td>{{percent .Flat $.Total}}</td>
+<td>{{niceDuration .Cum}}</td>
+<td>{{percent .Cum $.Total}}</td>
+<td>{{.Count}}</td>
+<td class="fn">{{.Func}}</td>
+</tr>
+{{end}}
+</table>
+
+<script>
+'use strict';
+var data = {{.Flame}};
+var rowHeight = 17;
+
+function color(name) {
+  var h = 0;
+  for (var i = 0; i < name.length; i++) {
+    h = (h * 31 + name.charCodeAt(i)) >>> 0;
+  }
+  return 'hsl(' + (h % 50) + ', 80%, ' + (60 + h % 20) + '%)';
+}
+
+function duration(ns) {
+  if (ns < 1e4) return ns + 'ns';
+  if (ns < 1e7) return (ns / 1e3).toFixed(1) + 'µs';
+  if (ns < 1e10) return (ns / 1e6).toFixed(1) + 'ms';
+  return (ns / 1e9).toFixed(1) + 's';
+}
+
+// render lays out the subtree of node, with node as wide as the page.
+function render(node) {
+  var flame = document.getElementById('flame');
+  flame.innerHTML = '';
+  var depth = layout(flame, node, 0, 100, 0);
+  flame.style.height = (depth * rowHeight) + 'px';
+}
+
+function layout(flame, node, x, width, depth) {
+  var div = document.createElement('div');
+  div.className = 'frame';
+  div.style.left = x + '%';
+  div.style.width = width + '%';
+  div.style.top = (depth * rowHeight) + 'px';
+  div.style.backgroundColor = color(node.n);
+  div.title = node.n + '\n' + duration(node.v) + ' (' + (100 * node.v / data.v).toFixed(2) + '%), ' + node.c + ' events';
+  div.textContent = node.n;
+  div.onclick = function() { render(node); };
+  flame.appendChild(div);
+
+  var maxDepth = depth + 1;
+  var cx = x;
+  (node.ch || []).forEach(function(c) {
+    var cw = node.v > 0 ? width * c.v / node.v : 0;
+    if (cw >= 0.1) {
+      maxDepth = Math.max(maxDepth, layout(flame, c, cx, cw, depth + 1));
+    }
+    cx += cw;
+  });
+  return maxDepth;
+}
+
+if (data.c > 0) {
+  render(data);
+}
+</script>
+</body>
+</html>
+`))
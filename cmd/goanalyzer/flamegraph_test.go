@@ -0,0 +1,77 @@
+// +build !js
+
+package main
+
+import (
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFlameGraph(t *testing.T) {
+	frame := func(pc uint64, fn string) *trace.Frame {
+		return &trace.Frame{PC: pc, Fn: fn, File: "main.go", Line: int(pc)}
+	}
+	// Stacks are innermost first, as in the trace.
+	p := buildProfile(map[uint64]Record{
+		1: {stk: []*trace.Frame{frame(3, "main.lock"), frame(2, "main.work"), frame(1, "main.main")}, n: 2, time: 300},
+		2: {stk: []*trace.Frame{frame(4, "main.recv"), frame(2, "main.work"), frame(1, "main.main")}, n: 1, time: 100},
+		3: {stk: []*trace.Frame{frame(4, "main.recv"), frame(1, "main.main")}, n: 1, time: 600},
+	})
+
+	root := newFlameGraph(p)
+	if root.Value != 1000 || root.Count != 4 {
+		t.Errorf("root: got value %d and count %d, want 1000 and 4", root.Value, root.Count)
+	}
+	if len(root.Children) != 1 || root.Children[0].Name != "main.main" {
+		t.Fatalf("root children %v, want main.main", root.Children)
+	}
+	main := root.Children[0]
+	if len(main.Children) != 2 || main.Children[0].Name != "main.recv" || main.Children[1].Name != "main.work" {
+		t.Fatalf("main.main children %v, want main.recv and main.work", main.Children)
+	}
+	if work := main.Children[1]; work.Value != 400 || work.Count != 3 || len(work.Children) != 2 {
+		t.Errorf("main.work: got %+v, want value 400, count 3 and two children", work)
+	}
+
+	top := profileTop(p, 2)
+	if len(top) != 2 {
+		t.Fatalf("got %d top functions, want 2", len(top))
+	}
+	if top[0].Func != "main.recv" || top[0].Flat != 700 || top[0].Cum != 700 {
+		t.Errorf("top[0] = %+v, want main.recv with flat and cum 700ns", top[0])
+	}
+	if top[1].Func != "main.lock" || top[1].Flat != 300 || top[1].Count != 2 {
+		t.Errorf("top[1] = %+v, want main.lock with flat 300ns in 2 events", top[1])
+	}
+}
+
+func TestServeProfile(t *testing.T) {
+	if err := traceProgram(t, prog0, "TestServeProfile"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	h := serveProfile("Synchronization blocking profile", pprofByGoroutine(pprofBlockEvent))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/block", nil))
+	body := w.Body.String()
+	if w.Code != 200 || !strings.Contains(body, "Synchronization blocking profile") || !strings.Contains(body, "Top 30 functions") {
+		t.Errorf("got status %d and page:\n%s", w.Code, body)
+	}
+	if !strings.Contains(body, `href="/block?raw=1"`) {
+		t.Errorf("page has no link to the raw profile:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/block?raw=1", nil))
+	if w.Code != 200 || w.Header().Get("Content-Type") != "application/octet-stream" || w.Body.Len() == 0 {
+		t.Errorf("raw profile: got status %d, content type %q and %d bytes", w.Code, w.Header().Get("Content-Type"), w.Body.Len())
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/block?top=x", nil))
+	if w.Code != 400 {
+		t.Errorf("bad top parameter: got status %d, want 400", w.Code)
+	}
+}
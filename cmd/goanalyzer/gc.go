@@ -0,0 +1,348 @@
+// GC cycles.
+
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/gc", httpGC)
+}
+
+// gcCycle is a GC cycle of the trace. Times are relative to the start
+// of the trace, and durations are encoded in nanoseconds in JSON.
+type gcCycle struct {
+	Seq        uint64
+	Start, End time.Duration // End is the end of the trace for a cycle in progress
+	Duration   time.Duration
+	Complete   bool
+	STW        []gcSTW // stop-the-world pauses overlapping the cycle
+	STWTime    time.Duration
+	// Heap in use at the start and the end of the cycle, and the heap
+	// goal the cycle was started for, if the trace has them.
+	HeapStart, HeapEnd uint64
+	NextGC             uint64
+	// Mark assists started during the cycle, and the number of
+	// goroutines doing them.
+	AssistTime time.Duration
+	Assists    int
+	// Bytes swept and reclaimed from the end of the cycle to the end of
+	// the sweep termination of the next one, in the sweep of the heap
+	// marked by the cycle.
+	Swept, Reclaimed uint64
+}
+
+// gcSTW is a stop-the-world pause.
+type gcSTW struct {
+	Kind     string // like "GC mark termination", empty before Go 1.10
+	Start    time.Duration
+	Duration time.Duration
+}
+
+// computeGCCycles returns the GC cycles of the non-empty trace in time
+// order.
+func computeGCCycles(events []*trace.Event) []*gcCycle {
+	base, last := events[0].Ts, events[len(events)-1].Ts
+	var cycles []*gcCycle
+	var stws, assists, sweeps []*trace.Event
+	var heap, nextGC uint64
+	var cur *gcCycle // in progress
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvGCStart:
+			c := &gcCycle{Seq: ev.Args[0], Start: time.Duration(ev.Ts - base), End: time.Duration(last - base), HeapStart: heap, NextGC: nextGC}
+			if ev.Link != nil {
+				c.End = time.Duration(ev.Link.Ts - base)
+				c.Complete = true
+			}
+			c.Duration = c.End - c.Start
+			cycles = append(cycles, c)
+			cur = c
+		case trace.EvGCDone:
+			if cur != nil {
+				cur.HeapEnd = heap
+				cur = nil
+			}
+		case trace.EvHeapAlloc:
+			heap = ev.Args[0]
+		case trace.EvNextGC:
+			nextGC = ev.Args[0]
+		case trace.EvGCSTWStart:
+			if ev.Link != nil {
+				stws = append(stws, ev)
+			}
+		case trace.EvGCMarkAssistStart:
+			if ev.Link != nil {
+				assists = append(assists, ev)
+			}
+		case trace.EvGCSweepDone:
+			sweeps = append(sweeps, ev)
+		}
+	}
+	if cur != nil {
+		cur.HeapEnd = heap
+	}
+
+	// Mark assists belong to the cycle in progress when they start.
+	assisting := make(map[*gcCycle]map[uint64]bool)
+	for _, ev := range assists {
+		t := time.Duration(ev.Ts - base)
+		i := sort.Search(len(cycles), func(i int) bool { return cycles[i].End >= t })
+		if i == len(cycles) || cycles[i].Start > t {
+			continue
+		}
+		c := cycles[i]
+		c.AssistTime += time.Duration(ev.Link.Ts - ev.Ts)
+		if assisting[c] == nil {
+			assisting[c] = make(map[uint64]bool)
+		}
+		assisting[c][ev.G] = true
+	}
+	for c, gs := range assisting {
+		c.Assists = len(gs)
+	}
+
+	// A cycle's pauses, for sweep and mark termination, start before or
+	// end after the cycle's own events: assign them by overlap.
+	i := 0
+	for _, ev := range stws {
+		start, end := time.Duration(ev.Ts-base), time.Duration(ev.Link.Ts-base)
+		for i < len(cycles) && cycles[i].End < start {
+			i++
+		}
+		if i == len(cycles) {
+			break
+		}
+		c := cycles[i]
+		if end < c.Start {
+			continue
+		}
+		s := gcSTW{Start: start, Duration: end - start}
+		if len(ev.SArgs) > 0 {
+			s.Kind = ev.SArgs[0]
+		}
+		c.STW = append(c.STW, s)
+		c.STWTime += s.Duration
+	}
+
+	// Sweeping follows the cycle that marked the heap it sweeps, up to
+	// the sweep termination of the next cycle, which finishes it.
+	sweepTerm := make([]time.Duration, len(cycles))
+	for i, c := range cycles {
+		sweepTerm[i] = c.Start
+		for _, s := range c.STW {
+			if s.Kind == "GC sweep termination" {
+				sweepTerm[i] = s.Start + s.Duration
+				break
+			}
+		}
+	}
+	for _, ev := range sweeps {
+		t := time.Duration(ev.Ts - base)
+		i := sort.Search(len(cycles), func(i int) bool { return sweepTerm[i] >= t }) - 1
+		if i < 0 {
+			continue
+		}
+		cycles[i].Swept += ev.Args[0]
+		cycles[i].Reclaimed += ev.Args[1]
+	}
+	return cycles
+}
+
+// stwByKind returns the total duration of the pauses of the cycle by
+// kind, in the order of their first pause.
+func (c *gcCycle) stwByKind() []gcSTW {
+	var kinds []gcSTW
+	for _, s := range c.STW {
+		i := 0
+		for i < len(kinds) && kinds[i].Kind != s.Kind {
+			i++
+		}
+		if i == len(kinds) {
+			kinds = append(kinds, gcSTW{Kind: s.Kind, Start: s.Start})
+		}
+		kinds[i].Duration += s.Duration
+	}
+	return kinds
+}
+
+// sortGCCycles sorts cycles by decreasing value of the field sortby of
+// gcCycle, or in time order if there is no such numeric field.
+func sortGCCycles(cycles []*gcCycle, sortby string) {
+	f, ok := reflect.TypeOf(gcCycle{}).FieldByName(sortby)
+	if !ok || sortby == "Start" || sortby == "End" || sortby == "Seq" {
+		return
+	}
+	var value func(c *gcCycle) float64
+	switch f.Type.Kind() {
+	case reflect.Int, reflect.Int64:
+		value = func(c *gcCycle) float64 { return float64(reflect.ValueOf(c).Elem().FieldByIndex(f.Index).Int()) }
+	case reflect.Uint64:
+		value = func(c *gcCycle) float64 { return float64(reflect.ValueOf(c).Elem().FieldByIndex(f.Index).Uint()) }
+	default:
+		return
+	}
+	sort.SliceStable(cycles, func(i, j int) bool {
+		return value(cycles[i]) > value(cycles[j])
+	})
+}
+
+// gcRow is a cycle of the GC page.
+type gcRow struct {
+	*gcCycle
+	Kinds []gcSTW // STW by kind
+	URL   string  // of the cycle in the trace viewer
+}
+
+// gcCyclesDuring returns the GC cycles of the trace that overlap the
+// window.
+func gcCyclesDuring(events []*trace.Event, w traceWindow) []*gcCycle {
+	if len(events) == 0 {
+		return nil
+	}
+	base := events[0].Ts
+	var cycles []*gcCycle
+	for _, c := range computeGCCycles(events) {
+		if base+int64(c.End) >= w.from && base+int64(c.Start) <= w.to {
+			cycles = append(cycles, c)
+		}
+	}
+	return cycles
+}
+
+// gcTotal sums the cycles of the GC page.
+type gcTotal struct {
+	Duration, STWTime, AssistTime time.Duration
+}
+
+// httpGC serves the table of the GC cycles.
+func httpGC(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	var base int64
+	if len(events) > 0 {
+		base = events[0].Ts
+	}
+	cycles := gcCyclesDuring(events, win)
+	if serveExport(w, r, "gc", func() interface{} { return cycles }) {
+		return
+	}
+
+	var total gcTotal
+	for _, c := range cycles {
+		total.Duration += c.Duration
+		total.STWTime += c.STWTime
+		total.AssistTime += c.AssistTime
+	}
+	sortby := r.FormValue("sortby")
+	sortGCCycles(cycles, sortby)
+	rows := make([]gcRow, len(cycles))
+	for i, c := range cycles {
+		rows[i] = gcRow{c, c.stwByKind(), st.viewerURL(base+int64(c.Start), base+int64(c.End))}
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	err = templGC.Execute(w, struct {
+		Rows  []gcRow
+		Total gcTotal
+	}{rows, total})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templGC = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+	"size":         func(n uint64) string { return niceSize(int64(n)) },
+}).Parse(`
+<!DOCTYPE html>
+<title>GC cycles</title>
+<style>
+body {
+  font-family: sans-serif;
+}
+th {
+  background-color: #050505;
+  color: #fff;
+  cursor: pointer;
+}
+table {
+  border-collapse: collapse;
+}
+.details tr:hover {
+  background-color: #f2f2f2;
+}
+.details td {
+  text-align: right;
+  border: 1px solid #000;
+  padding: 0.2em 0.5em;
+}
+.details td.kinds {
+  text-align: left;
+}
+</style>
+
+<script>
+function reloadTable(key, value) {
+  let params = new URLSearchParams(window.location.search);
+  params.set(key, value);
+  window.location.search = params.toString();
+}
+` + windowScript + `
+</script>
+
+<h2>GC cycles</h2>
+<p>{{len .Rows}} cycles, taking {{niceDuration .Total.Duration}} in total,
+with {{niceDuration .Total.STWTime}} stopped the world and {{niceDuration .Total.AssistTime}}
+of mark assists. Click on a column to sort by it, and on a cycle to show it in the trace viewer.
+Heap sizes are missing from traces that do not record them.
+Download as <a href="/gc?format=csv">CSV</a> or <a href="/gc?format=json">JSON</a>.</p>
+
+<table class="details">
+<tr>
+<th onclick="reloadTable('sortby', 'Start')"> Cycle </th>
+<th onclick="reloadTable('sortby', 'Start')"> Start </th>
+<th onclick="reloadTable('sortby', 'Duration')"> Duration </th>
+<th onclick="reloadTable('sortby', 'STWTime')"> STW </th>
+<th> STW by kind </th>
+<th onclick="reloadTable('sortby', 'HeapStart')"> Heap at start </th>
+<th onclick="reloadTable('sortby', 'HeapEnd')"> Heap at end </th>
+<th onclick="reloadTable('sortby', 'NextGC')"> Heap goal </th>
+<th onclick="reloadTable('sortby', 'AssistTime')"> Mark assists </th>
+<th onclick="reloadTable('sortby', 'Assists')"> Assisting goroutines </th>
+<th onclick="reloadTable('sortby', 'Swept')"> Swept </th>
+</tr>
+{{range .Rows}}
+<tr>
+<td><a href="{{.URL}}">{{.Seq}}</a>{{if not .Complete}} (in progress){{end}}</td>
+<td>{{niceDuration .Start}}</td>
+<td>{{niceDuration .Duration}}</td>
+<td>{{niceDuration .STWTime}}</td>
+<td class="kinds">{{range .Kinds}}{{if .Kind}}{{.Kind}}{{else}}STW{{end}}: {{niceDuration .Duration}}<br>{{end}}</td>
+<td>{{if .HeapStart}}{{size .HeapStart}}{{end}}</td>
+<td>{{if .HeapEnd}}{{size .HeapEnd}}{{end}}</td>
+<td>{{if .NextGC}}{{size .NextGC}}{{end}}</td>
+<td>{{niceDuration .AssistTime}}</td>
+<td>{{.Assists}}</td>
+<td>{{size .Swept}}</td>
+</tr>
+{{end}}
+</table>
+`))
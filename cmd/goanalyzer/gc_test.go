@@ -0,0 +1,107 @@
+// +build !js
+
+package main
+
+import (
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+var gcSink [][]byte
+
+func TestGCCycles(t *testing.T) {
+	prog := func() {
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 1000; j++ {
+				gcSink = append(gcSink, make([]byte, 1024))
+			}
+			gcSink = nil
+			runtime.GC()
+		}
+	}
+	if err := traceProgram(t, prog, "TestGCCycles"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+	cycles := computeGCCycles(events)
+	if len(cycles) < 3 {
+		t.Fatalf("%d GC cycles, want at least 3", len(cycles))
+	}
+	// The sweeps from the end of the first cycle are of the heap marked
+	// in the trace. How many there are depends on the runtime, which
+	// may have swept the heap in the background before the trace.
+	var swept, want uint64
+	for _, ev := range events {
+		if ev.Type == trace.EvGCSweepDone && time.Duration(ev.Ts-events[0].Ts) > cycles[0].End {
+			want += ev.Args[0]
+		}
+	}
+	for i, c := range cycles {
+		// The heap is only known once the trace records it.
+		if i > 0 && c.Complete && (c.HeapStart == 0 || c.HeapEnd == 0 || c.NextGC == 0) {
+			t.Errorf("cycle %d: heap %d to %d, goal %d, want all set", c.Seq, c.HeapStart, c.HeapEnd, c.NextGC)
+		}
+		if c.Complete && len(c.stwByKind()) == 0 {
+			t.Errorf("cycle %d has no STW", c.Seq)
+		}
+		swept += c.Swept
+	}
+	if swept != want {
+		t.Errorf("%d bytes swept in the cycles, want %d", swept, want)
+	}
+
+	sortGCCycles(cycles, "Duration")
+	for i := 1; i < len(cycles); i++ {
+		if cycles[i].Duration > cycles[i-1].Duration {
+			t.Errorf("cycles not sorted by decreasing duration: %v before %v", cycles[i-1].Duration, cycles[i].Duration)
+		}
+	}
+
+	for _, url := range []string{"/gc", "/gc?sortby=Swept", "/gc?sortby=STW"} {
+		w := httptest.NewRecorder()
+		httpGC(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != 200 || !strings.Contains(w.Body.String(), "GC cycles") {
+			t.Errorf("%s: status %d: %s", url, w.Code, w.Body)
+		}
+	}
+}
+
+func TestGCCyclesSweep(t *testing.T) {
+	// Two cycles, the second finishing the sweep of the heap marked by
+	// the first in its sweep termination.
+	gc1 := &trace.Event{Type: trace.EvGCStart, Ts: 10, Args: [3]uint64{1}, Link: &trace.Event{Type: trace.EvGCDone, Ts: 20}}
+	gc2 := &trace.Event{Type: trace.EvGCStart, Ts: 30, Args: [3]uint64{2}, Link: &trace.Event{Type: trace.EvGCDone, Ts: 60}}
+	stw := func(ts, end int64, kind string) *trace.Event {
+		return &trace.Event{Type: trace.EvGCSTWStart, Ts: ts, SArgs: []string{kind}, Link: &trace.Event{Type: trace.EvGCSTWDone, Ts: end}}
+	}
+	sweep := func(ts int64, swept uint64) *trace.Event {
+		return &trace.Event{Type: trace.EvGCSweepDone, Ts: ts, Args: [3]uint64{swept, 0}}
+	}
+	events := []*trace.Event{
+		{Type: trace.EvBatch, Ts: 0},
+		sweep(5, 1), // of a cycle before the trace
+		gc1, stw(10, 12, "GC sweep termination"), stw(18, 20, "GC mark termination"), gc1.Link,
+		sweep(25, 10),
+		gc2, stw(30, 34, "GC sweep termination"), sweep(32, 100), stw(55, 60, "GC mark termination"), gc2.Link,
+		sweep(70, 1000),
+	}
+	cycles := computeGCCycles(events)
+	if len(cycles) != 2 {
+		t.Fatalf("%d GC cycles, want 2", len(cycles))
+	}
+	if cycles[0].Swept != 110 || cycles[1].Swept != 1000 {
+		t.Errorf("swept %d and %d, want 110 and 1000", cycles[0].Swept, cycles[1].Swept)
+	}
+	for _, c := range cycles {
+		if len(c.STW) != 2 {
+			t.Errorf("cycle %d: STW %v, want sweep and mark termination", c.Seq, c.STW)
+		}
+	}
+}
@@ -15,7 +15,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -33,31 +32,74 @@ type gtype struct {
 	trace.GExecutionStat
 }
 
-var (
-	gsInit sync.Once
-	gs     map[uint64]*trace.GDesc
-)
-
-// analyzeGoroutines generates statistics about execution of all goroutines and stores them in gs.
-func analyzeGoroutines(events []*trace.Event) {
-	gsInit.Do(func() {
-		gs = trace.GoroutineStats(events)
+// analyzeGoroutines generates statistics about execution of all goroutines and stores them in st.gs.
+func (st *traceState) analyzeGoroutines(events []*trace.Event) {
+	st.gsInit.Do(func() {
+		if st.loader.gs != nil { // computed or read with the events
+			st.gs = st.loader.gs
+			return
+		}
+		st.gs = trace.GoroutineStats(events)
 	})
 }
 
 // httpGoroutines serves list of goroutine groups.
 func httpGoroutines(w http.ResponseWriter, r *http.Request) {
-	events, err := parseEvents()
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	stats, err := st.goroutineStats(win)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	analyzeGoroutines(events)
+	glist, totalExecTime := groupGoroutines(stats)
+	var n int64
+
+	sortby := r.FormValue("sortby")
+	_, ok := reflect.TypeOf(gtype{}).FieldByNameFunc(func(s string) bool {
+		return s == sortby
+	})
+	if !ok {
+		sortby = "ExecTime"
+	}
+	sortGoroutineGroups(glist, sortby)
+
+	if serveExport(w, r, "goroutines", func() interface{} {
+		list := make([]exportGroup, len(glist))
+		for i, g := range glist {
+			list[i] = newExportGroup(g)
+		}
+		return list
+	}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+
+	err = templGoroutines.Execute(w, struct {
+		N             int64
+		TotalExecTime int64
+		GList         []gtype
+	}{
+		N:             n,
+		TotalExecTime: totalExecTime,
+		GList:         glist})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// groupGoroutines groups goroutines by start PC and returns the groups
+// along with the total execution time of all goroutines.
+func groupGoroutines(gs map[uint64]*trace.GDesc) ([]gtype, int64) {
 	gss := make(map[uint64]gtype)
 
 	var totalExecTime int64
-	var maxTotalTime int64
-	var n int64
 
 	for _, g := range gs {
 		gs1 := gss[g.PC]
@@ -75,20 +117,10 @@ func httpGoroutines(w http.ResponseWriter, r *http.Request) {
 		}
 
 		gs1.N++
-		gs1.ExecTime.AddStat(g.ExecTime)
-		gs1.BlockTime.AddStat(g.BlockTime)
-		gs1.GCTime.AddStat(g.GCTime)
-		gs1.IOTime.AddStat(g.IOTime)
-		gs1.SchedWaitTime.AddStat(g.SchedWaitTime)
-		gs1.SyscallTime.AddStat(g.SyscallTime)
-		gs1.TotalTime.AddStat(g.TotalTime)
+		gs1.GExecutionStat.AddStat(g.GExecutionStat)
 
 		totalExecTime += g.ExecTime.Total
 
-		if maxTotalTime < gs1.TotalTime.Total {
-			maxTotalTime = gs1.TotalTime.Total
-		}
-
 		gss[g.PC] = gs1
 	}
 	var glist []gtype
@@ -96,15 +128,12 @@ func httpGoroutines(w http.ResponseWriter, r *http.Request) {
 		v.ID = k
 		glist = append(glist, v)
 	}
+	return glist, totalExecTime
+}
 
-	sortby := r.FormValue("sortby")
-	_, ok := reflect.TypeOf(gtype{}).FieldByNameFunc(func(s string) bool {
-		return s == sortby
-	})
-	if !ok {
-		sortby = "ExecTime"
-	}
-
+// sortGoroutineGroups sorts the groups by decreasing total of the
+// GExecutionStat field sortby.
+func sortGoroutineGroups(glist []gtype, sortby string) {
 	sort.SliceStable(glist, func(i, j int) bool {
 		ival := reflect.ValueOf(glist[i]).FieldByName(sortby).FieldByName("Total").Int()
 		jval := reflect.ValueOf(glist[j]).FieldByName(sortby).FieldByName("Total").Int()
@@ -113,21 +142,19 @@ func httpGoroutines(w http.ResponseWriter, r *http.Request) {
 		}
 		return ival > jval
 	})
+}
 
-	w.Header().Set("Content-Type", "text/html;charset=utf-8")
-
-	err = templGoroutines.Execute(w, struct {
-		N             int64
-		TotalExecTime int64
-		GList         []gtype
-	}{
-		N:             n,
-		TotalExecTime: totalExecTime,
-		GList:         glist})
-	if err != nil {
-		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
-		return
+// percentiles formats the tail latency percentiles of s for the goroutine tables.
+func percentiles(s trace.GExecutionStatEntry) template.HTML {
+	if s.Count == 0 {
+		return ""
+	}
+	var parts []string
+	for _, p := range []float64{50, 90, 99, 99.9} {
+		d := time.Duration(s.Percentile(p)) * time.Nanosecond
+		parts = append(parts, fmt.Sprintf("p%v:%s", p, niceDuration(d)))
 	}
+	return "<br><small>" + template.HTML(strings.Join(parts, " ")) + "</small>"
 }
 
 func gidList(m map[uint64]bool) string {
@@ -161,6 +188,7 @@ var templGoroutines = template.Must(template.New("").Funcs(template.FuncMap{
 		max := time.Duration(s.Max) * time.Nanosecond
 		return "<small>" + template.HTML("["+niceDuration(min)+"/"+niceDuration(d)+"/"+niceDuration(max)+"]") + "</small>"
 	},
+	"percentiles": percentiles,
 	"barLen": func(s trace.GExecutionStatEntry, total trace.GExecutionStatEntry) template.HTML {
 		if total.Total == 0 {
 			return "0"
@@ -168,7 +196,7 @@ var templGoroutines = template.Must(template.New("").Funcs(template.FuncMap{
 		return template.HTML(fmt.Sprintf("%.2f%%", float64(s.Total)/float64(total.Total)*100))
 	},
 	"unknownTime": func(desc *trace.GExecutionStat) trace.GExecutionStatEntry {
-		sum := desc.ExecTime.Total + desc.IOTime.Total + desc.BlockTime.Total + desc.SyscallTime.Total + desc.SchedWaitTime.Total
+		sum := desc.ExecTime.Total + desc.IOTime.Total + desc.BlockTime.Total + desc.SyscallTime.Total + desc.SchedWaitTime.Total + desc.MarkAssistTime.Total
 		if sum < desc.TotalTime.Total {
 			e := trace.GExecutionStatEntry{}
 			e.Total = desc.TotalTime.Total - sum
@@ -217,6 +245,7 @@ table {
 .block-time { background-color: #d01c8b; }
 .syscall-time { background-color: #7b3294; }
 .sched-time { background-color: #2c7bb6; }
+.assist-time { background-color: #1a9641; }
 </style>
 <script>
 function reloadTable(key, value) {
@@ -224,8 +253,10 @@ function reloadTable(key, value) {
   params.set(key, value);
   window.location.search = params.toString();
 }
+` + windowScript + `
 </script>
 <body>
+<p>Download as <a href="/goroutines?format=csv">CSV</a> or <a href="/goroutines?format=json">JSON</a>.</p>
 <table class="details">
 <tr>
 <th> Goroutine</th>
@@ -239,6 +270,7 @@ function reloadTable(key, value) {
 <th onclick="reloadTable('sortby', 'SchedWaitTime')" class="sched-time"> Scheduler wait</th>
 <th onclick="reloadTable('sortby', 'SweepTime')"> GC sweeping</th>
 <th onclick="reloadTable('sortby', 'GCTime')"> GC pause</th>
+<th onclick="reloadTable('sortby', 'MarkAssistTime')" class="assist-time"> GC mark assist</th>
 </tr>
 {{range $i,$e := .GList}}
   <tr>
@@ -254,15 +286,17 @@ function reloadTable(key, value) {
           {{if .BlockTime.Count}}<span style="width:{{barLen .BlockTime .TotalTime}}" class="block-time">&nbsp;</span>{{end}}
           {{if .SyscallTime.Count}}<span style="width:{{barLen .SyscallTime .TotalTime}}" class="syscall-time">&nbsp;</span>{{end}}
           {{if .SchedWaitTime.Count}}<span style="width:{{barLen .SchedWaitTime .TotalTime}}" class="sched-time">&nbsp;</span>{{end}}
+          {{if .MarkAssistTime.Count}}<span style="width:{{barLen .MarkAssistTime .TotalTime}}" class="assist-time">&nbsp;</span>{{end}}
         </div>
     </td>
-    <td> {{prettyDuration .ExecTime}}  {{percent .ExecTime.Total $.TotalExecTime}} {{minavgmax .ExecTime}}</td>
-    <td><a href="/io?id={{$e.ID}}"> {{prettyDuration .IOTime}} {{minavgmax .IOTime}}</a></td>
-    <td><a href="/block?id={{$e.ID}}"> {{prettyDuration .BlockTime}} {{minavgmax .BlockTime}}</a></td>
-    <td><a href="/syscall?id={{$e.ID}}"> {{prettyDuration .SyscallTime}} {{minavgmax .SyscallTime}}</a></td>
-    <td><a href="/sched?id={{$e.ID}}"> {{prettyDuration .SchedWaitTime}} {{minavgmax .SchedWaitTime}}</a></td>
+    <td> {{prettyDuration .ExecTime}}  {{percent .ExecTime.Total $.TotalExecTime}} {{minavgmax .ExecTime}} {{percentiles .ExecTime}}</td>
+    <td><a href="/io?id={{$e.ID}}"> {{prettyDuration .IOTime}} {{minavgmax .IOTime}} {{percentiles .IOTime}}</a></td>
+    <td><a href="/block?id={{$e.ID}}"> {{prettyDuration .BlockTime}} {{minavgmax .BlockTime}} {{percentiles .BlockTime}}</a></td>
+    <td><a href="/syscall?id={{$e.ID}}"> {{prettyDuration .SyscallTime}} {{minavgmax .SyscallTime}} {{percentiles .SyscallTime}}</a></td>
+    <td><a href="/sched?id={{$e.ID}}"> {{prettyDuration .SchedWaitTime}} {{minavgmax .SchedWaitTime}} {{percentiles .SchedWaitTime}}</a></td>
     <td> {{prettyDuration .SweepTime}} {{percent .SweepTime.Total .TotalTime.Total}}</td>
-    <td> {{prettyDuration .GCTime}} {{percent .GCTime.Total .TotalTime.Total}} {{minavgmax .GCTime}}</td>
+    <td> {{prettyDuration .GCTime}} {{percent .GCTime.Total .TotalTime.Total}} {{minavgmax .GCTime}} {{percentiles .GCTime}}</td>
+    <td><a href="/assist?id={{$e.ID}}"> {{prettyDuration .MarkAssistTime}} {{minavgmax .MarkAssistTime}} {{percentiles .MarkAssistTime}}</a></td>
 	{{end}}
   </tr>
 {{end}}
@@ -271,11 +305,100 @@ function reloadTable(key, value) {
 </html>
 `))
 
+// gStateCounts are the numbers of goroutines of a group by state.
+type gStateCounts struct {
+	Running, Runnable, Blocked, Syscall int64
+}
+
+func (c *gStateCounts) add(state gState, n int64) {
+	switch state {
+	case gRunning:
+		c.Running += n
+	case gRunnable:
+		c.Runnable += n
+	case gWaiting, gWaitingGC:
+		c.Blocked += n
+	case gSyscall:
+		c.Syscall += n
+	}
+}
+
+// groupStates tracks the states of the goroutines of some groups along
+// the events of the trace.
+type groupStates struct {
+	pcs    map[uint64]uint64 // start PC of the goroutines of the groups
+	states map[uint64]gState
+	counts map[uint64]*gStateCounts // by start PC
+}
+
+// newGroupStates returns the tracker of the groups of the goroutines
+// with the start PCs in pcs.
+func (st *traceState) newGroupStates(pcs map[uint64]bool) *groupStates {
+	s := &groupStates{pcs: make(map[uint64]uint64), states: make(map[uint64]gState), counts: make(map[uint64]*gStateCounts)}
+	for _, g := range st.gs {
+		if pcs[g.PC] {
+			s.pcs[g.ID] = g.PC
+		}
+	}
+	for pc := range pcs {
+		s.counts[pc] = new(gStateCounts)
+	}
+	return s
+}
+
+// update applies the goroutine state change of ev. It returns the start
+// PC of the group whose counts change, if any.
+func (s *groupStates) update(ev *trace.Event) (pc uint64, ok bool) {
+	g, state, ok := goroutineTransition(ev)
+	if !ok {
+		return 0, false
+	}
+	pc, ok = s.pcs[g]
+	if !ok || s.states[g] == state {
+		return 0, false
+	}
+	c := s.counts[pc]
+	c.add(s.states[g], -1)
+	c.add(state, 1)
+	if state == gDead {
+		delete(s.states, g)
+	} else {
+		s.states[g] = state
+	}
+	return pc, true
+}
+
+// groupSeries is the number of goroutines of a group by state, averaged
+// over equal intervals of the trace.
+type groupSeries struct {
+	Running, Runnable, Blocked, Syscall []float64
+}
+
+// computeGroupSeries returns the series of the group of goroutines with
+// start PC pc in the non-empty trace. It requires gs.
+func (st *traceState) computeGroupSeries(events []*trace.Event, pc uint64) groupSeries {
+	base, last := events[0].Ts, events[len(events)-1].Ts
+	var series [4]*stepSeries
+	for i := range series {
+		series[i] = newStepSeries(base, last, procSeriesBuckets)
+	}
+	s := st.newGroupStates(map[uint64]bool{pc: true})
+	c := s.counts[pc]
+	for _, ev := range events {
+		if _, ok := s.update(ev); !ok {
+			continue
+		}
+		for i, n := range []int64{c.Running, c.Runnable, c.Blocked, c.Syscall} {
+			series[i].set(ev.Ts, float64(n))
+		}
+	}
+	return groupSeries{series[0].averages(), series[1].averages(), series[2].averages(), series[3].averages()}
+}
+
 // httpGoroutine serves list of goroutines in a particular group.
 func httpGoroutine(w http.ResponseWriter, r *http.Request) {
-	// TODO(hyangah): support format=csv (raw data)
-
-	events, err := parseEvents()
+	st := requestTrace(r)
+	events, err := st.parseEvents()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -286,7 +409,11 @@ func httpGoroutine(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("failed to parse id parameter '%v': %v", r.FormValue("id"), err), http.StatusInternalServerError)
 		return
 	}
-	analyzeGoroutines(events)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var (
 		glist                   []*trace.GDesc
 		name                    string
@@ -294,7 +421,12 @@ func httpGoroutine(w http.ResponseWriter, r *http.Request) {
 		maxTotalTime            int64
 	)
 
-	for _, g := range gs {
+	stats, err := st.goroutineStats(win)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, g := range stats {
 		totalExecTime += g.ExecTime.Total
 
 		if g.PC != pc {
@@ -330,6 +462,21 @@ func httpGoroutine(w http.ResponseWriter, r *http.Request) {
 		return ival > jval
 	})
 
+	if serveExport(w, r, fmt.Sprintf("goroutine-%d", pc), func() interface{} {
+		list := make([]exportGoroutine, len(glist))
+		for i, g := range glist {
+			list[i] = newExportGoroutine(g, st.firstTimestamp())
+		}
+		return list
+	}) {
+		return
+	}
+
+	var series groupSeries
+	if len(events) > 0 {
+		series = st.computeGroupSeries(events, pc)
+	}
+
 	err = templGoroutine.Execute(w, struct {
 		Name            string
 		PC              uint64
@@ -338,6 +485,7 @@ func httpGoroutine(w http.ResponseWriter, r *http.Request) {
 		MaxTotal        int64
 		TotalExecTime   int64
 		GList           []*trace.GDesc
+		Series          groupSeries
 	}{
 		Name:            name,
 		PC:              pc,
@@ -345,7 +493,8 @@ func httpGoroutine(w http.ResponseWriter, r *http.Request) {
 		ExecTimePercent: execTimePercent,
 		MaxTotal:        maxTotalTime,
 		TotalExecTime:   execTime,
-		GList:           glist})
+		GList:           glist,
+		Series:          series})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
 		return
@@ -372,6 +521,7 @@ var templGoroutine = template.Must(template.New("").Funcs(template.FuncMap{
 		max := time.Duration(s.Max) * time.Nanosecond
 		return "<small>" + template.HTML("["+niceDuration(min)+"/"+niceDuration(d)+"/"+niceDuration(max)+"]") + "</small>"
 	},
+	"percentiles": percentiles,
 	"barLen": func(s trace.GExecutionStatEntry, total trace.GExecutionStatEntry) template.HTML {
 		if total.Total == 0 {
 			return "0"
@@ -379,7 +529,7 @@ var templGoroutine = template.Must(template.New("").Funcs(template.FuncMap{
 		return template.HTML(fmt.Sprintf("%.2f%%", float64(s.Total)/float64(total.Total)*100))
 	},
 	"unknownTime": func(desc *trace.GExecutionStat) trace.GExecutionStatEntry {
-		sum := desc.ExecTime.Total + desc.IOTime.Total + desc.BlockTime.Total + desc.SyscallTime.Total + desc.SchedWaitTime.Total
+		sum := desc.ExecTime.Total + desc.IOTime.Total + desc.BlockTime.Total + desc.SyscallTime.Total + desc.SchedWaitTime.Total + desc.MarkAssistTime.Total
 		if sum < desc.TotalTime.Total {
 			e := trace.GExecutionStatEntry{}
 			e.Total = desc.TotalTime.Total - sum
@@ -429,6 +579,7 @@ table {
 .block-time { background-color: #d01c8b; }
 .syscall-time { background-color: #7b3294; }
 .sched-time { background-color: #2c7bb6; }
+.assist-time { background-color: #1a9641; }
 </style>
 
 <script>
@@ -437,6 +588,7 @@ function reloadTable(key, value) {
   params.set(key, value);
   window.location.search = params.toString();
 }
+` + windowScript + `
 </script>
 
 <table class="summary">
@@ -444,10 +596,21 @@ function reloadTable(key, value) {
 	<tr><td>Number of Goroutines:</td><td>{{.N}}</td></tr>
 	<tr><td>Execution Time:</td><td>{{.ExecTimePercent}} of total program execution time </td> </tr>
 	<tr><td>Network Wait Time:</td><td> <a href="/io?id={{.PC}}">graph</a><a href="/io?id={{.PC}}&raw=1" download="io.profile">(download)</a></td></tr>
-	<tr><td>Sync Block Time:</td><td> <a href="/block?id={{.PC}}">graph</a><a href="/block?id={{.PC}}&raw=1" download="block.profile">(download)</a></td></tr>
+	<tr><td>Sync Block Time:</td><td> <a href="/block?id={{.PC}}">graph</a><a href="/block?id={{.PC}}&raw=1" download="block.profile">(download)</a> <a href="/contention?id={{.PC}}">(contention)</a></td></tr>
 	<tr><td>Blocking Syscall Time:</td><td> <a href="/syscall?id={{.PC}}">graph</a><a href="/syscall?id={{.PC}}&raw=1" download="syscall.profile">(download)</a></td></tr>
 	<tr><td>Scheduler Wait Time:</td><td> <a href="/sched?id={{.PC}}">graph</a><a href="/sched?id={{.PC}}&raw=1" download="sched.profile">(download)</a></td></tr>
+	<tr><td>GC Mark Assist Time:</td><td> <a href="/assist?id={{.PC}}">graph</a><a href="/assist?id={{.PC}}&raw=1" download="assist.profile">(download)</a></td></tr>
+	<tr><td>Export:</td><td> <a href="/goroutine?id={{.PC}}&format=csv">CSV</a> <a href="/goroutine?id={{.PC}}&format=json">JSON</a></td></tr>
 </table>
+<p>Goroutines of the group running (<span style="color: #d7191c">red</span>), runnable
+(<span style="color: #2c7bb6">blue</span>), blocked (<span style="color: #d01c8b">purple</span>) and in syscalls
+(<span style="color: #7b3294">violet</span>), averaged over intervals of the trace:</p>
+<svg id="chart" width="900" height="150"></svg>
+<script>
+'use strict';
+` + seriesChartScript + `
+drawSeries('chart', [[{{.Series.Running}}, '#d7191c'], [{{.Series.Runnable}}, '#2c7bb6'], [{{.Series.Blocked}}, '#d01c8b'], [{{.Series.Syscall}}, '#7b3294']]);
+</script>
 <p>
 <table class="details">
 <tr>
@@ -461,6 +624,7 @@ function reloadTable(key, value) {
 <th onclick="reloadTable('sortby', 'SchedWaitTime')" class="sched-time"> Scheduler wait</th>
 <th onclick="reloadTable('sortby', 'SweepTime')"> GC sweeping</th>
 <th onclick="reloadTable('sortby', 'GCTime')"> GC pause</th>
+<th onclick="reloadTable('sortby', 'MarkAssistTime')" class="assist-time"> GC mark assist</th>
 </tr>
 {{range .GList}}
   <tr>
@@ -474,15 +638,17 @@ function reloadTable(key, value) {
           {{if .BlockTime.Count}}<span style="width:{{barLen .BlockTime .TotalTime}}" class="block-time">&nbsp;</span>{{end}}
           {{if .SyscallTime.Count}}<span style="width:{{barLen .SyscallTime .TotalTime}}" class="syscall-time">&nbsp;</span>{{end}}
           {{if .SchedWaitTime.Count}}<span style="width:{{barLen .SchedWaitTime .TotalTime}}" class="sched-time">&nbsp;</span>{{end}}
+          {{if .MarkAssistTime.Count}}<span style="width:{{barLen .MarkAssistTime .TotalTime}}" class="assist-time">&nbsp;</span>{{end}}
         </div>
     </td>
-    <td> {{prettyDuration .ExecTime}}  {{percent .ExecTime.Total $.TotalExecTime}} {{minavgmax .ExecTime}}</td>
-    <td> {{prettyDuration .IOTime}} {{minavgmax .IOTime}}</td>
-    <td> {{prettyDuration .BlockTime}} {{minavgmax .BlockTime}}</td>
-    <td> {{prettyDuration .SyscallTime}} {{minavgmax .SyscallTime}}</td>
-    <td> {{prettyDuration .SchedWaitTime}} {{minavgmax .SchedWaitTime}}</td>
+    <td> {{prettyDuration .ExecTime}}  {{percent .ExecTime.Total $.TotalExecTime}} {{minavgmax .ExecTime}} {{percentiles .ExecTime}}</td>
+    <td> {{prettyDuration .IOTime}} {{minavgmax .IOTime}} {{percentiles .IOTime}}</td>
+    <td> {{prettyDuration .BlockTime}} {{minavgmax .BlockTime}} {{percentiles .BlockTime}}</td>
+    <td> {{prettyDuration .SyscallTime}} {{minavgmax .SyscallTime}} {{percentiles .SyscallTime}}</td>
+    <td> {{prettyDuration .SchedWaitTime}} {{minavgmax .SchedWaitTime}} {{percentiles .SchedWaitTime}}</td>
     <td> {{prettyDuration .SweepTime}} {{percent .SweepTime.Total .TotalTime.Total}}</td>
-    <td> {{prettyDuration .GCTime}} {{percent .GCTime.Total .TotalTime.Total}} {{minavgmax .GCTime}}</td>
+    <td> {{prettyDuration .GCTime}} {{percent .GCTime.Total .TotalTime.Total}} {{minavgmax .GCTime}} {{percentiles .GCTime}}</td>
+    <td> {{prettyDuration .MarkAssistTime}} {{minavgmax .MarkAssistTime}} {{percentiles .MarkAssistTime}}</td>
   </tr>
 {{end}}
 </table>
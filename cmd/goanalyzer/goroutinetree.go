@@ -0,0 +1,327 @@
+// Goroutine creation tree.
+
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"net/http"
+	"reflect"
+	"sort"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/goroutinetree", httpGoroutineTree)
+}
+
+// gtreeNode is a node of the goroutine creation tree: the goroutines
+// with the same start function, created at the same go statement by
+// goroutines of the parent node. Goroutines created by goroutines of
+// the same node at the same go statement, such as recursively spawned
+// ones, belong to that node too.
+type gtreeNode struct {
+	PC       uint64               // Start PC, as in gtype.
+	Name     string               // Start function.
+	Site     string               // Creation site, empty for the root and for goroutines existing at trace start.
+	Depth    int                  // Distance from the root.
+	GIDS     map[uint64]bool      // Goroutines in this node.
+	Self     trace.GExecutionStat // Of the goroutines in this node.
+	N        int                  // Goroutines in the subtree.
+	Subtree  trace.GExecutionStat // Of the goroutines in the subtree.
+	Children []*gtreeNode
+
+	key      gtreeKey
+	children map[gtreeKey]*gtreeNode
+}
+
+type gtreeKey struct {
+	pc   uint64 // start PC
+	site uint64 // PC of the go statement
+}
+
+func (n *gtreeNode) child(k gtreeKey) *gtreeNode {
+	c := n.children[k]
+	if c == nil {
+		if n.children == nil {
+			n.children = make(map[gtreeKey]*gtreeNode)
+		}
+		c = &gtreeNode{PC: k.pc, Depth: n.Depth + 1, GIDS: make(map[uint64]bool), key: k}
+		n.children[k] = c
+		n.Children = append(n.Children, c)
+	}
+	return c
+}
+
+// buildGoroutineTree aggregates the goroutines by creation site along
+// their parent-child relationship. The children of the returned root
+// are the goroutines that existed at the start of the trace or whose
+// creator is unknown.
+func buildGoroutineTree(gs map[uint64]*trace.GDesc) *gtreeNode {
+	glist := make([]*trace.GDesc, 0, len(gs))
+	for _, g := range gs {
+		glist = append(glist, g)
+	}
+	// A goroutine is created after its parent, so its parent's
+	// node exists when it is placed.
+	sort.Slice(glist, func(i, j int) bool {
+		if glist[i].CreationTime == glist[j].CreationTime {
+			return glist[i].ID < glist[j].ID
+		}
+		return glist[i].CreationTime < glist[j].CreationTime
+	})
+
+	root := &gtreeNode{GIDS: make(map[uint64]bool)}
+	nodes := make(map[uint64]*gtreeNode) // by goroutine ID
+	for _, g := range glist {
+		parent := nodes[g.ParentID]
+		if parent == nil {
+			parent = root
+		}
+		k := gtreeKey{pc: g.PC}
+		if len(g.CreationStk) > 0 {
+			k.site = g.CreationStk[0].PC
+		}
+		n := parent
+		if parent == root || parent.key != k {
+			n = parent.child(k)
+		}
+		if n.Name == "" {
+			n.Name = g.Name
+			if n.Name == "" {
+				n.Name = fmt.Sprint("PC:", g.PC)
+			}
+			if len(g.CreationStk) > 0 {
+				f := g.CreationStk[0]
+				n.Site = fmt.Sprintf("%s %s:%d", f.Fn, f.File, f.Line)
+			}
+		}
+		n.GIDS[g.ID] = true
+		n.Self.AddStat(g.GExecutionStat)
+		nodes[g.ID] = n
+	}
+	root.sum()
+	return root
+}
+
+// sum computes the subtree totals of n and its descendants.
+func (n *gtreeNode) sum() {
+	n.N = len(n.GIDS)
+	n.Subtree = trace.GExecutionStat{}
+	n.Subtree.AddStat(n.Self)
+	for _, c := range n.Children {
+		c.sum()
+		n.N += c.N
+		n.Subtree.AddStat(c.Subtree)
+	}
+}
+
+// sort orders the children of every node by decreasing subtree total
+// of the GExecutionStat field sortby, or by subtree size if sortby is "N".
+func (n *gtreeNode) sort(sortby string) {
+	val := func(c *gtreeNode) int64 {
+		if sortby == "N" {
+			return int64(c.N)
+		}
+		return reflect.ValueOf(c.Subtree).FieldByName(sortby).FieldByName("Total").Int()
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		ival, jval := val(n.Children[i]), val(n.Children[j])
+		if ival == jval {
+			return n.Children[i].Name < n.Children[j].Name
+		}
+		return ival > jval
+	})
+	for _, c := range n.Children {
+		c.sort(sortby)
+	}
+}
+
+// flatten returns the descendants of n in depth-first order.
+func (n *gtreeNode) flatten() []*gtreeNode {
+	var list []*gtreeNode
+	var walk func(*gtreeNode)
+	walk = func(n *gtreeNode) {
+		for _, c := range n.Children {
+			list = append(list, c)
+			walk(c)
+		}
+	}
+	walk(n)
+	return list
+}
+
+// SubtreeGIDS returns the goroutines in the subtree of n.
+func (n *gtreeNode) SubtreeGIDS() map[uint64]bool {
+	gids := make(map[uint64]bool, n.N)
+	for id := range n.GIDS {
+		gids[id] = true
+	}
+	for _, c := range n.flatten() {
+		for id := range c.GIDS {
+			gids[id] = true
+		}
+	}
+	return gids
+}
+
+// httpGoroutineTree serves the goroutine creation tree.
+func httpGoroutineTree(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	st.analyzeGoroutines(events)
+	root := buildGoroutineTree(st.gs)
+
+	sortby := r.FormValue("sortby")
+	_, ok := reflect.TypeOf(trace.GExecutionStat{}).FieldByName(sortby)
+	if !ok && sortby != "N" {
+		sortby = "ExecTime"
+	}
+	root.sort(sortby)
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	err = templGoroutineTree.Execute(w, struct {
+		N     int
+		Nodes []*gtreeNode
+	}{
+		N:     root.N,
+		Nodes: root.flatten(),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templGoroutineTree = template.Must(template.New("").Funcs(template.FuncMap{
+	"prettyDuration": func(s trace.GExecutionStatEntry) template.HTML {
+		d := time.Duration(s.Total) * time.Nanosecond
+		return template.HTML(niceDuration(d))
+	},
+	"gidList": func(gids map[uint64]bool) template.HTML {
+		return template.HTML(gidList(gids))
+	},
+	"minavgmax": func(s trace.GExecutionStatEntry) template.HTML {
+		if s.Count == 0 {
+			return ""
+		}
+		d := time.Duration(s.Total/s.Count) * time.Nanosecond
+		min := time.Duration(s.Min) * time.Nanosecond
+		max := time.Duration(s.Max) * time.Nanosecond
+		return "<small>" + template.HTML("["+niceDuration(min)+"/"+niceDuration(d)+"/"+niceDuration(max)+"]") + "</small>"
+	},
+	"barLen": func(s trace.GExecutionStatEntry, total trace.GExecutionStatEntry) template.HTML {
+		if total.Total == 0 {
+			return "0"
+		}
+		return template.HTML(fmt.Sprintf("%.2f%%", float64(s.Total)/float64(total.Total)*100))
+	},
+	"indent": func(depth int) template.CSS {
+		return template.CSS(fmt.Sprintf("padding-left: %dem", depth-1))
+	},
+}).Parse(`
+<!DOCTYPE html>
+<title>Goroutine creation tree</title>
+<style>
+th {
+  background-color: #050505;
+  color: #fff;
+}
+table {
+  border-collapse: collapse;
+}
+.details tr:hover {
+  background-color: #f2f2f2;
+}
+.details td {
+  text-align: right;
+  border: 1px solid black;
+}
+.details td.id {
+  text-align: left;
+  white-space: nowrap;
+}
+.stacked-bar-graph {
+  width: 300px;
+  height: 10px;
+  color: #414042;
+  white-space: nowrap;
+  font-size: 5px;
+}
+.stacked-bar-graph span {
+  display: inline-block;
+  width: 100%;
+  height: 100%;
+  box-sizing: border-box;
+  float: left;
+  padding: 0;
+}
+.exec-time { background-color: #d7191c; }
+.io-time { background-color: #fdae61; }
+.block-time { background-color: #d01c8b; }
+.syscall-time { background-color: #7b3294; }
+.sched-time { background-color: #2c7bb6; }
+</style>
+<script>
+function reloadTable(key, value) {
+  let params = new URLSearchParams(window.location.search);
+  params.set(key, value);
+  window.location.search = params.toString();
+}
+</script>
+<body>
+<p>Goroutines grouped by start function and by the go statement creating them,
+below the group of their creator. Times are totals over all {{.N}} goroutines
+in the subtree of each group.</p>
+<table class="details">
+<tr>
+<th> Goroutine</th>
+<th> Created at</th>
+<th> Count</th>
+<th onclick="reloadTable('sortby', 'N')"> Subtree</th>
+<th onclick="reloadTable('sortby', 'TotalTime')"> Total</th>
+<th></th>
+<th onclick="reloadTable('sortby', 'ExecTime')" class="exec-time"> Execution</th>
+<th onclick="reloadTable('sortby', 'IOTime')" class="io-time"> Network wait</th>
+<th onclick="reloadTable('sortby', 'BlockTime')" class="block-time"> Sync block </th>
+<th onclick="reloadTable('sortby', 'SyscallTime')" class="syscall-time"> Blocking syscall</th>
+<th onclick="reloadTable('sortby', 'SchedWaitTime')" class="sched-time"> Scheduler wait</th>
+<th onclick="reloadTable('sortby', 'SweepTime')"> GC sweeping</th>
+<th onclick="reloadTable('sortby', 'GCTime')"> GC pause</th>
+</tr>
+{{range .Nodes}}
+  <tr>
+    <td class="id" style="{{indent .Depth}}"><a href="/goroutine?id={{.PC}}">{{.Name}}</a></td>
+    <td class="id">{{.Site}}</td>
+    <td><a href="/trace?goid={{gidList .GIDS}}">{{len .GIDS}}</a></td>
+    <td><a href="/trace?goid={{gidList .SubtreeGIDS}}">{{.N}}</a></td>
+    {{with .Subtree}}
+    <td> {{prettyDuration .TotalTime}} </td>
+    <td>
+	<div class="stacked-bar-graph">
+          {{if .ExecTime.Count}}<span style="width:{{barLen .ExecTime .TotalTime}}" class="exec-time">&nbsp;</span>{{end}}
+          {{if .IOTime.Count}}<span style="width:{{barLen .IOTime .TotalTime}}" class="io-time">&nbsp;</span>{{end}}
+          {{if .BlockTime.Count}}<span style="width:{{barLen .BlockTime .TotalTime}}" class="block-time">&nbsp;</span>{{end}}
+          {{if .SyscallTime.Count}}<span style="width:{{barLen .SyscallTime .TotalTime}}" class="syscall-time">&nbsp;</span>{{end}}
+          {{if .SchedWaitTime.Count}}<span style="width:{{barLen .SchedWaitTime .TotalTime}}" class="sched-time">&nbsp;</span>{{end}}
+        </div>
+    </td>
+    <td> {{prettyDuration .ExecTime}} {{minavgmax .ExecTime}}</td>
+    <td> {{prettyDuration .IOTime}} {{minavgmax .IOTime}}</td>
+    <td> {{prettyDuration .BlockTime}} {{minavgmax .BlockTime}}</td>
+    <td> {{prettyDuration .SyscallTime}} {{minavgmax .SyscallTime}}</td>
+    <td> {{prettyDuration .SchedWaitTime}} {{minavgmax .SchedWaitTime}}</td>
+    <td> {{prettyDuration .SweepTime}}</td>
+    <td> {{prettyDuration .GCTime}} {{minavgmax .GCTime}}</td>
+    {{end}}
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"testing"
+)
+
+func TestGoroutineTree(t *testing.T) {
+	site := func(pc uint64) []*trace.Frame {
+		return []*trace.Frame{{PC: pc, Fn: "main.handler", File: "main.go", Line: int(pc)}}
+	}
+	gs := make(map[uint64]*trace.GDesc)
+	add := func(id, parent uint64, name string, pc uint64, stk []*trace.Frame, exec int64) {
+		g := &trace.GDesc{ID: id, ParentID: parent, Name: name, PC: pc, CreationTime: int64(id), CreationStk: stk}
+		g.ExecTime = trace.GExecutionStatEntry{Count: 1, Total: exec, Min: exec, Max: exec}
+		gs[id] = g
+	}
+	add(1, 0, "main.main", 100, nil, 10)
+	add(2, 1, "main.handler", 200, site(10), 20)
+	add(3, 1, "main.handler", 200, site(10), 20)
+	// Workers fanned out by both handlers at the same go statement.
+	for id := uint64(10); id < 20; id++ {
+		add(id, 2+id%2, "main.worker", 300, site(20), 5)
+	}
+	// A worker recursively spawning more workers at another site.
+	add(20, 10, "main.worker", 300, site(30), 1)
+	add(21, 20, "main.worker", 300, site(30), 1)
+	// The parent of a goroutine created at trace start is unknown.
+	add(30, 99, "main.orphan", 400, site(40), 7)
+
+	root := buildGoroutineTree(gs)
+	root.sort("ExecTime")
+	var got []string
+	for _, n := range root.flatten() {
+		got = append(got, fmt.Sprintf("%d:%s/%d/%d/%d", n.Depth, n.Name, len(n.GIDS), n.N, n.Subtree.ExecTime.Total))
+	}
+	want := "[1:main.main/1/15/102 2:main.handler/2/14/92 3:main.worker/10/12/52 4:main.worker/2/2/2 1:main.orphan/1/1/7]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("tree\n%v\nwant\n%s", got, want)
+	}
+	if n := root.N; n != len(gs) {
+		t.Errorf("root subtree has %d goroutines, want %d", n, len(gs))
+	}
+	if gids := root.Children[0].Children[0].SubtreeGIDS(); len(gids) != 14 || !gids[21] || gids[1] {
+		t.Errorf("handler subtree goroutines %v, want 2, 3 and the 12 workers", gids)
+	}
+}
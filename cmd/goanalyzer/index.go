@@ -0,0 +1,490 @@
+// On-disk index of parsed traces.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	// indexSuffix is appended to the name of a trace file for the name
+	// of its index.
+	indexSuffix = ".index"
+	// indexMagic starts index files. It changes with the format.
+	indexMagic = "goanalyzer index 3\n"
+)
+
+// indexBlockEvents is the number of events of an index block, the unit
+// of lazy loading. It is a variable for tests.
+var indexBlockEvents = 1 << 14
+
+// traceIndex is the index of a trace file, which stores the parsed and
+// ordered events, the stacks, the goroutine statistics and the ranges
+// of the trace, so that reopening a trace needs neither parsing nor
+// splitting it.
+//
+// The index file is next to the trace, or in the user cache directory
+// if the trace directory is not writable, and is named after the hash
+// of the trace there. It holds indexMagic, the events in blocks of
+// indexBlockEvents that are gob encoded separately so that the events of
+// a time window can be read alone, the gob encoded indexMeta, and the
+// offset of indexMeta as the last 8 bytes.
+type traceIndex struct {
+	file string
+	meta indexMeta
+}
+
+type indexMeta struct {
+	// Trace file the index is for, up to date if its size and
+	// modification time or its hash match.
+	Size    int64
+	ModTime time.Time
+	Hash    string // SHA-256
+
+	Trace      traceMeta
+	Blocks     []indexBlock
+	Stacks     map[uint64][]*trace.Frame
+	Ranges     []Range
+	Goroutines []indexGoroutine
+	Timeline   timeline
+}
+
+type indexBlock struct {
+	Off, Len   int64 // in the index file
+	First, N   int   // index of the first event, number of events
+	Start, End int64 // timestamps of the first and last events
+}
+
+// indexEvent is a trace.Event with references to other events as event
+// indexes plus one, 0 standing for nil.
+type indexEvent struct {
+	Off   int
+	Type  byte
+	Ts    int64
+	P     int
+	G     uint64
+	StkID uint64
+	Args  [3]uint64
+	SArgs []string
+	Link  int
+}
+
+type indexGoroutine struct {
+	G        trace.GDesc // without EndBlock and Regions
+	EndBlock int
+	Regions  []indexRegion
+}
+
+type indexRegion struct {
+	TaskID     uint64
+	Name       string
+	Start, End int
+	Stat       trace.GExecutionStat
+}
+
+func (ie *indexEvent) event(stacks map[uint64][]*trace.Frame) *trace.Event {
+	ev := &trace.Event{Off: ie.Off, Type: ie.Type, Ts: ie.Ts, P: ie.P, G: ie.G, StkID: ie.StkID, Args: ie.Args, SArgs: ie.SArgs}
+	if ev.StkID != 0 {
+		ev.Stk = stacks[ev.StkID]
+	}
+	return ev
+}
+
+// hashFile returns the hex encoded SHA-256 of file.
+func hashFile(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheIndexFile returns the index file in the user cache directory of
+// the trace with the given hash.
+func cacheIndexFile(hash string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goanalyzer", hash+indexSuffix), nil
+}
+
+// openIndex returns the up to date index of the trace file. When there
+// is none, it returns the hash of the trace, if computed, for
+// writeIndex.
+func openIndex(file string) (x *traceIndex, hash string, err error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, "", err
+	}
+	x, err = readIndex(file + indexSuffix)
+	if err == nil && x.meta.Size == fi.Size() && x.meta.ModTime.Equal(fi.ModTime()) {
+		return x, "", nil
+	}
+	// The trace may have been copied or touched: compare contents.
+	hash, err = hashFile(file)
+	if err != nil {
+		return nil, "", err
+	}
+	if x != nil && x.meta.Hash == hash {
+		return x, "", nil
+	}
+	if path, err := cacheIndexFile(hash); err == nil {
+		if x, err := readIndex(path); err == nil && x.meta.Hash == hash {
+			return x, "", nil
+		}
+	}
+	return nil, hash, fmt.Errorf("no index of %s", file)
+}
+
+// readIndex reads the metadata of the index file.
+func readIndex(file string) (*traceIndex, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var buf [8]byte
+	magic := make([]byte, len(indexMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || string(magic) != indexMagic {
+		return nil, fmt.Errorf("%s is not an index", file)
+	}
+	end := fi.Size() - int64(len(buf))
+	if _, err := f.ReadAt(buf[:], end); err != nil {
+		return nil, err
+	}
+	off := int64(binary.BigEndian.Uint64(buf[:]))
+	if off < int64(len(indexMagic)) || off > end {
+		return nil, fmt.Errorf("%s: bad metadata offset", file)
+	}
+	x := &traceIndex{file: file}
+	if err := gob.NewDecoder(bufio.NewReader(io.NewSectionReader(f, off, end-off))).Decode(&x.meta); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return x, nil
+}
+
+// writeIndex writes the index of the trace file, with meta completed by
+// the file and its hash, which is computed if empty, and returns the
+// index file.
+func writeIndex(file, hash string, meta indexMeta, events []*trace.Event, gs map[uint64]*trace.GDesc) (string, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	if hash == "" {
+		if hash, err = hashFile(file); err != nil {
+			return "", err
+		}
+	}
+	path := file + indexSuffix
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		// The trace directory is not writable.
+		if path, err = cacheIndexFile(hash); err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if f, err = ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp"); err != nil {
+			return "", err
+		}
+	}
+	meta.Size, meta.ModTime, meta.Hash = fi.Size(), fi.ModTime(), hash
+	err = encodeIndex(f, meta, events, gs)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+func encodeIndex(f io.Writer, meta indexMeta, events []*trace.Event, gs map[uint64]*trace.GDesc) error {
+	w := bufio.NewWriter(f)
+	ids := make(map[*trace.Event]int, len(events))
+	for i, ev := range events {
+		ids[ev] = i + 1
+	}
+	off := int64(len(indexMagic))
+	w.WriteString(indexMagic)
+	var buf bytes.Buffer
+	for first := 0; first < len(events); first += indexBlockEvents {
+		n := len(events) - first
+		if n > indexBlockEvents {
+			n = indexBlockEvents
+		}
+		block := make([]indexEvent, n)
+		for i, ev := range events[first : first+n] {
+			block[i] = indexEvent{Off: ev.Off, Type: ev.Type, Ts: ev.Ts, P: ev.P, G: ev.G, StkID: ev.StkID, Args: ev.Args, SArgs: ev.SArgs, Link: ids[ev.Link]}
+		}
+		buf.Reset()
+		if err := gob.NewEncoder(&buf).Encode(block); err != nil {
+			return err
+		}
+		meta.Blocks = append(meta.Blocks, indexBlock{Off: off, Len: int64(buf.Len()), First: first, N: n, Start: block[0].Ts, End: block[n-1].Ts})
+		off += int64(buf.Len())
+		w.Write(buf.Bytes())
+	}
+
+	for _, g := range gs {
+		ig := indexGoroutine{G: *g, EndBlock: ids[g.EndBlock]}
+		ig.G.EndBlock, ig.G.Regions = nil, nil
+		for _, s := range g.Regions {
+			ig.Regions = append(ig.Regions, indexRegion{TaskID: s.TaskID, Name: s.Name, Start: ids[s.Start], End: ids[s.End], Stat: s.GExecutionStat})
+		}
+		meta.Goroutines = append(meta.Goroutines, ig)
+	}
+	buf.Reset()
+	if err := gob.NewEncoder(&buf).Encode(&meta); err != nil {
+		return err
+	}
+	w.Write(buf.Bytes())
+	var trailer [8]byte
+	binary.BigEndian.PutUint64(trailer[:], uint64(off))
+	w.Write(trailer[:])
+	return w.Flush()
+}
+
+// readBlock reads the events of block b of the index file f.
+func (x *traceIndex) readBlock(f *os.File, b indexBlock) ([]indexEvent, error) {
+	var block []indexEvent
+	if err := gob.NewDecoder(bufio.NewReader(io.NewSectionReader(f, b.Off, b.Len))).Decode(&block); err != nil {
+		return nil, fmt.Errorf("%s: %v", x.file, err)
+	}
+	if len(block) != b.N {
+		return nil, fmt.Errorf("%s: block of %d events, want %d", x.file, len(block), b.N)
+	}
+	return block, nil
+}
+
+// load reads all the events of the index, like trace.Parse, and the
+// goroutine statistics, like trace.GoroutineStats.
+func (x *traceIndex) load() (trace.ParseResult, map[uint64]*trace.GDesc, error) {
+	f, err := os.Open(x.file)
+	if err != nil {
+		return trace.ParseResult{}, nil, err
+	}
+	defer f.Close()
+	events := make([]*trace.Event, 0, x.meta.Trace.Events)
+	var links []int
+	for _, b := range x.meta.Blocks {
+		block, err := x.readBlock(f, b)
+		if err != nil {
+			return trace.ParseResult{}, nil, err
+		}
+		for i := range block {
+			events = append(events, block[i].event(x.meta.Stacks))
+			links = append(links, block[i].Link)
+		}
+	}
+	at := func(id int) *trace.Event {
+		if id <= 0 || id > len(events) {
+			return nil
+		}
+		return events[id-1]
+	}
+	for i, l := range links {
+		events[i].Link = at(l)
+	}
+
+	gs := make(map[uint64]*trace.GDesc, len(x.meta.Goroutines))
+	for _, ig := range x.meta.Goroutines {
+		g := new(trace.GDesc)
+		*g = ig.G
+		g.EndBlock = at(ig.EndBlock)
+		for _, s := range ig.Regions {
+			g.Regions = append(g.Regions, &trace.UserRegionDesc{TaskID: s.TaskID, Name: s.Name, Start: at(s.Start), End: at(s.End), GExecutionStat: s.Stat})
+		}
+		gs[g.ID] = g
+	}
+	return trace.ParseResult{Events: events, Stacks: x.meta.Stacks}, gs, nil
+}
+
+// window reads the events with timestamps in [from, to], and only the
+// blocks of the index needed for them. The events linked to by events
+// of the window are read too, but their own links are left nil when
+// they are outside of the window.
+func (x *traceIndex) window(from, to int64) ([]*trace.Event, error) {
+	f, err := os.Open(x.file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	loaded := make(map[int]*trace.Event) // by event index plus one
+	var events []*trace.Event
+	var links []int
+	for _, b := range x.meta.Blocks {
+		if b.End < from || b.Start > to {
+			continue
+		}
+		block, err := x.readBlock(f, b)
+		if err != nil {
+			return nil, err
+		}
+		for i := range block {
+			if ie := &block[i]; ie.Ts >= from && ie.Ts <= to {
+				ev := ie.event(x.meta.Stacks)
+				loaded[b.First+i+1] = ev
+				events = append(events, ev)
+				links = append(links, ie.Link)
+			}
+		}
+	}
+
+	var missing []int
+	for _, l := range links {
+		if l != 0 && loaded[l] == nil {
+			missing = append(missing, l)
+		}
+	}
+	sort.Ints(missing)
+	for _, b := range x.meta.Blocks {
+		if len(missing) == 0 {
+			break
+		}
+		if missing[0] > b.First+b.N {
+			continue
+		}
+		block, err := x.readBlock(f, b)
+		if err != nil {
+			return nil, err
+		}
+		for len(missing) > 0 && missing[0] <= b.First+b.N {
+			if id := missing[0]; loaded[id] == nil {
+				loaded[id] = block[id-1-b.First].event(x.meta.Stacks)
+			}
+			missing = missing[1:]
+		}
+	}
+	for i, l := range links {
+		events[i].Link = loaded[l]
+	}
+	return events, nil
+}
+
+// scan passes the events of the index to add in order, a block at a
+// time, up to the first block with events after to. Only the events
+// whose linked event is not read yet are kept from one block to the
+// next, and their Link is set when it is read, as with a trace.Stream.
+func (x *traceIndex) scan(to int64, add func([]*trace.Event)) error {
+	f, err := os.Open(x.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	pending := make(map[int][]*trace.Event) // by index plus one of the linked event
+	for _, b := range x.meta.Blocks {
+		block, err := x.readBlock(f, b)
+		if err != nil {
+			return err
+		}
+		events := make([]*trace.Event, len(block))
+		for i := range block {
+			id := b.First + i + 1
+			ev := block[i].event(x.meta.Stacks)
+			events[i] = ev
+			for _, p := range pending[id] {
+				p.Link = ev
+			}
+			delete(pending, id)
+			switch l := block[i].Link; {
+			case l > id:
+				pending[l] = append(pending[l], ev)
+			case l > b.First:
+				ev.Link = events[l-1-b.First]
+			}
+		}
+		add(events)
+		if b.End > to {
+			break
+		}
+	}
+	return nil
+}
+
+// goroutines returns the goroutine statistics of the index without
+// reading the events, so without EndBlock and Regions.
+func (x *traceIndex) goroutines() map[uint64]*trace.GDesc {
+	gs := make(map[uint64]*trace.GDesc, len(x.meta.Goroutines))
+	for _, ig := range x.meta.Goroutines {
+		g := new(trace.GDesc)
+		*g = ig.G
+		gs[g.ID] = g
+	}
+	return gs
+}
+
+// open prepares the trace for the analyses on first use, see openTrace,
+// and returns its metadata.
+func (st *traceState) open() (*traceMeta, error) {
+	st.openInit.Do(func() {
+		st.meta, st.openErr = st.openTrace()
+	})
+	return st.meta, st.openErr
+}
+
+// openTrace prepares the trace of st.loader for the analyses and sets
+// st.ranges. It uses the index of the trace if there is an up to date
+// one, so that the events are only read when needed. Otherwise it
+// parses and splits the trace, and writes its index if -index is set.
+func (st *traceState) openTrace() (*traceMeta, error) {
+	var hash string
+	if *indexFlag {
+		x, h, err := openIndex(st.loader.file)
+		if err == nil {
+			log.Printf("Using index %s", x.file)
+			st.loader.index = x
+			st.ranges = x.meta.Ranges
+			m := x.meta.Trace
+			return &m, nil
+		}
+		hash = h
+	}
+	res, err := st.parseTrace()
+	if err != nil {
+		return nil, err
+	}
+	st.ranges = splitTrace(res)
+	m := newTraceMeta(res.Events)
+	if *indexFlag && len(res.Events) > 0 {
+		st.analyzeGoroutines(res.Events)
+		meta := indexMeta{Trace: *m, Stacks: res.Stacks, Ranges: st.ranges, Timeline: st.newTimeline(res.Events)}
+		path, err := writeIndex(st.loader.file, hash, meta, res.Events, st.gs)
+		if err != nil {
+			log.Printf("Failed to write index: %v", err)
+		} else {
+			log.Printf("Wrote index %s", path)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,216 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/trace"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	traceparser "github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+)
+
+func TestIndex(t *testing.T) {
+	defer func(st *traceState) { mainTrace = st }(mainTrace)
+	defer func(n int) { indexBlockEvents = n }(indexBlockEvents)
+	indexBlockEvents = 64
+
+	buf := new(bytes.Buffer)
+	if err := trace.Start(buf); err != nil {
+		t.Fatal(err)
+	}
+	prog0()
+	trace.Stop()
+	file := filepath.Join(t.TempDir(), "prog0.trace")
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := traceparser.Parse(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	wantGs := traceparser.GoroutineStats(want.Events)
+
+	mainTrace = newTraceState(file, "")
+	if _, err := mainTrace.openTrace(); err != nil {
+		t.Fatalf("openTrace: %v", err)
+	}
+	if mainTrace.loader.index != nil {
+		t.Fatalf("index used before it is written")
+	}
+	wantRanges := mainTrace.ranges
+	if _, err := os.Stat(file + indexSuffix); err != nil {
+		t.Fatalf("index not written: %v", err)
+	}
+
+	// Reopen the trace from its index, also after it is touched.
+	for _, touch := range []bool{false, true} {
+		if touch {
+			now := time.Now().Add(time.Hour)
+			if err := os.Chtimes(file, now, now); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mainTrace = newTraceState(file, "")
+		m, err := mainTrace.openTrace()
+		if err != nil {
+			t.Fatalf("openTrace: %v", err)
+		}
+		if mainTrace.loader.index == nil {
+			t.Fatalf("index not used (touched %v)", touch)
+		}
+		if m.Events != len(want.Events) || !reflect.DeepEqual(mainTrace.ranges, wantRanges) {
+			t.Errorf("index has %d events and ranges %v, want %d and %v", m.Events, mainTrace.ranges, len(want.Events), wantRanges)
+		}
+	}
+
+	// The bounds, the timeline and the analyses of a window do not read
+	// every event.
+	n := len(want.Events)
+	if first, last := mainTrace.firstTimestamp(), mainTrace.lastTimestamp(); first != want.Events[0].Ts || last != want.Events[n-1].Ts {
+		t.Errorf("index bounds [%d, %d], want [%d, %d]", first, last, want.Events[0].Ts, want.Events[n-1].Ts)
+	}
+	if tl := mainTrace.loader.index.meta.Timeline; tl.Start != want.Events[0].Ts || len(tl.Running) != procSeriesBuckets {
+		t.Errorf("index timeline %+v", tl)
+	}
+	win := traceWindow{want.Events[n/4].Ts, want.Events[n/2].Ts}
+	winGs, err := mainTrace.goroutineStats(win)
+	if err != nil {
+		t.Fatalf("goroutine statistics of the window: %v", err)
+	}
+	wantWinGs := traceparser.GoroutineStatsWindow(want.Events, win.from, win.to)
+	if len(winGs) != len(wantWinGs) {
+		t.Errorf("window has %d goroutines, want %d", len(winGs), len(wantWinGs))
+	}
+	for id, w := range wantWinGs {
+		if g := winGs[id]; g == nil || g.Name != w.Name || g.EndTime != w.EndTime || g.GExecutionStat.TotalTime.Total != w.GExecutionStat.TotalTime.Total ||
+			g.ExecTime.Count != w.ExecTime.Count || g.ExecTime.Total != w.ExecTime.Total || g.SchedWaitTime.Total != w.SchedWaitTime.Total {
+			t.Errorf("goroutine %d of the window is %+v, want %+v", id, g, w)
+		}
+	}
+	p, err := mainTrace.scanPprof(win.clip(nil, wantGs), win.to, pprofSchedEvent)
+	if err != nil {
+		t.Fatalf("profile of the window: %v", err)
+	}
+	total := func(p *profile.Profile) (n, d int64) {
+		for _, s := range p.Sample {
+			n += s.Value[0]
+			d += s.Value[1]
+		}
+		return n, d
+	}
+	gotN, gotD := total(p)
+	wantN, wantD := total(computePprof(win.clip(nil, wantGs), want.Events, pprofSchedEvent))
+	if gotN != wantN || gotD != wantD || gotN == 0 {
+		t.Errorf("profile of the window has %d events for %d ns, want %d for %d ns", gotN, gotD, wantN, wantD)
+	}
+	util, _, err := mainTrace.getMMUCurveWindow(reportMMUFlags, win)
+	if err != nil {
+		t.Fatalf("utilization of the window: %v", err)
+	}
+	if want := clipMutatorUtil(traceparser.MutatorUtilization(want.Events, reportMMUFlags), win); !reflect.DeepEqual(util, want) {
+		t.Errorf("utilization of the window differs from that of the whole trace")
+	}
+	if mainTrace.loader.parsed.Load() {
+		t.Errorf("analyses of a window read the whole index")
+	}
+
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to read the index: %v", err)
+	}
+	if len(events) != len(want.Events) {
+		t.Fatalf("index has %d events, want %d", len(events), len(want.Events))
+	}
+	for i, ev := range events {
+		w := want.Events[i]
+		if ev.Type != w.Type || ev.Ts != w.Ts || ev.G != w.G || len(ev.Stk) != len(w.Stk) || (ev.Link == nil) != (w.Link == nil) ||
+			ev.Link != nil && ev.Link.Ts != w.Link.Ts {
+			t.Fatalf("event %d is %v, want %v", i, ev, w)
+		}
+	}
+	mainTrace.analyzeGoroutines(events)
+	if len(mainTrace.gs) != len(wantGs) {
+		t.Errorf("index has %d goroutines, want %d", len(mainTrace.gs), len(wantGs))
+	}
+	for id, w := range wantGs {
+		g := mainTrace.gs[id]
+		if g == nil || g.Name != w.Name || g.ExecTime.Total != w.ExecTime.Total || len(g.Regions) != len(w.Regions) {
+			t.Errorf("goroutine %d is %+v, want %+v", id, g, w)
+			continue
+		}
+		for i, s := range g.Regions {
+			if s.Name != w.Regions[i].Name || (s.Start == nil) != (w.Regions[i].Start == nil) || s.Start != nil && s.Start.Ts != w.Regions[i].Start.Ts {
+				t.Errorf("goroutine %d region %d is %+v, want %+v", id, i, s, w.Regions[i])
+			}
+		}
+	}
+
+	// A window in the middle of the trace, across blocks.
+	from, to := want.Events[n/4].Ts, want.Events[n/2].Ts
+	winEvents, err := mainTrace.loader.index.window(from, to)
+	if err != nil {
+		t.Fatalf("window: %v", err)
+	}
+	var wantWin []*traceparser.Event
+	for _, ev := range want.Events {
+		if ev.Ts >= from && ev.Ts <= to {
+			wantWin = append(wantWin, ev)
+		}
+	}
+	if len(winEvents) != len(wantWin) {
+		t.Fatalf("window has %d events, want %d", len(winEvents), len(wantWin))
+	}
+	for i, ev := range winEvents {
+		w := wantWin[i]
+		if ev.Ts != w.Ts || (ev.Link == nil) != (w.Link == nil) || ev.Link != nil && (ev.Link.Ts != w.Link.Ts || ev.Link.Type != w.Link.Type) {
+			t.Fatalf("window event %d is %v, want %v", i, ev, w)
+		}
+	}
+
+	// A different trace of the same name has no index.
+	if err := ioutil.WriteFile(file, buf.Bytes()[:len(buf.Bytes())-1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := openIndex(file); err == nil {
+		t.Errorf("index of a modified trace used")
+	}
+}
+
+func TestTraceLoaderStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := trace.Start(buf); err != nil {
+		t.Fatal(err)
+	}
+	prog0()
+	trace.Stop()
+	file := filepath.Join(t.TempDir(), "prog0.trace")
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := &traceLoader{file: file}
+	res, err := l.parse()
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	want, err := traceparser.Parse(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if len(res.Events) != len(want.Events) {
+		t.Fatalf("streamed %d events, want %d", len(res.Events), len(want.Events))
+	}
+	// The statistics computed while streaming are those of the events.
+	if got, want := l.gs, traceparser.GoroutineStats(res.Events); !reflect.DeepEqual(got, want) {
+		t.Errorf("goroutine statistics of the stream differ from those of the events")
+	}
+	if got, want := l.util, traceparser.MutatorUtilization(res.Events, reportMMUFlags); !reflect.DeepEqual(got, want) {
+		t.Errorf("mutator utilization of the stream differs from that of the events")
+	}
+}
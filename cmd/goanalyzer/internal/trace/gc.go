@@ -51,25 +51,67 @@ const (
 // If the UtilPerProc flag is not given, this always returns a single
 // utilization function. Otherwise, it returns one function per P.
 func MutatorUtilization(events []*Event, flags UtilFlags) [][]MutatorUtil {
-	if len(events) == 0 {
-		return nil
+	b := NewMutatorUtilBuilder(flags)
+	b.Add(events)
+	return b.Finish()
+}
+
+// MutatorUtilBuilder generates the utilization functions of
+// MutatorUtilization incrementally, from the chunks of events returned
+// by a Stream.
+type MutatorUtilBuilder struct {
+	flags UtilFlags
+	ps    []mutatorUtilP
+	stw   int
+
+	out     [][]MutatorUtil
+	assists map[uint64]bool
+	running map[uint64]bool
+	bgMark  map[uint64]bool
+	lastTs  int64
+	empty   bool
+}
+
+type mutatorUtilP struct {
+	// gc > 0 indicates that GC is active on this P.
+	gc int
+	// series the logical series number for this P. This
+	// is necessary because Ps may be removed and then
+	// re-added, and then the new P needs a new series.
+	series int
+}
+
+func NewMutatorUtilBuilder(flags UtilFlags) *MutatorUtilBuilder {
+	return &MutatorUtilBuilder{
+		flags:   flags,
+		out:     [][]MutatorUtil{},
+		assists: map[uint64]bool{},
+		running: map[uint64]bool{},
+		bgMark:  map[uint64]bool{},
+		empty:   true,
 	}
+}
 
-	type perP struct {
-		// gc > 0 indicates that GC is active on this P.
-		gc int
-		// series the logical series number for this P. This
-		// is necessary because Ps may be removed and then
-		// re-added, and then the new P needs a new series.
-		series int
+// goStops reports whether events of type typ end the execution of the
+// goroutine started by the preceding GoStart, which links to them.
+func goStops(typ byte) bool {
+	switch typ {
+	case EvGoEnd, EvGoStop, EvGoSched, EvGoPreempt, EvGoSysBlock,
+		EvGoSleep, EvGoBlock, EvGoBlockSend, EvGoBlockRecv, EvGoBlockSelect,
+		EvGoBlockSync, EvGoBlockCond, EvGoBlockNet, EvGoBlockGC:
+		return true
 	}
-	ps := []perP{}
-	stw := 0
+	return false
+}
 
-	out := [][]MutatorUtil{}
-	assists := map[uint64]bool{}
-	block := map[uint64]*Event{}
-	bgMark := map[uint64]bool{}
+// Add adds the next events of the trace.
+func (b *MutatorUtilBuilder) Add(events []*Event) {
+	flags, ps, stw, out := b.flags, b.ps, b.stw, b.out
+	assists, running, bgMark := b.assists, b.running, b.bgMark
+	if len(events) > 0 {
+		b.empty = false
+		b.lastTs = events[len(events)-1].Ts
+	}
 
 	for _, ev := range events {
 		switch ev.Type {
@@ -91,7 +133,7 @@ func MutatorUtilization(events []*Event, flags UtilFlags) [][]MutatorUtil {
 					series = len(out)
 					out = append(out, []MutatorUtil{{ev.Ts, 1}})
 				}
-				ps = append(ps, perP{series: series})
+				ps = append(ps, mutatorUtilP{series: series})
 			}
 		case EvGCSTWStart:
 			if flags&UtilSTW != 0 {
@@ -139,9 +181,11 @@ func MutatorUtilization(events []*Event, flags UtilFlags) [][]MutatorUtil {
 				// Unblocked during assist.
 				ps[ev.P].gc++
 			}
-			block[ev.G] = ev.Link
+			// The Link of the GoStart, which ends the execution, may
+			// not be known yet: look for it by type.
+			running[ev.G] = true
 		default:
-			if ev != block[ev.G] {
+			if !running[ev.G] || !goStops(ev.Type) {
 				continue
 			}
 
@@ -154,7 +198,7 @@ func MutatorUtilization(events []*Event, flags UtilFlags) [][]MutatorUtil {
 				ps[ev.P].gc--
 				delete(bgMark, ev.G)
 			}
-			delete(block, ev.G)
+			delete(running, ev.G)
 		}
 
 		if flags&UtilPerProc == 0 {
@@ -190,15 +234,23 @@ func MutatorUtilization(events []*Event, flags UtilFlags) [][]MutatorUtil {
 		}
 	}
 
+	b.ps, b.stw, b.out = ps, stw, out
+}
+
+// Finish returns the utilization functions of the events added.
+func (b *MutatorUtilBuilder) Finish() [][]MutatorUtil {
+	if b.empty {
+		return nil
+	}
 	// Add final 0 utilization event to any remaining series. This
 	// is important to mark the end of the trace. The exact value
 	// shouldn't matter since no window should extend beyond this,
 	// but using 0 is symmetric with the start of the trace.
-	mu := MutatorUtil{events[len(events)-1].Ts, 0}
-	for i := range ps {
-		out[ps[i].series] = addUtil(out[ps[i].series], mu)
+	mu := MutatorUtil{b.lastTs, 0}
+	for i := range b.ps {
+		b.out[b.ps[i].series] = addUtil(b.out[b.ps[i].series], mu)
 	}
-	return out
+	return b.out
 }
 
 func addUtil(util []MutatorUtil, mu MutatorUtil) []MutatorUtil {
@@ -80,7 +80,7 @@ func TestMMUTrace(t *testing.T) {
 	// Can't be t.Parallel() because it modifies the
 	// testingOneBand package variable.
 
-	data, err := ioutil.ReadFile("testdata/stress_1_10_good")
+	data, err := ioutil.ReadFile("testdata/stress_1_11_good")
 	if err != nil {
 		t.Fatalf("failed to read input file: %v", err)
 	}
@@ -122,7 +122,7 @@ func TestMMUTrace(t *testing.T) {
 }
 
 func BenchmarkMMU(b *testing.B) {
-	data, err := ioutil.ReadFile("testdata/stress_1_10_good")
+	data, err := ioutil.ReadFile("testdata/stress_1_11_good")
 	if err != nil {
 		b.Fatalf("failed to read input file: %v", err)
 	}
@@ -5,6 +5,7 @@
 package trace
 
 import (
+	"math"
 	"sort"
 )
 
@@ -17,6 +18,15 @@ type GDesc struct {
 	StartTime    int64
 	EndTime      int64
 
+	// Goroutine that created this goroutine, 0 if it existed
+	// at the start of the trace, and the stack of its go statement.
+	ParentID    uint64
+	CreationStk []*Frame
+
+	// Blocking event of a goroutine that was still blocked at the end
+	// of the trace, nil otherwise.
+	EndBlock *Event
+
 	// List of regions in the goroutine, sorted based on the start time.
 	Regions []*UserRegionDesc
 
@@ -43,16 +53,19 @@ type UserRegionDesc struct {
 	End *Event
 
 	GExecutionStat
+
+	from int64 // start of the region, while it is active
 }
 
-// GExecutionStateEntry contains basic statistical timing information. A more
-// advanced version might keep a histogram of timings. All of the values are
+// GExecutionStateEntry contains basic statistical timing information and
+// a histogram of the timings for percentiles. All of the values are
 // in nanos, except Count which is a count of entries in Total
 type GExecutionStatEntry struct {
 	Count int64
 	Total int64
 	Min   int64
 	Max   int64
+	Hist  Histogram
 }
 
 func (s *GExecutionStatEntry) addTime(time int64) {
@@ -71,7 +84,7 @@ func (s *GExecutionStatEntry) addTime(time int64) {
 	if time > s.Max {
 		s.Max = time
 	}
-
+	s.Hist.Add(time)
 }
 
 func (s *GExecutionStatEntry) AddStat(s2 GExecutionStatEntry) {
@@ -88,6 +101,58 @@ func (s *GExecutionStatEntry) AddStat(s2 GExecutionStatEntry) {
 	}
 	s.Total += s2.Total
 	s.Count += s2.Count
+	s.Hist.merge(s2.Hist)
+}
+
+// Percentile returns the approximate p-th percentile (0 <= p <= 100)
+// of the timings in nanos, or 0 if there are none.
+func (s GExecutionStatEntry) Percentile(p float64) int64 {
+	switch {
+	case s.Count == 0:
+		return 0
+	case p <= 0:
+		return s.Min
+	case p >= 100:
+		return s.Max
+	}
+	v := s.Hist.quantile(p / 100)
+	if v < s.Min {
+		v = s.Min
+	}
+	if v > s.Max {
+		v = s.Max
+	}
+	return v
+}
+
+// Stddev returns the approximate sample standard deviation of the
+// timings in nanos, taking each timing at the midpoint of its histogram
+// bucket (clamped to Min and Max), or 0 if there are fewer than two.
+func (s GExecutionStatEntry) Stddev() float64 {
+	if s.Count < 2 {
+		return 0
+	}
+	mean := float64(s.Total) / float64(s.Count)
+	var n, sum float64
+	for i, c := range s.Hist.Buckets {
+		if c == 0 {
+			continue
+		}
+		v := bucketMid(i)
+		if v < s.Min {
+			v = s.Min
+		}
+		if v > s.Max {
+			v = s.Max
+		}
+		d := float64(v) - mean
+		sum += float64(c) * d * d
+		n += float64(c)
+	}
+	if n < 2 {
+		return 0
+	}
+	return math.Sqrt(sum / (n - 1))
 }
 
 // GExecutionStat contains statistics about a goroutine's execution
@@ -100,45 +165,71 @@ type GExecutionStat struct {
 	SyscallTime   GExecutionStatEntry
 	GCTime        GExecutionStatEntry
 	SweepTime     GExecutionStatEntry
-	TotalTime     GExecutionStatEntry
+	// MarkAssistTime is the time spent in GC mark assists, taken out of
+	// ExecTime, and blocked waiting for GC assist work.
+	MarkAssistTime GExecutionStatEntry
+	TotalTime      GExecutionStatEntry
+}
+
+// AddStat adds the statistics of s2 to s.
+func (s *GExecutionStat) AddStat(s2 GExecutionStat) {
+	s.ExecTime.AddStat(s2.ExecTime)
+	s.SchedWaitTime.AddStat(s2.SchedWaitTime)
+	s.IOTime.AddStat(s2.IOTime)
+	s.BlockTime.AddStat(s2.BlockTime)
+	s.SyscallTime.AddStat(s2.SyscallTime)
+	s.GCTime.AddStat(s2.GCTime)
+	s.SweepTime.AddStat(s2.SweepTime)
+	s.MarkAssistTime.AddStat(s2.MarkAssistTime)
+	s.TotalTime.AddStat(s2.TotalTime)
 }
 
-// sub returns the stats v-s.
-func (s GExecutionStat) sub(v GExecutionStat) (r GExecutionStat) {
-	r = s
-	r.ExecTime.Total -= v.ExecTime.Total
-	r.ExecTime.Min -= v.ExecTime.Min
-	r.ExecTime.Max -= v.ExecTime.Max
-	r.SchedWaitTime.Total -= v.SchedWaitTime.Total
-	r.SchedWaitTime.Min -= v.SchedWaitTime.Min
-	r.SchedWaitTime.Max -= v.SchedWaitTime.Max
-	r.IOTime.Total -= v.IOTime.Total
-	r.IOTime.Min -= v.IOTime.Min
-	r.IOTime.Max -= v.IOTime.Max
-	r.BlockTime.Total -= v.BlockTime.Total
-	r.BlockTime.Min -= v.BlockTime.Min
-	r.BlockTime.Max -= v.BlockTime.Max
-	r.SyscallTime.Total -= v.SyscallTime.Total
-	r.SyscallTime.Min -= r.SyscallTime.Min
-	r.SyscallTime.Max -= r.SyscallTime.Max
-	r.GCTime.Total -= v.GCTime.Total
-	r.GCTime.Min -= v.GCTime.Min
-	r.GCTime.Max -= v.GCTime.Max
-	r.SweepTime.Total -= v.SweepTime.Total
-	r.SweepTime.Min -= v.SweepTime.Min
-	r.SweepTime.Max -= v.SweepTime.Max
-	r.TotalTime.Total -= v.TotalTime.Total
-	r.TotalTime.Min -= r.TotalTime.Min
-	r.TotalTime.Max -= r.TotalTime.Max
-	return r
+// entries returns the entries of s.
+func (s *GExecutionStat) entries() [9]*GExecutionStatEntry {
+	return [...]*GExecutionStatEntry{
+		&s.ExecTime, &s.SchedWaitTime, &s.IOTime, &s.BlockTime, &s.SyscallTime,
+		&s.GCTime, &s.SweepTime, &s.MarkAssistTime, &s.TotalTime,
+	}
+}
+
+// clone returns a copy of s that does not share histogram storage with s.
+func (s GExecutionStat) clone() GExecutionStat {
+	s.ExecTime.Hist = s.ExecTime.Hist.clone()
+	s.SchedWaitTime.Hist = s.SchedWaitTime.Hist.clone()
+	s.IOTime.Hist = s.IOTime.Hist.clone()
+	s.BlockTime.Hist = s.BlockTime.Hist.clone()
+	s.SyscallTime.Hist = s.SyscallTime.Hist.clone()
+	s.GCTime.Hist = s.GCTime.Hist.clone()
+	s.SweepTime.Hist = s.SweepTime.Hist.clone()
+	s.MarkAssistTime.Hist = s.MarkAssistTime.Hist.clone()
+	s.TotalTime.Hist = s.TotalTime.Hist.clone()
+	return s
 }
 
 // snapshotStat returns the snapshot of the goroutine execution statistics.
 // This is called as we process the ordered trace event stream. lastTs and
 // activeGCStartTime are used to process pending statistics if this is called
 // before any goroutine end event.
-func (g *GDesc) snapshotStat(lastTs, activeGCStartTime int64) (ret GExecutionStat) {
-	ret = g.GExecutionStat
+func (g *GDesc) snapshotStat(lastTs, activeGCStartTime int64) GExecutionStat {
+	return g.pendingStat(g.GExecutionStat, lastTs, activeGCStartTime, g.addTime)
+}
+
+// regionStat returns the statistics of the active region r at lastTs:
+// those of the intervals added since it started, with the pending ones
+// from its start.
+func (g *GDesc) regionStat(r *UserRegionDesc, lastTs, activeGCStartTime int64) GExecutionStat {
+	return g.pendingStat(r.GExecutionStat, lastTs, activeGCStartTime, func(s *GExecutionStatEntry, start, end int64) {
+		if start < r.from {
+			start = r.from
+		}
+		g.addTime(s, start, end)
+	})
+}
+
+// pendingStat returns the statistics stat with the pending ones at
+// lastTs added by add.
+func (g *GDesc) pendingStat(stat GExecutionStat, lastTs, activeGCStartTime int64, add func(s *GExecutionStatEntry, start, end int64)) (ret GExecutionStat) {
+	ret = stat.clone()
 
 	if g.gdesc == nil {
 		return ret // finalized GDesc. No pending state.
@@ -146,55 +237,110 @@ func (g *GDesc) snapshotStat(lastTs, activeGCStartTime int64) (ret GExecutionSta
 
 	if activeGCStartTime != 0 { // terminating while GC is active
 		if g.CreationTime < activeGCStartTime {
-			ret.GCTime.addTime(lastTs - activeGCStartTime)
+			add(&ret.GCTime, activeGCStartTime, lastTs)
 		} else {
 			// The goroutine's lifetime completely overlaps
 			// with a GC.
-			ret.GCTime.addTime(lastTs - g.CreationTime)
+			add(&ret.GCTime, g.CreationTime, lastTs)
 		}
 	}
 
 	if g.TotalTime.Count == 0 {
-		ret.TotalTime.addTime(lastTs - g.CreationTime)
+		add(&ret.TotalTime, g.CreationTime, lastTs)
 	}
 
 	if g.lastStartTime != 0 {
-		ret.ExecTime.addTime(lastTs - g.lastStartTime)
+		add(&ret.ExecTime, g.lastStartTime, lastTs)
 	}
 	if g.blockNetTime != 0 {
-		ret.IOTime.addTime(lastTs - g.blockNetTime)
+		add(&ret.IOTime, g.blockNetTime, lastTs)
 	}
 	if g.blockSyncTime != 0 {
-		ret.BlockTime.addTime(lastTs - g.blockSyncTime)
+		add(&ret.BlockTime, g.blockSyncTime, lastTs)
 	}
 	if g.blockSyscallTime != 0 {
-		ret.SyscallTime.addTime(lastTs - g.blockSyscallTime)
+		add(&ret.SyscallTime, g.blockSyscallTime, lastTs)
 	}
 	if g.blockSchedTime != 0 {
-		ret.SchedWaitTime.addTime(lastTs - g.blockSchedTime)
+		add(&ret.SchedWaitTime, g.blockSchedTime, lastTs)
 	}
 	if g.blockSweepTime != 0 {
-		ret.SweepTime.addTime(lastTs - g.blockSweepTime)
+		add(&ret.SweepTime, g.blockSweepTime, lastTs)
+	}
+	if g.markAssistTime != 0 {
+		add(&ret.MarkAssistTime, g.markAssistTime, lastTs)
+	}
+	if g.blockGCTime != 0 {
+		add(&ret.MarkAssistTime, g.blockGCTime, lastTs)
 	}
 	return ret
 }
 
+// addTime adds the time from start to end to s, limited to the window
+// of the statistics. If s is an entry of the statistics of g, the time
+// since the start of each active region is also added to the same entry
+// of the region.
+func (g *GDesc) addTime(s *GExecutionStatEntry, start, end int64) {
+	if start < g.from {
+		start = g.from
+	}
+	if end > g.to {
+		end = g.to
+	}
+	if start >= end {
+		return
+	}
+	s.addTime(end - start)
+	if len(g.activeRegions) == 0 {
+		return
+	}
+	for i, e := range g.GExecutionStat.entries() {
+		if e != s {
+			continue
+		}
+		for _, r := range g.activeRegions {
+			if rstart := max(start, r.from); rstart < end {
+				r.GExecutionStat.entries()[i].addTime(end - rstart)
+			}
+		}
+		return
+	}
+}
+
+// stopExec ends the execution, or the mark assist, the goroutine has
+// been running since its last start.
+func (g *GDesc) stopExec(ts int64) {
+	if g.markAssistTime != 0 {
+		g.addTime(&g.MarkAssistTime, g.markAssistTime, ts)
+		g.markAssistTime = 0
+	} else {
+		g.addTime(&g.ExecTime, g.lastStartTime, ts)
+	}
+	g.lastStartTime = 0
+}
+
 // finalize is called when processing a goroutine end event or at
 // the end of trace processing. This finalizes the execution GExecutionStatEntry
 // and any active regions in the goroutine, in which case trigger is nil.
 func (g *GDesc) finalize(lastTs, activeGCStartTime int64, trigger *Event) {
 	if trigger != nil {
 		g.EndTime = trigger.Ts
+	} else if g.gdesc != nil {
+		g.EndBlock = g.blockEv
+	}
+	if g.PC == 0 && g.gdesc != nil && g.unnamedStart != nil && len(g.unnamedStart.Stk) > 0 {
+		// The stack of the start event was set after it was added,
+		// see Stream.
+		g.PC = g.unnamedStart.Stk[0].PC
+		g.Name = g.unnamedStart.Stk[0].Fn
 	}
-	finalStat := g.snapshotStat(lastTs, activeGCStartTime)
-
-	g.GExecutionStat = finalStat
 	for _, s := range g.activeRegions {
 		s.End = trigger
-		s.GExecutionStat = finalStat.sub(s.GExecutionStat)
+		s.GExecutionStat = g.regionStat(s, lastTs, activeGCStartTime)
 		g.Regions = append(g.Regions, s)
 	}
-	*(g.gdesc) = gdesc{}
+	g.GExecutionStat = g.snapshotStat(lastTs, activeGCStartTime)
+	*(g.gdesc) = gdesc{from: g.from, to: g.to}
 }
 
 // gdesc is a private part of GDesc that is required only during analysis.
@@ -206,20 +352,62 @@ type gdesc struct {
 	blockSweepTime   int64
 	blockGCTime      int64
 	blockSchedTime   int64
+	markAssistTime   int64 // start of the running part of the current mark assist
+	inMarkAssist     bool
+
+	from, to int64 // window of the statistics
+
+	blockEv      *Event // last blocking event, nil if not blocked
+	unnamedStart *Event // first start event, while it has no stack
 
 	activeRegions []*UserRegionDesc // stack of active regions
 }
 
 // GoroutineStats generates statistics for all goroutines in the trace.
 func GoroutineStats(events []*Event) map[uint64]*GDesc {
-	gs := make(map[uint64]*GDesc)
-	var lastTs int64
-	var gcStartTime int64 // gcStartTime == 0 indicates gc is inactive.
+	b := NewGoroutineStatsBuilder()
+	b.Add(events)
+	return b.Finish()
+}
+
+// GoroutineStatsWindow generates statistics for the goroutines that
+// existed during the time window [from, to] of the trace, counting only
+// the time in the window: intervals across its bounds are cut at them.
+// The other fields of GDesc describe the whole goroutine.
+func GoroutineStatsWindow(events []*Event, from, to int64) map[uint64]*GDesc {
+	b := NewGoroutineStatsWindowBuilder(from, to)
+	b.Add(events)
+	return b.Finish()
+}
+
+// GoroutineStatsBuilder generates the statistics of GoroutineStats
+// incrementally, from the chunks of events returned by a Stream.
+type GoroutineStatsBuilder struct {
+	gs          map[uint64]*GDesc
+	lastTs      int64
+	gcStartTime int64 // gcStartTime == 0 indicates gc is inactive.
+	from, to    int64 // window of the statistics
+}
+
+func NewGoroutineStatsBuilder() *GoroutineStatsBuilder {
+	return NewGoroutineStatsWindowBuilder(math.MinInt64, math.MaxInt64)
+}
+
+// NewGoroutineStatsWindowBuilder returns a builder of the statistics of
+// GoroutineStatsWindow.
+func NewGoroutineStatsWindowBuilder(from, to int64) *GoroutineStatsBuilder {
+	return &GoroutineStatsBuilder{gs: make(map[uint64]*GDesc), from: from, to: to}
+}
+
+// Add adds the next events of the trace.
+func (b *GoroutineStatsBuilder) Add(events []*Event) {
+	gs := b.gs
+	lastTs, gcStartTime := b.lastTs, b.gcStartTime
 	for _, ev := range events {
 		lastTs = ev.Ts
 		switch ev.Type {
 		case EvGoCreate:
-			g := &GDesc{ID: ev.Args[0], CreationTime: ev.Ts, gdesc: new(gdesc)}
+			g := &GDesc{ID: ev.Args[0], CreationTime: ev.Ts, ParentID: ev.G, CreationStk: ev.Stk, gdesc: &gdesc{from: b.from, to: b.to}}
 			g.blockSchedTime = ev.Ts
 			// When a goroutine is newly created, inherit the
 			// task of the active region. For ease handling of
@@ -230,23 +418,31 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 				s := regions[len(regions)-1]
 				if s.TaskID != 0 {
 					g.gdesc.activeRegions = []*UserRegionDesc{
-						{TaskID: s.TaskID, Start: ev},
+						{TaskID: s.TaskID, Start: ev, from: ev.Ts},
 					}
 				}
 			}
 			gs[g.ID] = g
 		case EvGoStart, EvGoStartLabel:
 			g := gs[ev.G]
-			if g.PC == 0 {
+			if g.PC == 0 && len(ev.Stk) > 0 {
 				g.PC = ev.Stk[0].PC
 				g.Name = ev.Stk[0].Fn
+				g.unnamedStart = nil
+			} else if g.PC == 0 && g.unnamedStart == nil {
+				g.unnamedStart = ev
 			}
-			g.lastStartTime = ev.Ts
+			if g.inMarkAssist {
+				g.markAssistTime = ev.Ts
+			} else {
+				g.lastStartTime = ev.Ts
+			}
+			g.blockEv = nil
 			if g.StartTime == 0 {
 				g.StartTime = ev.Ts
 			}
 			if g.blockSchedTime != 0 {
-				g.SchedWaitTime.addTime(ev.Ts - g.blockSchedTime)
+				g.addTime(&g.SchedWaitTime, g.blockSchedTime, ev.Ts)
 				g.blockSchedTime = 0
 			}
 		case EvGoEnd, EvGoStop:
@@ -255,51 +451,76 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 		case EvGoBlockSend, EvGoBlockRecv, EvGoBlockSelect,
 			EvGoBlockSync, EvGoBlockCond:
 			g := gs[ev.G]
-			g.ExecTime.addTime(ev.Ts - g.lastStartTime)
-			g.lastStartTime = 0
+			g.stopExec(ev.Ts)
 			g.blockSyncTime = ev.Ts
+			g.blockEv = ev
 		case EvGoSched, EvGoPreempt:
 			g := gs[ev.G]
-			g.ExecTime.addTime(ev.Ts - g.lastStartTime)
-			g.lastStartTime = 0
+			g.stopExec(ev.Ts)
 			g.blockSchedTime = ev.Ts
 		case EvGoSleep, EvGoBlock:
 			g := gs[ev.G]
-			g.ExecTime.addTime(ev.Ts - g.lastStartTime)
-			g.lastStartTime = 0
+			g.stopExec(ev.Ts)
+			g.blockEv = ev
 		case EvGoBlockNet:
 			g := gs[ev.G]
-			g.ExecTime.addTime(ev.Ts - g.lastStartTime)
-			g.lastStartTime = 0
+			g.stopExec(ev.Ts)
 			g.blockNetTime = ev.Ts
+			g.blockEv = ev
 		case EvGoBlockGC:
 			g := gs[ev.G]
-			g.ExecTime.addTime(ev.Ts - g.lastStartTime)
-			g.lastStartTime = 0
+			g.stopExec(ev.Ts)
 			g.blockGCTime = ev.Ts
+			g.blockEv = ev
+		case EvGoWaiting:
+			// Blocked since before the trace started. Only Go 1.22
+			// and later traces record why.
+			g := gs[ev.G]
+			if g.PC == 0 && len(ev.Stk) > 0 {
+				// It may not run again to be named by its start.
+				f := ev.Stk[len(ev.Stk)-1]
+				g.PC, g.Name = f.PC, f.Fn
+			}
+			g.blockSchedTime = 0
+			g.blockEv = ev
+			switch byte(ev.Args[1]) {
+			case EvGoBlockSend, EvGoBlockRecv, EvGoBlockSelect,
+				EvGoBlockSync, EvGoBlockCond:
+				g.blockSyncTime = ev.Ts
+			case EvGoBlockNet:
+				g.blockNetTime = ev.Ts
+			case EvGoBlockGC:
+				g.blockGCTime = ev.Ts
+			}
 		case EvGoUnblock:
 			g := gs[ev.Args[0]]
 			if g.blockNetTime != 0 {
-				g.IOTime.addTime(ev.Ts - g.blockNetTime)
+				g.addTime(&g.IOTime, g.blockNetTime, ev.Ts)
 				g.blockNetTime = 0
 			}
 			if g.blockSyncTime != 0 {
-				g.BlockTime.addTime(ev.Ts - g.blockSyncTime)
+				g.addTime(&g.BlockTime, g.blockSyncTime, ev.Ts)
 				g.blockSyncTime = 0
 			}
+			if g.blockGCTime != 0 {
+				g.addTime(&g.MarkAssistTime, g.blockGCTime, ev.Ts)
+				g.blockGCTime = 0
+			}
 			g.blockSchedTime = ev.Ts
+			g.blockEv = nil
 		case EvGoSysBlock:
 			g := gs[ev.G]
-			g.ExecTime.addTime(ev.Ts - g.lastStartTime)
-			g.lastStartTime = 0
+			g.stopExec(ev.Ts)
 			g.blockSyscallTime = ev.Ts
+			g.blockEv = ev
 		case EvGoSysExit:
 			g := gs[ev.G]
 			if g.blockSyscallTime != 0 {
-				g.SyscallTime.addTime(ev.Ts - g.blockSyscallTime)
+				g.addTime(&g.SyscallTime, g.blockSyscallTime, ev.Ts)
 				g.blockSyscallTime = 0
 			}
 			g.blockSchedTime = ev.Ts
+			g.blockEv = nil
 		case EvGCSweepStart:
 			g := gs[ev.G]
 			if g != nil {
@@ -309,9 +530,30 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 		case EvGCSweepDone:
 			g := gs[ev.G]
 			if g != nil && g.blockSweepTime != 0 {
-				g.SweepTime.addTime(ev.Ts - g.blockSweepTime)
+				g.addTime(&g.SweepTime, g.blockSweepTime, ev.Ts)
 				g.blockSweepTime = 0
 			}
+		case EvGCMarkAssistStart:
+			g := gs[ev.G]
+			if g != nil && !g.inMarkAssist {
+				g.inMarkAssist = true
+				if g.lastStartTime != 0 {
+					g.addTime(&g.ExecTime, g.lastStartTime, ev.Ts)
+					g.lastStartTime = 0
+				}
+				g.markAssistTime = ev.Ts
+			}
+		case EvGCMarkAssistDone:
+			// The assist may have started before the trace.
+			g := gs[ev.G]
+			if g != nil && g.inMarkAssist {
+				g.inMarkAssist = false
+				if g.markAssistTime != 0 {
+					g.addTime(&g.MarkAssistTime, g.markAssistTime, ev.Ts)
+					g.markAssistTime = 0
+					g.lastStartTime = ev.Ts
+				}
+			}
 		case EvGCStart:
 			gcStartTime = ev.Ts
 		case EvGCDone:
@@ -320,9 +562,9 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 					continue
 				}
 				if gcStartTime < g.CreationTime {
-					g.GCTime.addTime(ev.Ts - g.CreationTime)
+					g.addTime(&g.GCTime, g.CreationTime, ev.Ts)
 				} else {
-					g.GCTime.addTime(ev.Ts - gcStartTime)
+					g.addTime(&g.GCTime, gcStartTime, ev.Ts)
 				}
 			}
 			gcStartTime = 0 // indicates gc is inactive.
@@ -331,33 +573,46 @@ func GoroutineStats(events []*Event) map[uint64]*GDesc {
 			switch mode := ev.Args[1]; mode {
 			case 0: // region start
 				g.activeRegions = append(g.activeRegions, &UserRegionDesc{
-					Name:           ev.SArgs[0],
-					TaskID:         ev.Args[0],
-					Start:          ev,
-					GExecutionStat: g.snapshotStat(lastTs, gcStartTime),
+					Name:   ev.SArgs[0],
+					TaskID: ev.Args[0],
+					Start:  ev,
+					from:   ev.Ts,
 				})
 			case 1: // region end
 				var sd *UserRegionDesc
 				if regionStk := g.activeRegions; len(regionStk) > 0 {
 					n := len(regionStk)
 					sd = regionStk[n-1]
+					sd.GExecutionStat = g.regionStat(sd, lastTs, gcStartTime)
 					regionStk = regionStk[:n-1] // pop
 					g.activeRegions = regionStk
 				} else {
+					// Started before the trace: the region is as
+					// long as the goroutine so far.
 					sd = &UserRegionDesc{
-						Name:   ev.SArgs[0],
-						TaskID: ev.Args[0],
+						Name:           ev.SArgs[0],
+						TaskID:         ev.Args[0],
+						GExecutionStat: g.snapshotStat(lastTs, gcStartTime),
 					}
 				}
-				sd.GExecutionStat = g.snapshotStat(lastTs, gcStartTime).sub(sd.GExecutionStat)
 				sd.End = ev
 				g.Regions = append(g.Regions, sd)
 			}
 		}
 	}
+	b.lastTs, b.gcStartTime = lastTs, gcStartTime
+}
 
-	for _, g := range gs {
-		g.finalize(lastTs, gcStartTime, nil)
+// Finish returns the statistics of the events added, of the goroutines
+// that existed during the window of the builder.
+func (b *GoroutineStatsBuilder) Finish() map[uint64]*GDesc {
+	gs := b.gs
+	for id, g := range gs {
+		if g.CreationTime > b.to || g.EndTime != 0 && g.EndTime < b.from {
+			delete(gs, id)
+			continue
+		}
+		g.finalize(b.lastTs, b.gcStartTime, nil)
 
 		// sort based on region start time
 		sort.Slice(g.Regions, func(i, j int) bool {
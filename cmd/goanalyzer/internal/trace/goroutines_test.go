@@ -0,0 +1,184 @@
+package trace
+
+import "testing"
+
+func TestGoroutineStatsMarkAssist(t *testing.T) {
+	// Goroutine 1 runs, assists, blocks waiting for assist work, runs the
+	// rest of the assist once unblocked, and runs again until it ends.
+	events := []*Event{
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{1}},
+		{Ts: 10, Type: EvGoStart, G: 1},
+		{Ts: 20, Type: EvGCMarkAssistStart, G: 1},
+		{Ts: 30, Type: EvGoBlockGC, G: 1},
+		{Ts: 50, Type: EvGoUnblock, Args: [3]uint64{1}},
+		{Ts: 60, Type: EvGoStart, G: 1},
+		{Ts: 70, Type: EvGCMarkAssistDone, G: 1},
+		{Ts: 100, Type: EvGoEnd, G: 1},
+	}
+	g := GoroutineStats(events)[1]
+	for _, tc := range []struct {
+		name        string
+		stat        GExecutionStatEntry
+		count, want int64
+	}{
+		{"ExecTime", g.ExecTime, 2, 40},
+		{"MarkAssistTime", g.MarkAssistTime, 3, 40},
+		{"SchedWaitTime", g.SchedWaitTime, 2, 15},
+		{"BlockTime", g.BlockTime, 0, 0},
+	} {
+		if tc.stat.Count != tc.count || tc.stat.Total != tc.want {
+			t.Errorf("%s: %d intervals totaling %d, want %d totaling %d", tc.name, tc.stat.Count, tc.stat.Total, tc.count, tc.want)
+		}
+	}
+	if g.MarkAssistTime.Min != 10 || g.MarkAssistTime.Max != 20 {
+		t.Errorf("MarkAssistTime min %d, max %d, want 10 and 20", g.MarkAssistTime.Min, g.MarkAssistTime.Max)
+	}
+}
+
+func TestGoroutineStatsWaiting(t *testing.T) {
+	// Goroutines 1 and 2 are blocked on channels when tracing starts.
+	// Goroutine 1 is unblocked and ends, goroutine 2 stays blocked.
+	stk := []*Frame{{PC: 1, Fn: "runtime.chanrecv"}, {PC: 2, Fn: "main.worker"}}
+	waiting := &Event{Ts: 5, Type: EvGoWaiting, G: 2, Args: [3]uint64{2, EvGoBlockRecv}, Stk: stk}
+	events := []*Event{
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{1}},
+		{Ts: 5, Type: EvGoWaiting, G: 1, Args: [3]uint64{1, EvGoBlockSend}, Stk: stk},
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{2}},
+		waiting,
+		{Ts: 30, Type: EvGoUnblock, Args: [3]uint64{1}},
+		{Ts: 40, Type: EvGoStart, G: 1},
+		{Ts: 50, Type: EvGoEnd, G: 1},
+	}
+	gs := GoroutineStats(events)
+	if g := gs[1]; g.BlockTime.Total != 25 || g.SchedWaitTime.Total != 10 {
+		t.Errorf("goroutine 1 blocked for %d and waiting to run for %d, want 25 and 10", g.BlockTime.Total, g.SchedWaitTime.Total)
+	}
+	if g := gs[2]; g.EndBlock != waiting || g.Name != "main.worker" {
+		t.Errorf("goroutine 2 %q blocked at %v, want main.worker blocked at %v", g.Name, g.EndBlock, waiting)
+	}
+}
+
+func TestGoroutineStatsWindow(t *testing.T) {
+	// In the window [20, 90], goroutine 1 runs for 40, is blocked for 20
+	// and waits for 10 to run again. Goroutine 2 ended before the window
+	// and goroutine 3 was created after it.
+	events := []*Event{
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{1}},
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{2}},
+		{Ts: 6, Type: EvGoStart, G: 2},
+		{Ts: 8, Type: EvGoEnd, G: 2},
+		{Ts: 10, Type: EvGoStart, G: 1},
+		{Ts: 40, Type: EvGoBlockSend, G: 1},
+		{Ts: 60, Type: EvGoUnblock, Args: [3]uint64{1}},
+		{Ts: 70, Type: EvGoStart, G: 1},
+		{Ts: 95, Type: EvGoCreate, G: 1, Args: [3]uint64{3}},
+		{Ts: 100, Type: EvGoEnd, G: 1},
+	}
+	gs := GoroutineStatsWindow(events, 20, 90)
+	if len(gs) != 1 || gs[1] == nil {
+		t.Fatalf("goroutines in the window: %v, want only 1", gs)
+	}
+	g := gs[1]
+	for _, tc := range []struct {
+		name        string
+		stat        GExecutionStatEntry
+		count, want int64
+	}{
+		{"ExecTime", g.ExecTime, 2, 40},
+		{"BlockTime", g.BlockTime, 1, 20},
+		{"SchedWaitTime", g.SchedWaitTime, 1, 10},
+		{"TotalTime", g.TotalTime, 1, 70},
+	} {
+		if tc.stat.Count != tc.count || tc.stat.Total != tc.want {
+			t.Errorf("%s: %d intervals totaling %d, want %d totaling %d", tc.name, tc.stat.Count, tc.stat.Total, tc.count, tc.want)
+		}
+	}
+	// Both runs are cut to 20 by the window.
+	if e := g.ExecTime; e.Min != 20 || e.Max != 20 || e.Percentile(50) != 20 || e.Percentile(99) != 20 {
+		t.Errorf("ExecTime min %d, max %d, p50 %d, p99 %d, want 20", e.Min, e.Max, e.Percentile(50), e.Percentile(99))
+	}
+	if g.StartTime != 10 || g.EndTime != 100 {
+		t.Errorf("goroutine from %d to %d, want the whole goroutine from 10 to 100", g.StartTime, g.EndTime)
+	}
+
+	// The whole trace is the window of GoroutineStats.
+	whole := GoroutineStatsWindow(events, 0, 100)
+	for id, g := range GoroutineStats(events) {
+		if w := whole[id]; w == nil || w.ExecTime.Total != g.ExecTime.Total || w.TotalTime.Total != g.TotalTime.Total {
+			t.Errorf("goroutine %d: window of the whole trace differs from GoroutineStats", id)
+		}
+	}
+}
+
+func TestGoroutineStatsWindowRuns(t *testing.T) {
+	// Goroutine 1 runs twice for 10ns in the window [100, 200].
+	events := []*Event{
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{1}},
+		{Ts: 10, Type: EvGoStart, G: 1},
+		{Ts: 20, Type: EvGoSched, G: 1},
+		{Ts: 110, Type: EvGoStart, G: 1},
+		{Ts: 120, Type: EvGoSched, G: 1},
+		{Ts: 150, Type: EvGoStart, G: 1},
+		{Ts: 160, Type: EvGoSched, G: 1},
+		{Ts: 300, Type: EvGoStart, G: 1},
+		{Ts: 310, Type: EvGoEnd, G: 1},
+	}
+	e := GoroutineStatsWindow(events, 100, 200)[1].ExecTime
+	if e.Count != 2 || e.Total != 20 {
+		t.Errorf("%d runs totaling %d, want 2 totaling 20", e.Count, e.Total)
+	}
+	if avg := e.Total / e.Count; avg != 10 || e.Min != 10 || e.Max != 10 {
+		t.Errorf("average %d, min %d, max %d, want 10", avg, e.Min, e.Max)
+	}
+	for _, p := range []float64{50, 99} {
+		if got := e.Percentile(p); got != 10 {
+			t.Errorf("p%v = %d, want 10", p, got)
+		}
+	}
+}
+
+func TestGoroutineStatsRegions(t *testing.T) {
+	// Goroutine 1 runs a region from 50 to 100, in which it runs for 10,
+	// blocks, and runs for 20, then starts a region it does not end.
+	// The run in progress when the first region starts counts for the
+	// region only since then.
+	events := []*Event{
+		{Ts: 5, Type: EvGoCreate, Args: [3]uint64{1}},
+		{Ts: 10, Type: EvGoStart, G: 1},
+		{Ts: 20, Type: EvGoBlock, G: 1},
+		{Ts: 30, Type: EvGoUnblock, Args: [3]uint64{1}},
+		{Ts: 40, Type: EvGoStart, G: 1},
+		{Ts: 50, Type: EvUserRegion, G: 1, Args: [3]uint64{0, 0}, SArgs: []string{"r"}},
+		{Ts: 60, Type: EvGoBlockRecv, G: 1},
+		{Ts: 65, Type: EvGoUnblock, Args: [3]uint64{1}},
+		{Ts: 80, Type: EvGoStart, G: 1},
+		{Ts: 100, Type: EvUserRegion, G: 1, Args: [3]uint64{0, 1}, SArgs: []string{"r"}},
+		{Ts: 105, Type: EvUserRegion, G: 1, Args: [3]uint64{0, 0}, SArgs: []string{"open"}},
+		{Ts: 110, Type: EvGoEnd, G: 1},
+	}
+	g := GoroutineStats(events)[1]
+	if g.ExecTime.Count != 3 || g.ExecTime.Min != 10 || g.ExecTime.Max != 30 {
+		t.Errorf("goroutine ran %d times, min %d, max %d, want 3, 10 and 30", g.ExecTime.Count, g.ExecTime.Min, g.ExecTime.Max)
+	}
+	if len(g.Regions) != 2 {
+		t.Fatalf("%d regions, want 2", len(g.Regions))
+	}
+	r := g.Regions[0]
+	for _, tc := range []struct {
+		name                   string
+		stat                   GExecutionStatEntry
+		count, total, min, max int64
+	}{
+		{"ExecTime", r.ExecTime, 2, 30, 10, 20},
+		{"BlockTime", r.BlockTime, 1, 5, 5, 5},
+		{"SchedWaitTime", r.SchedWaitTime, 1, 15, 15, 15},
+	} {
+		if s := tc.stat; s.Count != tc.count || s.Total != tc.total || s.Min != tc.min || s.Max != tc.max {
+			t.Errorf("region %s: %d intervals totaling %d, min %d, max %d, want %d totaling %d, min %d, max %d",
+				tc.name, s.Count, s.Total, s.Min, s.Max, tc.count, tc.total, tc.min, tc.max)
+		}
+	}
+	if r := g.Regions[1]; r.Name != "open" || r.ExecTime.Count != 1 || r.ExecTime.Total != 5 {
+		t.Errorf("region %q ran %d times for %d, want \"open\" once for 5", r.Name, r.ExecTime.Count, r.ExecTime.Total)
+	}
+}
@@ -0,0 +1,143 @@
+package trace
+
+import (
+	"math"
+	"sort"
+)
+
+// histBucketsPerDecade is the number of histogram buckets for every
+// power of 10 nanoseconds. Each bucket spans a factor of ~1.26, so a
+// percentile estimate is within ~13% of the true value.
+const histBucketsPerDecade = 10
+
+// Histogram is a log-scale histogram of durations in nanoseconds.
+// Bucket boundaries are the same for every Histogram, so histograms
+// built from different goroutines can be merged by summing buckets.
+type Histogram struct {
+	// Buckets[i] is the number of durations d with
+	// BucketMin(i) <= d < BucketMin(i+1). The slice is only as
+	// long as the highest non-empty bucket.
+	Buckets []int64
+}
+
+// histBounds[i] is the lower bound of bucket i in nanoseconds, the
+// smallest integer not below 10^(i/histBucketsPerDecade). The bounds
+// of the powers of 10 are computed with integers, so that 10^k falls
+// in bucket k*histBucketsPerDecade.
+var histBounds = func() []int64 {
+	var bounds []int64
+	for pow := int64(1); ; pow *= 10 {
+		bounds = append(bounds, pow)
+		for i := 1; i < histBucketsPerDecade; i++ {
+			v := math.Ceil(float64(pow) * math.Pow(10, float64(i)/histBucketsPerDecade))
+			if v >= math.MaxInt64 {
+				return bounds
+			}
+			bounds = append(bounds, int64(v))
+		}
+		if pow > math.MaxInt64/10 {
+			return bounds
+		}
+	}
+}()
+
+func histBucket(d int64) int {
+	if d <= 1 {
+		return 0
+	}
+	return sort.Search(len(histBounds), func(i int) bool { return histBounds[i] > d }) - 1
+}
+
+// BucketMin returns the lower bound of the bucket in nanoseconds.
+func (h *Histogram) BucketMin(bucket int) int64 {
+	if bucket >= len(histBounds) {
+		return math.MaxInt64
+	}
+	return histBounds[bucket]
+}
+
+// bucketMid returns the geometric midpoint of the bucket in nanoseconds.
+func bucketMid(bucket int) int64 {
+	return int64(math.Pow(10, (float64(bucket)+0.5)/histBucketsPerDecade))
+}
+
+// Add records the duration d in nanoseconds.
+func (h *Histogram) Add(d int64) {
+	bucket := histBucket(d)
+	if len(h.Buckets) <= bucket {
+		h.grow(bucket + 1)
+	}
+	h.Buckets[bucket]++
+}
+
+// grow reallocates the buckets with at least n entries. Copies of a
+// Histogram share its buckets, which add updates in place once they are
+// long enough: a copy that must not observe later updates, like a
+// snapshot, has to be made with clone.
+func (h *Histogram) grow(n int) {
+	if n < len(h.Buckets) {
+		n = len(h.Buckets)
+	}
+	buckets := make([]int64, n)
+	copy(buckets, h.Buckets)
+	h.Buckets = buckets
+}
+
+// merge adds the counts of h2 to h.
+func (h *Histogram) merge(h2 Histogram) {
+	if len(h2.Buckets) == 0 {
+		return
+	}
+	h.grow(len(h2.Buckets))
+	for i, n := range h2.Buckets {
+		h.Buckets[i] += n
+	}
+}
+
+// clone returns a copy of h that does not share storage with h.
+func (h Histogram) clone() Histogram {
+	if h.Buckets == nil {
+		return h
+	}
+	return Histogram{Buckets: append([]int64(nil), h.Buckets...)}
+}
+
+// Span returns the first and last non-empty buckets, or -1 and -1 if
+// the histogram is empty.
+func (h *Histogram) Span() (first, last int) {
+	first, last = -1, -1
+	for i, n := range h.Buckets {
+		if n > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	return first, last
+}
+
+// quantile returns the approximate q-quantile (0 <= q <= 1) of the
+// recorded durations, or 0 if the histogram is empty. The result is
+// the geometric midpoint of the bucket containing the quantile.
+func (h *Histogram) quantile(q float64) int64 {
+	var total int64
+	for _, n := range h.Buckets {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var sum int64
+	for i, n := range h.Buckets {
+		sum += n
+		if sum >= rank {
+			return bucketMid(i)
+		}
+	}
+	return h.BucketMin(len(h.Buckets))
+}
@@ -0,0 +1,103 @@
+package trace
+
+import (
+	"testing"
+)
+
+// near returns true if got is within the relative error of a single
+// histogram bucket from want.
+func near(got, want int64) bool {
+	return float64(got) >= float64(want)*0.85 && float64(got) <= float64(want)*1.15
+}
+
+func TestPercentile(t *testing.T) {
+	var s GExecutionStatEntry
+	for i := int64(1); i <= 1000; i++ {
+		s.addTime(i * 1000)
+	}
+	for _, test := range []struct {
+		p    float64
+		want int64
+	}{
+		{50, 500000},
+		{90, 900000},
+		{99, 990000},
+		{99.9, 999000},
+		{100, 1000000},
+	} {
+		if got := s.Percentile(test.p); !near(got, test.want) {
+			t.Errorf("p%v = %d, want ~%d", test.p, got, test.want)
+		}
+	}
+	if got := s.Percentile(0); got != s.Min {
+		t.Errorf("p0 = %d, want min %d", got, s.Min)
+	}
+	var empty GExecutionStatEntry
+	if got := empty.Percentile(99); got != 0 {
+		t.Errorf("p99 of empty entry = %d, want 0", got)
+	}
+}
+
+func TestPercentileMerge(t *testing.T) {
+	// A group with many fast goroutines and a single slow one:
+	// the tail must come from the slow goroutine.
+	var group GExecutionStatEntry
+	for i := 0; i < 99; i++ {
+		var g GExecutionStatEntry
+		g.addTime(1000)
+		group.AddStat(g)
+	}
+	var slow GExecutionStatEntry
+	slow.addTime(1e9)
+	group.AddStat(slow)
+
+	if group.Count != 100 {
+		t.Fatalf("count = %d, want 100", group.Count)
+	}
+	if got := group.Percentile(50); !near(got, 1000) {
+		t.Errorf("p50 = %d, want ~1000", got)
+	}
+	if got := group.Percentile(99.9); !near(got, 1e9) {
+		t.Errorf("p99.9 = %d, want ~1e9", got)
+	}
+	if len(slow.Hist.Buckets) == len(group.Hist.Buckets) && &slow.Hist.Buckets[0] == &group.Hist.Buckets[0] {
+		t.Errorf("merged histogram shares storage with its input")
+	}
+}
+
+func TestHistBucket(t *testing.T) {
+	for k, pow := 0, int64(1); k <= 18; k, pow = k+1, pow*10 {
+		if got := histBucket(pow); got != k*histBucketsPerDecade {
+			t.Errorf("histBucket(%d) = %d, want %d", pow, got, k*histBucketsPerDecade)
+		}
+		if pow > 1 {
+			if got := histBucket(pow - 1); got != k*histBucketsPerDecade-1 {
+				t.Errorf("histBucket(%d) = %d, want %d", pow-1, got, k*histBucketsPerDecade-1)
+			}
+		}
+	}
+	// Below 10ns, buckets narrower than 1ns are empty.
+	var h Histogram
+	for i := range histBounds {
+		if b := h.BucketMin(i); b != h.BucketMin(i+1) && histBucket(b) != i {
+			t.Errorf("bound %d of bucket %d is in bucket %d", b, i, histBucket(b))
+		}
+	}
+}
+
+func TestStddev(t *testing.T) {
+	var s GExecutionStatEntry
+	for i := 0; i < 50; i++ {
+		s.addTime(1000)
+		s.addTime(3000)
+	}
+	// Exact sample standard deviation is ~1005.
+	if got := s.Stddev(); got < 800 || got > 1200 {
+		t.Errorf("stddev = %v, want ~1005", got)
+	}
+	var one GExecutionStatEntry
+	one.addTime(1000)
+	if got := one.Stddev(); got != 0 {
+		t.Errorf("stddev of a single timing = %v, want 0", got)
+	}
+}
@@ -4,7 +4,10 @@
 # license that can be found in the LICENSE file.
 
 # mkcanned.bash creates canned traces for the trace test suite using
-# the current Go version.
+# the current Go version, which must be Go 1.21 or older: later traces
+# are read by golang.org/x/exp/trace and are not canned here. The label
+# names the format, such as 1_21. The traces of testdata come from
+# src/internal/trace/internal/tracev1/testdata of the Go repository.
 
 set -e
 
@@ -13,8 +16,18 @@ if [ $# != 1 ]; then
     exit 1
 fi
 
+minor=$(go version | sed -E 's/.* go1\.([0-9]+).*/\1/')
+if [ "$minor" -ge 22 ]; then
+    echo "$0: $(go version) writes the Go 1.22 format, use Go 1.21 or older" >&2
+    exit 1
+fi
+
+cd "$(dirname "$0")"
+goroot=$(go env GOROOT)
+
 go test -run ClientServerParallel4 -trace "testdata/http_$1_good" net/http
-go test -run 'TraceStress$|TraceStressStartStop$|TestUserTaskSpan$' runtime/trace -savetraces
-mv ../../runtime/trace/TestTraceStress.trace "testdata/stress_$1_good"
-mv ../../runtime/trace/TestTraceStressStartStop.trace "testdata/stress_start_stop_$1_good"
-mv ../../runtime/trace/TestUserTaskSpan.trace "testdata/user_task_span_$1_good"
+rm -f http.test
+go test -run 'TraceStress$|TraceStressStartStop$|TestUserTaskRegion$' runtime/trace -savetraces
+mv "$goroot/src/runtime/trace/TestTraceStress.trace" "testdata/stress_$1_good"
+mv "$goroot/src/runtime/trace/TestTraceStressStartStop.trace" "testdata/stress_start_stop_$1_good"
+mv "$goroot/src/runtime/trace/TestUserTaskRegion.trace" "testdata/user_task_region_$1_good"
@@ -52,6 +52,10 @@ const (
 // incorrect (condition observed on some machines).
 func order1007(m map[int][]*Event) (events []*Event, err error) {
 	pending := 0
+	// CPU profile samples are recorded in the order the signal handler
+	// was able to write them, so re-sort them by the timestamp taken in
+	// the signal handler. They have no ordering dependencies otherwise.
+	sort.Stable(eventList(m[ProfileP]))
 	var batches []*eventBatch
 	for _, v := range m {
 		pending += len(v)
@@ -75,6 +75,7 @@ const (
 	NetpollP // depicts network unblocks
 	SyscallP // depicts returns from syscalls
 	GCP      // depicts GC state
+	ProfileP // depicts recording of CPU profile samples
 )
 
 // ParseResult is the result of Parse.
@@ -100,7 +101,14 @@ func Parse(r io.Reader, bin string) (ParseResult, error) {
 // parse parses, post-processes and verifies the trace. It returns the
 // trace version and the list of events.
 func parse(r io.Reader, bin string) (int, ParseResult, error) {
-	ver, rawEvents, strings, err := readTrace(r)
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(16); err == nil {
+		if ver, err := parseHeader(header); err == nil && ver >= 1022 {
+			res, err := parseGo122(br)
+			return ver, res, err
+		}
+	}
+	ver, rawEvents, strings, err := readTrace(br)
 	if err != nil {
 		return 0, ParseResult{}, err
 	}
@@ -150,9 +158,11 @@ func readTrace(r io.Reader) (ver int, events []rawEvent, strings map[uint64]stri
 		return
 	}
 	switch ver {
-	case 1005, 1007, 1008, 1009, 1010, 1011:
+	case 1005, 1007, 1008, 1009, 1010, 1011, 1019, 1021:
 		// Note: When adding a new version, add canned traces
 		// from the old version to the test suite using mkcanned.bash.
+		// Go 1.12 through 1.18 emit the 1.11 format and Go 1.20
+		// emits the 1.19 format.
 		break
 	default:
 		err = fmt.Errorf("unsupported trace file version %v.%v (update Go toolchain) %v", ver/1000, ver%1000, ver)
@@ -287,6 +297,16 @@ func readStr(r io.Reader, off0 int) (s string, off int, err error) {
 	return string(buf), off + n, nil
 }
 
+// ReadVersion reads the header of a trace and returns the version of
+// Go that produced it, such as 1022 for Go 1.22.
+func ReadVersion(r io.Reader) (int, error) {
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	return parseHeader(header[:])
+}
+
 // parseHeader parses trace header of the form "go 1.7 trace\x00\x00\x00\x00"
 // and returns parsed version as 1007.
 func parseHeader(buf []byte) (int, error) {
@@ -421,14 +441,22 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 				}
 			case EvGCSTWStart:
 				e.G = 0
-				switch e.Args[0] {
-				case 0:
-					e.SArgs = []string{"mark termination"}
-				case 1:
-					e.SArgs = []string{"sweep termination"}
-				default:
-					err = fmt.Errorf("unknown STW kind %d", e.Args[0])
-					return
+				if ver < 1021 {
+					// Named as from 1.21 on, so that the kinds are the
+					// same in the traces of every version.
+					switch e.Args[0] {
+					case 0:
+						e.SArgs = []string{stwReasonStringsGo121[1]}
+					case 1:
+						e.SArgs = []string{stwReasonStringsGo121[2]}
+					default:
+						err = fmt.Errorf("unknown STW kind %d", e.Args[0])
+						return
+					}
+				} else if kind := e.Args[0]; kind < uint64(len(stwReasonStringsGo121)) {
+					e.SArgs = []string{stwReasonStringsGo121[kind]}
+				} else {
+					e.SArgs = []string{"unknown"}
 				}
 			case EvGCStart, EvGCDone, EvGCSTWDone:
 				e.G = 0
@@ -448,8 +476,22 @@ func parseEvents(ver int, rawEvents []rawEvent, strings map[uint64]string) (even
 			case EvUserLog:
 				// e.Args 0: taskID, 1:keyID, 2: stackID
 				e.SArgs = []string{strings[e.Args[1]], raw.sargs[0]}
+			case EvCPUSample:
+				// e.Args 0: real timestamp, 1: real P id (-1 when absent), 2: goroutine id
+				e.Ts = int64(e.Args[0])
+				e.P = int(e.Args[1])
+				e.G = e.Args[2]
+				e.Args[0] = 0
+			}
+			if raw.typ == EvCPUSample {
+				// CPU profile samples are written to the trace by a
+				// separate goroutine some time after they are taken,
+				// so keep them in their own batch until all batches are
+				// merged in timestamp order.
+				batches[ProfileP] = append(batches[ProfileP], e)
+			} else {
+				batches[lastP] = append(batches[lastP], e)
 			}
-			batches[lastP] = append(batches[lastP], e)
 		}
 	}
 	if len(batches) == 0 {
@@ -603,6 +645,11 @@ func postProcessTrace(ver int, events []*Event) error {
 	}
 
 	for _, ev := range events {
+		if ev.Type == EvCPUSample {
+			// Samples are taken asynchronously and do not affect
+			// goroutine or P state.
+			continue
+		}
 		g := gs[ev.G]
 		p := ps[ev.P]
 
@@ -1040,7 +1087,7 @@ const (
 	EvGoSysCall         = 28 // syscall enter [timestamp, stack]
 	EvGoSysExit         = 29 // syscall exit [timestamp, goroutine id, seq, real timestamp]
 	EvGoSysBlock        = 30 // syscall blocks [timestamp]
-	EvGoWaiting         = 31 // denotes that goroutine is blocked when tracing starts [timestamp, goroutine id]
+	EvGoWaiting         = 31 // denotes that goroutine is blocked when tracing starts [timestamp, goroutine id, blocking event type (Go 1.22+)]
 	EvGoInSyscall       = 32 // denotes that goroutine is in syscall when tracing starts [timestamp, goroutine id]
 	EvHeapAlloc         = 33 // memstats.heap_live change [timestamp, heap_alloc]
 	EvNextGC            = 34 // memstats.next_gc change [timestamp, next_gc]
@@ -1058,7 +1105,8 @@ const (
 	EvUserTaskEnd       = 46 // end of task [timestamp, internal task id, stack]
 	EvUserRegion        = 47 // trace.WithRegion [timestamp, internal task id, mode(0:start, 1:end), stack, name string]
 	EvUserLog           = 48 // trace.Log [timestamp, internal id, key string id, stack, value string]
-	EvCount             = 49
+	EvCPUSample         = 49 // CPU profiling sample [timestamp, real timestamp, real P id (-1 when absent), goroutine id, stack]
+	EvCount             = 50
 )
 
 var EventDescriptions = [EvCount]struct {
@@ -1117,4 +1165,29 @@ var EventDescriptions = [EvCount]struct {
 	EvUserTaskEnd:       {"UserTaskEnd", 1011, true, []string{"taskid"}, nil},
 	EvUserRegion:        {"UserRegion", 1011, true, []string{"taskid", "mode", "typeid"}, []string{"name"}},
 	EvUserLog:           {"UserLog", 1011, true, []string{"id", "keyid"}, []string{"category", "message"}},
+	EvCPUSample:         {"CPUSample", 1019, true, []string{"ts", "p", "g"}, nil},
+}
+
+// stwReasonStringsGo121 are the STW reasons recorded in the kind
+// argument of EvGCSTWStart by Go 1.21, and the names of the STW kinds
+// of every version. Earlier versions only record mark termination (0)
+// and sweep termination (1).
+var stwReasonStringsGo121 = [...]string{
+	"unknown",
+	"GC mark termination",
+	"GC sweep termination",
+	"write heap dump",
+	"goroutine profile",
+	"goroutine profile cleanup",
+	"all goroutines stack trace",
+	"read mem stats",
+	"AllThreadsSyscall",
+	"GOMAXPROCS",
+	"start trace",
+	"stop trace",
+	"CountPagesInUse (test)",
+	"ReadMetricsSlow (test)",
+	"ReadMemStatsSlow (test)",
+	"PageCachePagesLeaked (test)",
+	"ResetDebugLog (test)",
 }
@@ -0,0 +1,641 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// parseGo122 parses traces in the generational format written by Go 1.22
+// and later. That format has explicit goroutine and P state transitions
+// instead of per-P batches ordered by sequence numbers, so decoding and
+// validation is left to golang.org/x/exp/trace and its events are
+// translated to the events of the older formats. The rest of the package
+// then works unchanged on the result.
+func parseGo122(r io.Reader) (ParseResult, error) {
+	s, err := newGo122Stream(r)
+	if err != nil {
+		return ParseResult{}, err
+	}
+	var events []*Event
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return ParseResult{}, err
+		}
+		events = append(events, chunk...)
+	}
+	if len(events) == 0 {
+		return ParseResult{}, fmt.Errorf("trace is empty")
+	}
+	return ParseResult{Events: events, Stacks: s.Stacks()}, nil
+}
+
+// go122BlockTypes maps the reasons recorded for blocked goroutines to the
+// corresponding blocking event. Other reasons become EvGoBlock.
+var go122BlockTypes = map[string]byte{
+	"sleep":                        EvGoSleep,
+	"chan send":                    EvGoBlockSend,
+	"chan receive":                 EvGoBlockRecv,
+	"select":                       EvGoBlockSelect,
+	"sync":                         EvGoBlockSync,
+	"sync.(*Cond).Wait":            EvGoBlockCond,
+	"network":                      EvGoBlockNet,
+	"GC mark assist wait for work": EvGoBlockGC,
+}
+
+// go122BlockFuncs maps the functions goroutines block in to the
+// corresponding blocking event, for the goroutines already blocked
+// when a generation starts, which have no reason.
+var go122BlockFuncs = map[string]byte{
+	"time.Sleep":                  EvGoSleep,
+	"runtime.chansend":            EvGoBlockSend,
+	"runtime.chanrecv":            EvGoBlockRecv,
+	"runtime.selectgo":            EvGoBlockSelect,
+	"runtime.semacquire1":         EvGoBlockSync,
+	"sync.runtime_notifyListWait": EvGoBlockCond,
+	"runtime.netpollblock":        EvGoBlockNet,
+	"runtime.gcParkAssist":        EvGoBlockGC,
+}
+
+// go122Converter translates Go 1.22 trace events. The input is already
+// validated, so unlike postProcessTrace it only tracks enough state to
+// link events, and never fails.
+type go122Converter struct {
+	events []*Event // converted since the last Stream.Next
+	n      int      // events converted
+	lastTs int64
+	stacks map[uint64][]*Frame
+	// stackIDs caches the stack ID of every stack seen in the current
+	// generation. stackKeys maps PCs to stack IDs across generations.
+	stackIDs  map[exptrace.Stack]uint64
+	stackKeys map[string]uint64
+	minTs     exptrace.Time
+
+	gs    map[uint64]*go122G
+	ps    map[int]*go122P
+	tasks map[uint64]*Event // task id to task creation events
+	evGC  *Event
+	evSTW *Event
+	gcSeq uint64
+	// gomaxprocs is the last GOMAXPROCS emitted, 0 before the first.
+	gomaxprocs uint64
+}
+
+type go122G struct {
+	ev           *Event // last event that awaits the next GoStart or GoUnblock
+	evStart      *Event
+	evCreate     *Event
+	evMarkAssist *Event
+	regions      []*Event // stack of active regions
+	// anon is the creation event of a goroutine that was running when
+	// tracing started, until a stack reveals its start function.
+	// anonStart is its first GoStart.
+	anon       *Event
+	anonStart  *Event
+	syscallP   int  // P held when entering the current syscall
+	sysBlocked bool // the current syscall lost its P
+}
+
+type go122P struct {
+	running  bool
+	syscallG uint64 // goroutine in a syscall holding the P, or 0
+	evSweep  *Event
+}
+
+// go122Task returns the task ID in the form of the older formats,
+// where 0 stands for no task.
+func go122Task(id exptrace.TaskID) uint64 {
+	if id == exptrace.NoTask {
+		return 0
+	}
+	return uint64(id)
+}
+
+func (c *go122Converter) g(id uint64) *go122G {
+	g := c.gs[id]
+	if g == nil {
+		g = &go122G{syscallP: -1}
+		c.gs[id] = g
+	}
+	return g
+}
+
+func (c *go122Converter) p(id int) *go122P {
+	p := c.ps[id]
+	if p == nil {
+		p = new(go122P)
+		c.ps[id] = p
+	}
+	return p
+}
+
+// proc returns the P of the event, or SyscallP for events that
+// happened on a thread without a P.
+func (c *go122Converter) proc(ev exptrace.Event) int {
+	if p := ev.Proc(); p != exptrace.NoProc {
+		return int(p)
+	}
+	return SyscallP
+}
+
+func (c *go122Converter) goroutine(ev exptrace.Event) uint64 {
+	if g := ev.Goroutine(); g != exptrace.NoGoroutine {
+		return uint64(g)
+	}
+	return 0
+}
+
+func (c *go122Converter) emit(ev exptrace.Event, typ byte, p int, g uint64) *Event {
+	return c.emitAt(int64(ev.Time()-c.minTs), typ, p, g)
+}
+
+func (c *go122Converter) emitAt(ts int64, typ byte, p int, g uint64) *Event {
+	// There are no file offsets to report, so use the event index.
+	e := &Event{Off: c.n, Type: typ, Ts: ts, P: p, G: g}
+	c.events = append(c.events, e)
+	c.n++
+	c.lastTs = ts
+	return e
+}
+
+func (c *go122Converter) stack(s exptrace.Stack) uint64 {
+	if s == exptrace.NoStack {
+		return 0
+	}
+	if id, ok := c.stackIDs[s]; ok {
+		return id
+	}
+	var stk []*Frame
+	for f := range s.Frames() {
+		stk = append(stk, &Frame{PC: f.PC, Fn: f.Func, File: f.File, Line: int(f.Line)})
+	}
+	id := c.internStack(stk)
+	c.stackIDs[s] = id
+	return id
+}
+
+// startStack returns the ID of a stack holding only the outermost frame
+// of s, which is the function the goroutine started with.
+func (c *go122Converter) startStack(s exptrace.Stack) uint64 {
+	var last *Frame
+	for f := range s.Frames() {
+		last = &Frame{PC: f.PC, Fn: f.Func, File: f.File, Line: int(f.Line)}
+	}
+	if last == nil {
+		return 0
+	}
+	return c.internStack([]*Frame{last})
+}
+
+func (c *go122Converter) internStack(stk []*Frame) uint64 {
+	if len(stk) == 0 {
+		return 0
+	}
+	var key strings.Builder
+	for _, f := range stk {
+		fmt.Fprintf(&key, "%x,", f.PC)
+	}
+	if id, ok := c.stackKeys[key.String()]; ok {
+		return id
+	}
+	id := uint64(len(c.stacks) + 1)
+	c.stacks[id] = stk
+	c.stackKeys[key.String()] = id
+	return id
+}
+
+func (c *go122Converter) convert(ev exptrace.Event) {
+	switch ev.Kind() {
+	case exptrace.EventSync:
+		// Stacks are per generation, don't keep the old ones alive.
+		c.stackIDs = make(map[exptrace.Stack]uint64)
+	case exptrace.EventStateTransition:
+		st := ev.StateTransition()
+		switch st.Resource.Kind {
+		case exptrace.ResourceGoroutine:
+			c.goTransition(ev, st)
+		case exptrace.ResourceProc:
+			c.procTransition(ev, st)
+		}
+	case exptrace.EventRangeBegin, exptrace.EventRangeActive, exptrace.EventRangeEnd:
+		c.rangeEvent(ev)
+	case exptrace.EventMetric:
+		m := ev.Metric()
+		var typ byte
+		switch m.Name {
+		case "/memory/classes/heap/objects:bytes":
+			typ = EvHeapAlloc
+		case "/gc/heap/goal:bytes":
+			typ = EvNextGC
+		case "/sched/gomaxprocs:threads":
+			// The metric is sampled at every generation: only its
+			// changes are events of the older formats.
+			if m.Value.Uint64() == c.gomaxprocs {
+				return
+			}
+			c.gomaxprocs = m.Value.Uint64()
+			typ = EvGomaxprocs
+		default:
+			return
+		}
+		e := c.emit(ev, typ, c.proc(ev), c.goroutine(ev))
+		e.Args[0] = m.Value.Uint64()
+	case exptrace.EventLabel:
+		// Labels, such as the kind of a GC worker, follow the GoStart
+		// of the goroutine they apply to.
+		l := ev.Label()
+		if l.Resource.Kind != exptrace.ResourceGoroutine {
+			return
+		}
+		if g := c.gs[uint64(l.Resource.Goroutine())]; g != nil && g.evStart != nil {
+			g.evStart.Type = EvGoStartLabel
+			g.evStart.SArgs = []string{l.Label}
+		}
+	case exptrace.EventTaskBegin:
+		t := ev.Task()
+		e := c.emit(ev, EvUserTaskCreate, c.proc(ev), c.goroutine(ev))
+		e.Args[0] = go122Task(t.ID)
+		e.Args[1] = go122Task(t.Parent)
+		e.StkID = c.stack(ev.Stack())
+		e.SArgs = []string{t.Type}
+		c.tasks[e.Args[0]] = e
+	case exptrace.EventTaskEnd:
+		t := ev.Task()
+		e := c.emit(ev, EvUserTaskEnd, c.proc(ev), c.goroutine(ev))
+		e.Args[0] = go122Task(t.ID)
+		e.StkID = c.stack(ev.Stack())
+		if create := c.tasks[e.Args[0]]; create != nil {
+			create.Link = e
+			delete(c.tasks, e.Args[0])
+		}
+	case exptrace.EventRegionBegin, exptrace.EventRegionEnd:
+		r := ev.Region()
+		gid := c.goroutine(ev)
+		e := c.emit(ev, EvUserRegion, c.proc(ev), gid)
+		e.Args[0] = go122Task(r.Task)
+		e.StkID = c.stack(ev.Stack())
+		e.SArgs = []string{r.Type}
+		g := c.g(gid)
+		if ev.Kind() == exptrace.EventRegionBegin {
+			g.regions = append(g.regions, e) // push
+			return
+		}
+		e.Args[1] = 1
+		if n := len(g.regions); n > 0 {
+			s := g.regions[n-1]
+			if s.Args[0] == e.Args[0] && s.SArgs[0] == e.SArgs[0] {
+				s.Link = e
+				g.regions = g.regions[:n-1]
+			}
+		}
+	case exptrace.EventLog:
+		l := ev.Log()
+		e := c.emit(ev, EvUserLog, c.proc(ev), c.goroutine(ev))
+		e.Args[0] = go122Task(l.Task)
+		e.StkID = c.stack(ev.Stack())
+		e.SArgs = []string{l.Category, l.Message}
+	case exptrace.EventStackSample:
+		p := -1
+		if ev.Proc() != exptrace.NoProc {
+			p = int(ev.Proc())
+		}
+		e := c.emit(ev, EvCPUSample, p, c.goroutine(ev))
+		e.StkID = c.stack(ev.Stack())
+	}
+}
+
+func (c *go122Converter) goTransition(ev exptrace.Event, st exptrace.StateTransition) {
+	from, to := st.Goroutine()
+	if from == to {
+		// The state of a known goroutine restated at a generation boundary.
+		return
+	}
+	id := uint64(st.Resource.Goroutine())
+	g := c.g(id)
+	p := c.proc(ev)
+	if g.anon != nil && st.Stack != exptrace.NoStack {
+		c.name(g, st.Stack)
+	}
+
+	if from == exptrace.GoUndetermined || from == exptrace.GoNotExist {
+		if to == exptrace.GoNotExist {
+			return
+		}
+		// Goroutines that exist when tracing starts are created by g0,
+		// like the older formats do.
+		create := c.emit(ev, EvGoCreate, p, 0)
+		create.Args[0] = id
+		if from == exptrace.GoNotExist {
+			create.G = c.goroutine(ev)
+			create.Args[1] = c.stack(st.Stack)
+			create.StkID = c.stack(ev.Stack())
+		} else if create.Args[1] = c.startStack(st.Stack); create.Args[1] == 0 {
+			g.anon = create
+		}
+		g.ev = create
+		g.evCreate = create
+		switch to {
+		case exptrace.GoRunning:
+			c.start(ev, id, g, p)
+		case exptrace.GoWaiting:
+			e := c.emit(ev, EvGoWaiting, p, id)
+			e.Args[0] = id
+			e.StkID = c.stack(st.Stack)
+			e.Args[1] = uint64(c.blockType(st.Reason, e.StkID))
+			g.ev = e
+		case exptrace.GoSyscall:
+			e := c.emit(ev, EvGoInSyscall, p, id)
+			e.Args[0] = id
+			g.ev = e
+			g.sysBlocked = true
+		}
+		return
+	}
+
+	switch to {
+	case exptrace.GoRunning:
+		if from == exptrace.GoSyscall {
+			if !g.sysBlocked {
+				// A syscall that returned without losing its P.
+				c.endSyscall(id, g)
+				return
+			}
+			c.sysExit(ev, id, g)
+		}
+		c.start(ev, id, g, p)
+	case exptrace.GoRunnable:
+		switch from {
+		case exptrace.GoRunning:
+			typ := byte(EvGoSched)
+			if st.Reason == "preempted" {
+				typ = EvGoPreempt
+			}
+			e := c.emit(ev, typ, p, id)
+			e.StkID = c.stack(st.Stack)
+			c.stop(g, e)
+			g.ev = e
+		case exptrace.GoWaiting:
+			up := p
+			if g.ev != nil && g.ev.Type == EvGoBlockNet {
+				up = NetpollP
+			} else if ev.Proc() == exptrace.NoProc {
+				up = TimerP
+			}
+			e := c.emit(ev, EvGoUnblock, up, c.goroutine(ev))
+			e.Args[0] = id
+			e.StkID = c.stack(ev.Stack())
+			if g.ev != nil {
+				g.ev.Link = e
+			}
+			g.ev = e
+		case exptrace.GoSyscall:
+			if !g.sysBlocked {
+				c.sysBlock(ev, id, g, g.syscallP)
+			}
+			c.sysExit(ev, id, g)
+		}
+	case exptrace.GoWaiting:
+		stk := c.stack(st.Stack)
+		e := c.emit(ev, c.blockType(st.Reason, stk), p, id)
+		e.StkID = stk
+		c.stop(g, e)
+		g.ev = e
+	case exptrace.GoSyscall:
+		e := c.emit(ev, EvGoSysCall, p, id)
+		e.StkID = c.stack(st.Stack)
+		g.ev = e
+		g.syscallP = -1
+		if ev.Proc() != exptrace.NoProc {
+			g.syscallP = p
+			c.p(p).syscallG = id
+		}
+	case exptrace.GoNotExist:
+		e := c.emit(ev, EvGoEnd, p, id)
+		c.stop(g, e)
+		for _, s := range g.regions { // flush all active regions
+			s.Link = e
+		}
+		c.endSyscall(id, g)
+		delete(c.gs, id)
+	}
+}
+
+// blockType returns the blocking event of a goroutine blocked for
+// reason at the stack stk. Without a reason, the innermost blocking
+// function of the stack tells.
+func (c *go122Converter) blockType(reason string, stk uint64) byte {
+	if typ, ok := go122BlockTypes[reason]; ok {
+		return typ
+	}
+	if reason == "" {
+		for _, f := range c.stacks[stk] {
+			if typ, ok := go122BlockFuncs[f.Fn]; ok {
+				return typ
+			}
+		}
+	}
+	return EvGoBlock
+}
+
+func (c *go122Converter) start(ev exptrace.Event, id uint64, g *go122G, p int) {
+	e := c.emit(ev, EvGoStart, p, id)
+	e.Args[0] = id
+	if g.anon != nil && g.anonStart == nil {
+		g.anonStart = e
+	}
+	if g.evCreate != nil {
+		e.StkID = g.evCreate.Args[1]
+		g.evCreate = nil
+	}
+	if g.ev != nil {
+		g.ev.Link = e
+		g.ev = nil
+	}
+	g.evStart = e
+}
+
+// name sets the start stack of an anonymous goroutine from the
+// outermost frame of one of its stacks.
+func (c *go122Converter) name(g *go122G, s exptrace.Stack) {
+	id := c.startStack(s)
+	if id == 0 {
+		return
+	}
+	g.anon.Args[1] = id
+	if g.anonStart != nil {
+		// The GoStart may have been returned by Stream.Next already.
+		g.anonStart.StkID = id
+		g.anonStart.Stk = c.stacks[id]
+	}
+	g.anon = nil
+	g.anonStart = nil
+}
+
+func (c *go122Converter) stop(g *go122G, e *Event) {
+	if g.evStart != nil {
+		g.evStart.Link = e
+		g.evStart = nil
+	}
+}
+
+func (c *go122Converter) sysBlock(ev exptrace.Event, id uint64, g *go122G, p int) {
+	if p < 0 {
+		p = SyscallP
+	}
+	e := c.emit(ev, EvGoSysBlock, p, id)
+	c.stop(g, e)
+	g.sysBlocked = true
+}
+
+func (c *go122Converter) sysExit(ev exptrace.Event, id uint64, g *go122G) {
+	e := c.emit(ev, EvGoSysExit, SyscallP, id)
+	e.Args[0] = id
+	if g.ev != nil && g.ev.Type == EvGoSysCall {
+		g.ev.Link = e
+	}
+	g.ev = e
+	c.endSyscall(id, g)
+}
+
+func (c *go122Converter) endSyscall(id uint64, g *go122G) {
+	if p := c.ps[g.syscallP]; p != nil && p.syscallG == id {
+		p.syscallG = 0
+	}
+	g.syscallP = -1
+	g.sysBlocked = false
+}
+
+func (c *go122Converter) procTransition(ev exptrace.Event, st exptrace.StateTransition) {
+	from, to := st.Proc()
+	id := int(st.Resource.Proc())
+	switch {
+	case from == to:
+	case to == exptrace.ProcRunning:
+		c.p(id).running = true
+		e := c.emit(ev, EvProcStart, id, 0)
+		if ev.Thread() != exptrace.NoThread {
+			e.Args[0] = uint64(ev.Thread())
+		}
+	case from == exptrace.ProcRunning:
+		// The P is stopped or stolen. A goroutine that was in a syscall
+		// on it is now blocked in the syscall.
+		p := c.p(id)
+		if g := c.gs[p.syscallG]; p.syscallG != 0 && g != nil && !g.sysBlocked {
+			c.sysBlock(ev, p.syscallG, g, id)
+		}
+		p.syscallG = 0
+		p.running = false
+		c.emit(ev, EvProcStop, id, 0)
+	}
+}
+
+func (c *go122Converter) rangeEvent(ev exptrace.Event) {
+	r := ev.Range()
+	end := ev.Kind() == exptrace.EventRangeEnd
+	switch {
+	case r.Name == "GC concurrent mark phase":
+		if !end {
+			if c.evGC == nil {
+				c.gcSeq++
+				c.evGC = c.emit(ev, EvGCStart, GCP, 0)
+				c.evGC.Args[0] = c.gcSeq
+				c.evGC.StkID = c.stack(ev.Stack())
+			}
+		} else if c.evGC != nil {
+			c.evGC.Link = c.emit(ev, EvGCDone, c.proc(ev), 0)
+			c.evGC = nil
+		}
+	case strings.HasPrefix(r.Name, "stop-the-world ("):
+		if !end {
+			if c.evSTW == nil {
+				c.evSTW = c.emit(ev, EvGCSTWStart, c.proc(ev), 0)
+				c.evSTW.SArgs = []string{strings.TrimSuffix(strings.TrimPrefix(r.Name, "stop-the-world ("), ")")}
+			}
+		} else if c.evSTW != nil {
+			c.evSTW.Link = c.emit(ev, EvGCSTWDone, c.proc(ev), 0)
+			c.evSTW = nil
+		}
+	case r.Name == "GC incremental sweep" && r.Scope.Kind == exptrace.ResourceProc:
+		id := int(r.Scope.Proc())
+		p := c.p(id)
+		if !end {
+			if p.evSweep == nil {
+				p.evSweep = c.emit(ev, EvGCSweepStart, id, c.goroutine(ev))
+				p.evSweep.StkID = c.stack(ev.Stack())
+			}
+		} else if p.evSweep != nil {
+			e := c.emit(ev, EvGCSweepDone, id, c.goroutine(ev))
+			for _, a := range ev.RangeAttributes() {
+				switch a.Name {
+				case "bytes swept":
+					e.Args[0] = a.Value.Uint64()
+				case "bytes reclaimed":
+					e.Args[1] = a.Value.Uint64()
+				}
+			}
+			p.evSweep.Link = e
+			p.evSweep = nil
+		}
+	case r.Name == "GC mark assist" && r.Scope.Kind == exptrace.ResourceGoroutine:
+		id := uint64(r.Scope.Goroutine())
+		g := c.g(id)
+		if !end {
+			if g.evMarkAssist == nil {
+				g.evMarkAssist = c.emit(ev, EvGCMarkAssistStart, c.proc(ev), id)
+				g.evMarkAssist.StkID = c.stack(ev.Stack())
+			}
+		} else if g.evMarkAssist != nil {
+			g.evMarkAssist.Link = c.emit(ev, EvGCMarkAssistDone, c.proc(ev), id)
+			g.evMarkAssist = nil
+		}
+	}
+}
+
+// finish ends everything that is still in progress at the end of the
+// trace, like StopTrace does in the older formats, so that analyses and
+// the viewer see an end for every start event.
+func (c *go122Converter) finish(ts int64) {
+	var gids []uint64
+	for id := range c.gs {
+		gids = append(gids, id)
+	}
+	sort.Slice(gids, func(i, j int) bool { return gids[i] < gids[j] })
+	for _, id := range gids {
+		g := c.gs[id]
+		if g.evMarkAssist != nil {
+			g.evMarkAssist.Link = c.emitAt(ts, EvGCMarkAssistDone, g.evMarkAssist.P, id)
+		}
+		if g.evStart != nil {
+			e := c.emitAt(ts, EvGoSched, g.evStart.P, id)
+			c.stop(g, e)
+			g.ev = e
+		}
+	}
+	var pids []int
+	for id := range c.ps {
+		pids = append(pids, id)
+	}
+	sort.Ints(pids)
+	for _, id := range pids {
+		p := c.ps[id]
+		if p.evSweep != nil {
+			p.evSweep.Link = c.emitAt(ts, EvGCSweepDone, id, p.evSweep.G)
+		}
+		if p.running {
+			c.emitAt(ts, EvProcStop, id, 0)
+		}
+	}
+	if c.evSTW != nil {
+		c.evSTW.Link = c.emitAt(ts, EvGCSTWDone, c.evSTW.P, 0)
+	}
+	if c.evGC != nil {
+		c.evGC.Link = c.emitAt(ts, EvGCDone, GCP, 0)
+	}
+}
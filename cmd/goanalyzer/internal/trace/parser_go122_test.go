@@ -0,0 +1,108 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime"
+	rtrace "runtime/trace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseGo122(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := rtrace.Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	ctx, task := rtrace.NewTask(context.Background(), "task0")
+	ch := make(chan int)
+	done := make(chan bool)
+	go func() {
+		rtrace.WithRegion(ctx, "region0", func() {
+			<-ch
+		})
+		done <- true
+	}()
+	time.Sleep(time.Millisecond)
+	ch <- 1
+	<-done
+	runtime.GC()
+	task.End()
+	rtrace.Stop()
+
+	res, err := Parse(buf, "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	var gcs, tasks int
+	for _, ev := range res.Events {
+		switch ev.Type {
+		case EvGoStart, EvGoStartLabel:
+			if ev.Link == nil {
+				t.Errorf("GoStart of g %d at %d has no end", ev.G, ev.Ts)
+			}
+		case EvGCStart:
+			if ev.Link == nil {
+				t.Errorf("GC at %d has no end", ev.Ts)
+			}
+			gcs++
+		case EvUserTaskCreate:
+			if ev.SArgs[0] == "task0" && ev.Link != nil {
+				tasks++
+			}
+		}
+	}
+	if gcs == 0 {
+		t.Errorf("no GC in the trace")
+	}
+	if tasks != 1 {
+		t.Errorf("found %d complete task0 tasks, want 1", tasks)
+	}
+
+	var found bool
+	for _, g := range GoroutineStats(res.Events) {
+		if !strings.Contains(g.Name, "TestParseGo122.func") {
+			continue
+		}
+		found = true
+		if g.BlockTime.Count != 1 || g.BlockTime.Total < int64(time.Millisecond)/2 {
+			t.Errorf("goroutine block time %+v, want one block of ~1ms", g.BlockTime)
+		}
+		if len(g.Regions) != 1 || g.Regions[0].Name != "region0" || g.Regions[0].End == nil {
+			t.Errorf("goroutine regions %v, want a complete region0", g.Regions)
+		}
+	}
+	if !found {
+		t.Errorf("goroutine started by the test is not in the trace")
+	}
+}
+
+func TestParseGo122Gomaxprocs(t *testing.T) {
+	n := runtime.GOMAXPROCS(0)
+	defer runtime.GOMAXPROCS(n)
+	buf := new(bytes.Buffer)
+	if err := rtrace.Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	runtime.GOMAXPROCS(n + 1)
+	runtime.GOMAXPROCS(n)
+	// The metric is sampled again in the next generations.
+	time.Sleep(1500 * time.Millisecond)
+	rtrace.Stop()
+
+	res, err := Parse(buf, "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	var procs []uint64
+	for _, ev := range res.Events {
+		if ev.Type == EvGomaxprocs {
+			procs = append(procs, ev.Args[0])
+		}
+	}
+	if want := []uint64{uint64(n), uint64(n + 1), uint64(n)}; fmt.Sprint(procs) != fmt.Sprint(want) {
+		t.Errorf("GOMAXPROCS events %v, want %v", procs, want)
+	}
+}
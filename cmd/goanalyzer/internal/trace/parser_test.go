@@ -6,6 +6,7 @@ package trace
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
@@ -100,3 +101,87 @@ func TestTimestampOverflow(t *testing.T) {
 		t.Fatalf("failed to parse: %v", err)
 	}
 }
+
+func TestParseVersions(t *testing.T) {
+	// Go 1.12 through 1.18 write the 1.11 format and Go 1.20 writes
+	// the 1.19 format, so these traces cover every supported toolchain.
+	for _, test := range []struct {
+		file string
+		ver  int
+	}{
+		{"testdata/stress_1_11_good", 1011},
+		{"testdata/stress_1_19_good", 1019},
+		{"testdata/stress_1_21_good", 1021},
+	} {
+		data, err := ioutil.ReadFile(test.file)
+		if err != nil {
+			t.Fatalf("failed to read input file: %v", err)
+		}
+		ver, res, err := parse(bytes.NewReader(data), "")
+		if err != nil {
+			t.Errorf("%s: failed to parse: %v", test.file, err)
+			continue
+		}
+		if ver != test.ver {
+			t.Errorf("%s: version %d, want %d", test.file, ver, test.ver)
+		}
+		// Every GC cycle stops the world for the sweep termination,
+		// then for the mark termination, whatever the numbering of
+		// the kinds in the version.
+		var cycles int
+		var kinds []string
+		for _, ev := range res.Events {
+			switch ev.Type {
+			case EvGCStart:
+				kinds = kinds[:0]
+			case EvGCSTWStart:
+				if len(ev.SArgs) != 1 {
+					t.Fatalf("%s: STW event %v has no kind", test.file, ev)
+				}
+				kinds = append(kinds, ev.SArgs[0])
+			case EvGCDone:
+				if got, want := strings.Join(kinds, ", "), "GC sweep termination, GC mark termination"; got != want {
+					t.Fatalf("%s: STW kinds of the GC cycle at %d: %s, want %s", test.file, ev.Ts, got, want)
+				}
+				cycles++
+			}
+		}
+		if cycles == 0 {
+			t.Errorf("%s: no GC cycle", test.file)
+		}
+	}
+}
+
+func TestCPUSample(t *testing.T) {
+	// CPU samples were added in Go 1.19.
+	data, err := ioutil.ReadFile("testdata/fmt_1_21_pprof_good")
+	if err != nil {
+		t.Fatalf("failed to read input file: %v", err)
+	}
+	res, err := Parse(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	first, last := res.Events[0].Ts, res.Events[len(res.Events)-1].Ts
+	var samples int
+	for _, ev := range res.Events {
+		if ev.Type != EvCPUSample {
+			continue
+		}
+		samples++
+		// The sample uses the timestamp taken in the signal handler,
+		// relative to the first event in the trace.
+		if ev.Ts < first || ev.Ts > last || len(ev.Stk) == 0 {
+			t.Errorf("CPU sample at %d with %d frames, want one in [%d, %d] with a stack", ev.Ts, len(ev.Stk), first, last)
+		}
+	}
+	if samples == 0 {
+		t.Errorf("no CPU sample event")
+	}
+
+	// The same trace, as if written by Go 1.11.
+	old := append([]byte("go 1.11 trace\x00\x00\x00"), data[16:]...)
+	if _, err := Parse(bytes.NewReader(old), ""); err == nil || !strings.Contains(err.Error(), fmt.Sprintf("unknown event type %d", EvCPUSample)) {
+		t.Fatalf("CPU sample in a 1.11 trace: got error %v, want an unknown event type", err)
+	}
+}
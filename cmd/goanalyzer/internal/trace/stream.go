@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	exptrace "golang.org/x/exp/trace"
+)
+
+// streamChunk is about the number of events Stream.Next returns at a
+// time. It is a variable for tests.
+var streamChunk = 1 << 16
+
+// Stream parses a trace incrementally: Next returns the events of
+// ParseResult.Events in order, a chunk at a time.
+//
+// Traces written by Go 1.22 and later are decoded a generation at a
+// time by golang.org/x/exp/trace and converted as they are read, so as
+// long as the caller does not keep the events, memory is bounded by the
+// size of a generation and the number of goroutines rather than by the
+// size of the trace. Older traces have per-P batches that are only
+// ordered once the whole trace is read: they are parsed up front, and
+// the chunks only spare the caller from holding all the events.
+//
+// The events of a returned chunk may still be completed by later ones:
+// the Link of an event is set when the linked event is read, and the
+// stack of the first GoStart of a goroutine that was running when
+// tracing started is known once the goroutine records one.
+type Stream struct {
+	// Go 1.22 and later.
+	rd    *exptrace.Reader
+	c     *go122Converter
+	first bool
+	eof   bool
+
+	// Older traces.
+	events []*Event // not yet returned
+	stacks map[uint64][]*Frame
+}
+
+// NewStream returns a Stream of the trace read from r. bin is the
+// program binary, which is required for traces written by Go 1.6 and
+// below.
+func NewStream(r io.Reader, bin string) (*Stream, error) {
+	br := bufio.NewReader(r)
+	if header, err := br.Peek(16); err == nil {
+		if ver, err := parseHeader(header); err == nil && ver >= 1022 {
+			return newGo122Stream(br)
+		}
+	}
+	ver, res, err := parse(br, bin)
+	if err != nil {
+		return nil, err
+	}
+	if ver < 1007 && bin == "" {
+		return nil, fmt.Errorf("for traces produced by go 1.6 or below, the binary argument must be provided")
+	}
+	return &Stream{events: res.Events, stacks: res.Stacks}, nil
+}
+
+func newGo122Stream(r io.Reader) (*Stream, error) {
+	rd, err := exptrace.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	c := &go122Converter{
+		stacks:    make(map[uint64][]*Frame),
+		stackIDs:  make(map[exptrace.Stack]uint64),
+		stackKeys: make(map[string]uint64),
+		gs:        make(map[uint64]*go122G),
+		ps:        make(map[int]*go122P),
+		tasks:     make(map[uint64]*Event),
+	}
+	return &Stream{rd: rd, c: c, first: true}, nil
+}
+
+// Next returns the next events of the trace, or io.EOF after the last
+// ones.
+func (s *Stream) Next() ([]*Event, error) {
+	if s.c == nil {
+		n := len(s.events)
+		if n == 0 {
+			return nil, io.EOF
+		}
+		if n > streamChunk {
+			n = streamChunk
+		}
+		// Copy the chunk so that the events are freed once the caller
+		// drops it.
+		events := append([]*Event(nil), s.events[:n]...)
+		for i := range s.events[:n] {
+			s.events[i] = nil
+		}
+		s.events = s.events[n:]
+		return events, nil
+	}
+
+	c := s.c
+	// Don't end a chunk with a GoStart, which the label of a GC worker
+	// that follows turns into a GoStartLabel.
+	for !s.eof && (len(c.events) < streamChunk || c.events[len(c.events)-1].Type == EvGoStart) {
+		ev, err := s.rd.ReadEvent()
+		if err == io.EOF {
+			s.eof = true
+			if c.n > 0 {
+				c.finish(c.lastTs)
+			}
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.first {
+			c.minTs = ev.Time()
+			s.first = false
+		}
+		c.convert(ev)
+	}
+	events := c.events
+	if len(events) == 0 {
+		return nil, io.EOF
+	}
+	c.events = nil
+	// Attach stack traces.
+	for _, ev := range events {
+		if ev.StkID != 0 {
+			ev.Stk = c.stacks[ev.StkID]
+		}
+	}
+	return events, nil
+}
+
+// Stacks returns the stack traces keyed by stack IDs of the events
+// returned so far.
+func (s *Stream) Stacks() map[uint64][]*Frame {
+	if s.c == nil {
+		return s.stacks
+	}
+	return s.c.stacks
+}
@@ -0,0 +1,96 @@
+package trace
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"runtime"
+	rtrace "runtime/trace"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStream(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := rtrace.Start(buf); err != nil {
+		t.Fatalf("failed to start tracing: %v", err)
+	}
+	var wg sync.WaitGroup
+	ch := make(chan int)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range ch {
+				time.Sleep(time.Duration(v) * time.Microsecond)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	runtime.GC()
+	rtrace.Stop()
+	data := buf.Bytes()
+
+	res, err := Parse(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	defer func(n int) { streamChunk = n }(streamChunk)
+	streamChunk = 16
+	s, err := NewStream(bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("failed to create stream: %v", err)
+	}
+	const flags = UtilSTW | UtilBackground | UtilAssist
+	gb := NewGoroutineStatsBuilder()
+	mb := NewMutatorUtilBuilder(flags)
+	pb := NewMutatorUtilBuilder(flags | UtilPerProc)
+	var n, chunks int
+	for {
+		events, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		n += len(events)
+		chunks++
+		gb.Add(events)
+		mb.Add(events)
+		pb.Add(events)
+	}
+	if n != len(res.Events) || chunks < 2 {
+		t.Fatalf("stream returned %d events in %d chunks, want %d events in chunks", n, chunks, len(res.Events))
+	}
+
+	want, got := GoroutineStats(res.Events), gb.Finish()
+	if len(got) != len(want) {
+		t.Errorf("stream has %d goroutines, want %d", len(got), len(want))
+	}
+	for id, w := range want {
+		g := got[id]
+		if g == nil {
+			t.Errorf("goroutine %d is missing from the stream", id)
+			continue
+		}
+		if g.Name != w.Name || g.PC != w.PC || g.ExecTime.Total != w.ExecTime.Total ||
+			g.BlockTime.Total != w.BlockTime.Total || g.SchedWaitTime.Total != w.SchedWaitTime.Total {
+			t.Errorf("goroutine %d: stream %s %x exec %d block %d sched %d, want %s %x exec %d block %d sched %d", id,
+				g.Name, g.PC, g.ExecTime.Total, g.BlockTime.Total, g.SchedWaitTime.Total,
+				w.Name, w.PC, w.ExecTime.Total, w.BlockTime.Total, w.SchedWaitTime.Total)
+		}
+	}
+	if got, want := mb.Finish(), MutatorUtilization(res.Events, flags); !reflect.DeepEqual(got, want) {
+		t.Errorf("stream mutator utilization differs")
+	}
+	if got, want := pb.Finish(), MutatorUtilization(res.Events, flags|UtilPerProc); !reflect.DeepEqual(got, want) {
+		t.Errorf("stream per-P mutator utilization differs")
+	}
+}
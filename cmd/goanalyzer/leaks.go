@@ -0,0 +1,313 @@
+// Possibly leaked goroutines.
+
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/leaks", httpLeaks)
+}
+
+const (
+	// leakTailDefault is the default length of the tail of the trace,
+	// as a fraction of its duration, that a goroutine must have been
+	// blocked for to be reported.
+	leakTailDefault = 0.25
+	// leakBuckets is the number of intervals the counts over time
+	// are computed for.
+	leakBuckets = 50
+)
+
+// leakKinds are the blocking events that may leak a goroutine, with
+// the names of their reasons.
+var leakKinds = map[byte]string{
+	trace.EvGoBlockSend:   "chan send",
+	trace.EvGoBlockRecv:   "chan receive",
+	trace.EvGoBlockSelect: "select",
+	trace.EvGoBlockSync:   "sync",
+	trace.EvGoBlockCond:   "sync.Cond",
+	trace.EvGoBlockNet:    "network",
+}
+
+// leakGroup is a group of goroutines, with the same start function and
+// creation site, that were blocked at the same stack from before the
+// tail of the trace until its end.
+type leakGroup struct {
+	Kind  string         // Blocking reason.
+	Name  string         // Start function.
+	Site  string         // Creation site.
+	Stack []*trace.Frame // Blocking stack.
+	GIDS  map[uint64]bool
+	// Time, relative to the start of the trace, the first goroutine
+	// of the group blocked at.
+	Since time.Duration
+	// Counts[i] is the number of goroutines of the group blocked for
+	// good by the end of the i-th of leakBuckets intervals of the trace.
+	Counts []int
+
+	blocked []int64 // times the goroutines blocked at
+}
+
+// Frame returns the innermost frame of the blocking stack outside of
+// package runtime, or the innermost frame if there is none.
+func (l *leakGroup) Frame() *trace.Frame {
+	for _, f := range l.Stack {
+		if !strings.HasPrefix(f.Fn, "runtime.") {
+			return f
+		}
+	}
+	if len(l.Stack) > 0 {
+		return l.Stack[0]
+	}
+	return &trace.Frame{}
+}
+
+// findLeaks groups the goroutines blocked on channels, selects, sync
+// primitives or the network at the end of the non-empty events, and
+// since at least tail before their end, by blocking stack and creation
+// site. Goroutines blocked since before the first event count as
+// blocked since then. Goroutines of the runtime itself are left out.
+// The groups are sorted by decreasing size.
+func findLeaks(events []*trace.Event, gs map[uint64]*trace.GDesc, tail time.Duration) []*leakGroup {
+	start, end := events[0].Ts, events[len(events)-1].Ts
+	type key struct {
+		pc, site, stk uint64
+		typ           byte
+	}
+	groups := make(map[key]*leakGroup)
+	for _, g := range gs {
+		ev := g.EndBlock
+		if ev == nil || strings.HasPrefix(g.Name, "runtime.") {
+			continue
+		}
+		typ, blocked := ev.Type, ev.Ts
+		if typ == trace.EvGoWaiting {
+			// Blocked since before the trace started, whenever the
+			// trace recorded it.
+			typ, blocked = byte(ev.Args[1]), start
+		}
+		if blocked < start {
+			blocked = start
+		}
+		if end-blocked < int64(tail) {
+			continue
+		}
+		kind, ok := leakKinds[typ]
+		if !ok {
+			continue
+		}
+		k := key{pc: g.PC, stk: ev.StkID, typ: typ}
+		if len(g.CreationStk) > 0 {
+			k.site = g.CreationStk[0].PC
+		}
+		l := groups[k]
+		if l == nil {
+			l = &leakGroup{Kind: kind, Name: g.Name, Stack: ev.Stk, GIDS: make(map[uint64]bool), Since: time.Duration(blocked - start)}
+			if l.Name == "" {
+				l.Name = fmt.Sprint("PC:", g.PC)
+			}
+			if len(g.CreationStk) > 0 {
+				f := g.CreationStk[0]
+				l.Site = fmt.Sprintf("%s %s:%d", f.Fn, f.File, f.Line)
+			}
+			groups[k] = l
+		}
+		l.GIDS[g.ID] = true
+		if d := time.Duration(blocked - start); d < l.Since {
+			l.Since = d
+		}
+		l.blocked = append(l.blocked, blocked)
+	}
+
+	var list []*leakGroup
+	for _, l := range groups {
+		l.Counts = make([]int, leakBuckets)
+		for i := range l.Counts {
+			bound := start + (end-start)*int64(i+1)/leakBuckets
+			for _, ts := range l.blocked {
+				if ts <= bound {
+					l.Counts[i]++
+				}
+			}
+		}
+		list = append(list, l)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if len(list[i].GIDS) != len(list[j].GIDS) {
+			return len(list[i].GIDS) > len(list[j].GIDS)
+		}
+		return list[i].Since < list[j].Since
+	})
+	return list
+}
+
+// leakTail returns the default tail of the non-empty trace for findLeaks.
+func leakTail(events []*trace.Event) time.Duration {
+	return time.Duration(float64(events[len(events)-1].Ts-events[0].Ts) * leakTailDefault)
+}
+
+// httpLeaks serves the possibly leaked goroutines. The tail parameter
+// overrides the default tail the goroutines were blocked for. With the
+// from and to parameters, the goroutines are those blocked at the end
+// of the window, since at least tail before it.
+func httpLeaks(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(events) == 0 {
+		http.Error(w, "empty trace", http.StatusInternalServerError)
+		return
+	}
+	st.analyzeGoroutines(events)
+	gs := st.gs
+	if win != wholeTrace {
+		// The goroutine states at the end of the window.
+		i := sort.Search(len(events), func(i int) bool { return events[i].Ts >= win.from })
+		j := sort.Search(len(events), func(i int) bool { return events[i].Ts > win.to })
+		if i >= j {
+			http.Error(w, "no events in the window", http.StatusBadRequest)
+			return
+		}
+		gs = trace.GoroutineStats(events[:j])
+		events = events[i:j]
+	}
+
+	tail := leakTail(events)
+	if s := r.FormValue("tail"); s != "" {
+		tail, err = time.ParseDuration(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse tail parameter '%v': %v", s, err), http.StatusInternalServerError)
+			return
+		}
+	}
+	groups := findLeaks(events, gs, tail)
+	n := 0
+	for _, l := range groups {
+		n += len(l.GIDS)
+	}
+
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	err = templLeaks.Execute(w, struct {
+		Tail     time.Duration
+		Duration time.Duration
+		Window   bool
+		N        int
+		Groups   []*leakGroup
+	}{
+		Tail:     tail,
+		Window:   win != wholeTrace,
+		Duration: time.Duration(events[len(events)-1].Ts - events[0].Ts),
+		N:        n,
+		Groups:   groups,
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templLeaks = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+	"gidList": func(gids map[uint64]bool) template.HTML {
+		return template.HTML(gidList(gids))
+	},
+	"countBars": func(counts []int) template.HTML {
+		max := 0
+		for _, c := range counts {
+			if c > max {
+				max = c
+			}
+		}
+		var b strings.Builder
+		b.WriteString(`<div class="counts">`)
+		for _, c := range counts {
+			h := 0
+			if max > 0 {
+				h = c * 20 / max
+			}
+			fmt.Fprintf(&b, `<span style="height:%dpx" title="%d"></span>`, h, c)
+		}
+		b.WriteString(`</div>`)
+		return template.HTML(b.String())
+	},
+}).Parse(`
+<!DOCTYPE html>
+<title>Possibly leaked goroutines</title>
+<style>
+th {
+  background-color: #050505;
+  color: #fff;
+}
+table {
+  border-collapse: collapse;
+}
+.details tr:hover {
+  background-color: #f2f2f2;
+}
+.details td {
+  border: 1px solid black;
+  vertical-align: top;
+  padding: 0.2em 0.4em;
+}
+.stack {
+  font-size: 85%;
+  white-space: nowrap;
+}
+.counts {
+  display: flex;
+  align-items: flex-end;
+  height: 20px;
+}
+.counts span {
+  width: 3px;
+  margin-right: 1px;
+  background-color: #d01c8b;
+}
+</style>
+<script>` + windowScript + `</script>
+<body>
+<p>Goroutines blocked on a channel, select, sync primitive or the network from before
+the last {{niceDuration .Tail}} of the {{niceDuration .Duration}} {{if .Window}}window{{else}}trace{{end}} until its end,
+grouped by blocking stack and creation site: {{.N}} goroutines.
+The counts show how many goroutines of each group were blocked for good over the trace.</p>
+<table class="details">
+<tr>
+<th> Goroutine</th>
+<th> Count</th>
+<th> Blocked on</th>
+<th> Blocking stack</th>
+<th> Created at</th>
+<th> First blocked at</th>
+<th> Count over time</th>
+</tr>
+{{range .Groups}}
+  <tr>
+    <td>{{.Name}}</td>
+    <td><a href="/trace?goid={{gidList .GIDS}}">{{len .GIDS}}</a></td>
+    <td>{{.Kind}}</td>
+    <td class="stack">{{range .Stack}}{{.Fn}}<br>&nbsp;&nbsp;{{.File}}:{{.Line}}<br>{{end}}</td>
+    <td class="stack">{{.Site}}</td>
+    <td>{{niceDuration .Since}}</td>
+    <td>{{countBars .Counts}}</td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,111 @@
+// +build !js
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// leakyWorker blocks until done is closed.
+func leakyWorker(done chan struct{}) {
+	<-done
+}
+
+func TestFindLeaks(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	prog := func() {
+		for i := 0; i < 3; i++ {
+			go leakyWorker(done)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := traceProgram(t, prog, "TestFindLeaks"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+
+	var leak *leakGroup
+	for _, l := range findLeaks(events, mainTrace.gs, leakTail(events)) {
+		if l.Kind == "chan receive" && l.Frame().Fn == "github.com/robaho/goanalyzer/cmd/goanalyzer.leakyWorker" {
+			leak = l
+		}
+	}
+	if leak == nil {
+		t.Fatalf("no leaked leakyWorker goroutines found")
+	}
+	if n := len(leak.GIDS); n != 3 {
+		t.Errorf("%d leaked leakyWorker goroutines, want 3", n)
+	}
+	if n := leak.Counts[len(leak.Counts)-1]; n != 3 {
+		t.Errorf("%d leaked leakyWorker goroutines at the end of the trace, want 3", n)
+	}
+	for i := 1; i < len(leak.Counts); i++ {
+		if leak.Counts[i] < leak.Counts[i-1] {
+			t.Errorf("leaked goroutine counts decrease: %v", leak.Counts)
+			break
+		}
+	}
+
+	// Nothing was blocked for the whole trace.
+	duration := time.Duration(events[len(events)-1].Ts - events[0].Ts)
+	if leaks := findLeaks(events, mainTrace.gs, duration+1); len(leaks) != 0 {
+		t.Errorf("found %d groups blocked for longer than the trace", len(leaks))
+	}
+
+	rep, err := mainTrace.buildReport()
+	if err != nil {
+		t.Fatalf("failed to build report: %v", err)
+	}
+	var reported bool
+	for _, l := range rep.Leaks {
+		if l.Count == 3 && l.Kind == "chan receive" {
+			reported = true
+		}
+	}
+	if !reported {
+		t.Errorf("report does not list the leaked goroutines: %+v", rep.Leaks)
+	}
+}
+
+// blockedWorker blocks until done is closed.
+func blockedWorker(done chan struct{}) {
+	<-done
+}
+
+func TestFindLeaksBlockedBeforeTrace(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	for i := 0; i < 2; i++ {
+		go blockedWorker(done)
+	}
+	time.Sleep(10 * time.Millisecond)
+	prog := func() { time.Sleep(50 * time.Millisecond) }
+	if err := traceProgram(t, prog, "TestFindLeaksBlockedBeforeTrace"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+
+	var leak *leakGroup
+	for _, l := range findLeaks(events, mainTrace.gs, leakTail(events)) {
+		if l.Kind == "chan receive" && l.Name == "github.com/robaho/goanalyzer/cmd/goanalyzer.blockedWorker" {
+			leak = l
+		}
+	}
+	if leak == nil {
+		t.Fatalf("no leaked blockedWorker goroutines found")
+	}
+	if n := len(leak.GIDS); n != 2 {
+		t.Errorf("%d leaked blockedWorker goroutines, want 2", n)
+	}
+	if leak.Since != 0 {
+		t.Errorf("blockedWorker goroutines blocked since %v, want the start of the trace", leak.Since)
+	}
+}
@@ -6,6 +6,7 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
@@ -15,9 +16,12 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"runtime/debug"
+	"sort"
 	"sync"
+	"sync/atomic"
 
 	_ "net/http/pprof" // Required to use pprof
 )
@@ -30,9 +34,36 @@ Given a trace file produced by 'go test':
 Open a web browser displaying trace:
 	go tool trace [flags] [pkg.test] trace.out
 
+Browse the traces in a directory, including the traces uploaded or
+captured through the web UI, or the directory of trace.out with it
+open:
+	go tool trace [flags] dir
+	go tool trace -dir [flags] trace.out
+
+Capture a trace from a process serving net/http/pprof, store it in
+dir (the current directory by default) with a timestamped name, and
+browse dir with the new trace open, or use it in the other modes:
+	go tool trace -capture=http://host:port [-seconds=N] [flags] [dir]
+
 Generate a pprof-like profile from the trace:
     go tool trace -pprof=TYPE [pkg.test] trace.out
 
+Print a summary of the goroutine, user task, user region, GC and
+goroutine leak analyses:
+    go tool trace -report=FORMAT [pkg.test] trace.out
+
+Check the trace against the thresholds in a rules file, exiting with
+status 1 if any rule fails and 2 if the rules or the trace cannot be read:
+    go tool trace -check=RULES [pkg.test] trace.out
+
+Convert the whole trace to Perfetto's protobuf format, for
+ui.perfetto.dev and trace_processor:
+    go tool trace -perfetto=trace.perfetto-trace [pkg.test] trace.out
+
+Compare the trace against a base trace, such as one recorded before a
+change, on the /diff page:
+    go tool trace -base=before.out after.out
+
 [pkg.test] argument is required for traces produced by Go 1.6 and below.
 Go 1.7 does not require the binary argument.
 
@@ -41,10 +72,31 @@ Supported profile types are:
     - sync: synchronization blocking profile
     - syscall: syscall blocking profile
     - sched: scheduler latency profile
+    - assist: GC mark assist profile
+
+Supported report formats are text, json and markdown.
+
+A rules file has one rule per line, such as:
+    p99 SchedWaitTime of group main.worker < 2ms
+    MMU at 10ms window > 0.6
+    user task 'request' p95 < 50ms
+    STW max < 1ms
+Blank lines and lines starting with # are skipped. A rule may be quoted
+and preceded by "- ", and a "rules:" line is skipped, so that a YAML
+list of rules is read too; no other YAML syntax is supported.
 
 Flags:
 	-http=addr: HTTP service address (e.g., ':6060')
 	-pprof=type: print a pprof-like profile instead
+	-report=format: print a summary report instead
+	-check=file: check the trace against the rules in file instead
+	-base=file: compare the trace against the base trace in file
+	-perfetto=file: write the trace in Perfetto's format to file instead
+	-dir: serve the directory of the trace, with the trace open
+	-maxmem=MB: heap size above which parsed traces of a directory are dropped
+	-capture=url: capture a trace from /debug/pprof/trace at url first
+	-seconds=N: duration of the captured trace, 5 by default
+	-index=false: neither read nor write the index of the parsed trace
 	-d: print debug info such as parsed events
 
 Note that while the various profiles available when launching
@@ -54,9 +106,18 @@ and is only actively tested on that browser.
 `
 
 var (
-	httpFlag  = flag.String("http", "localhost:0", "HTTP service address (e.g., ':6060')")
-	pprofFlag = flag.String("pprof", "", "print a pprof-like profile instead")
-	debugFlag = flag.Bool("d", false, "print debug information such as parsed events list")
+	httpFlag     = flag.String("http", "localhost:0", "HTTP service address (e.g., ':6060')")
+	pprofFlag    = flag.String("pprof", "", "print a pprof-like profile instead")
+	reportFlag   = flag.String("report", "", "print a summary report (text, json or markdown) instead")
+	checkFlag    = flag.String("check", "", "check the trace against the rules in file instead")
+	baseFlag     = flag.String("base", "", "compare the trace against the base trace in file")
+	perfettoFlag = flag.String("perfetto", "", "write the trace in Perfetto's format to file instead")
+	dirFlag      = flag.Bool("dir", false, "serve the directory of the trace, with the trace open")
+	maxMemFlag   = flag.Int("maxmem", 2048, "heap size in MB above which parsed traces of a directory are dropped")
+	captureFlag  = flag.String("capture", "", "capture a trace from the net/http/pprof server at this URL")
+	secondsFlag  = flag.Int("seconds", 5, "duration of the trace to capture in seconds")
+	indexFlag    = flag.Bool("index", true, "read and write an index of the parsed trace, next to it or in the user cache directory")
+	debugFlag    = flag.Bool("d", false, "print debug information such as parsed events list")
 
 	// The binary file name, left here for serveSVGProfile.
 	programBinary string
@@ -65,42 +126,100 @@ var (
 
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, usageMessage)
+		fmt.Fprint(os.Stderr, usageMessage)
 		os.Exit(2)
 	}
 	flag.Parse()
 
 	// Go 1.7 traces embed symbol info and does not require the binary.
 	// But we optionally accept binary as first arg for Go 1.5 traces.
-	switch flag.NArg() {
-	case 1:
+	switch {
+	case *captureFlag != "":
+		// The argument is the directory to store the trace in.
+		dir := "."
+		switch flag.NArg() {
+		case 0:
+		case 1:
+			dir = flag.Arg(0)
+		default:
+			flag.Usage()
+		}
+		log.Printf("Capturing a %ds trace from %s...", *secondsFlag, *captureFlag)
+		file, err := captureTrace(*captureFlag, *secondsFlag, dir)
+		if err != nil {
+			dief("failed to capture trace: %v\n", err)
+		}
+		log.Printf("Stored the trace in %s", file)
+		if !singleTraceMode() {
+			serveTraceDir(dir, filepath.Base(file))
+		}
+		traceFile = file
+	case flag.NArg() == 1:
 		traceFile = flag.Arg(0)
-	case 2:
+	case flag.NArg() == 2:
 		programBinary = flag.Arg(0)
 		traceFile = flag.Arg(1)
 	default:
 		flag.Usage()
 	}
-
-	var pprofFunc func(io.Writer, *http.Request) error
-	switch *pprofFlag {
-	case "net":
-		pprofFunc = pprofByGoroutine(computePprofIO)
-	case "sync":
-		pprofFunc = pprofByGoroutine(computePprofBlock)
-	case "syscall":
-		pprofFunc = pprofByGoroutine(computePprofSyscall)
-	case "sched":
-		pprofFunc = pprofByGoroutine(computePprofSched)
+	if fi, err := os.Stat(traceFile); err == nil && fi.IsDir() {
+		if singleTraceMode() {
+			dief("%s is a directory: only the web UI serves directories\n", traceFile)
+		}
+		serveTraceDir(traceFile, "")
+	}
+	if *dirFlag {
+		if singleTraceMode() || programBinary != "" {
+			dief("-dir serves a directory in the web UI, without a program binary\n")
+		}
+		serveTraceDir(filepath.Dir(traceFile), filepath.Base(traceFile))
 	}
-	if pprofFunc != nil {
-		if err := pprofFunc(os.Stdout, &http.Request{}); err != nil {
+	mainTrace = newTraceState(traceFile, programBinary)
+	baseLoader.file = *baseFlag
+
+	if *pprofFlag != "" {
+		match, ok := pprofEvents[*pprofFlag]
+		if !ok {
+			dief("unknown pprof type %s\n", *pprofFlag)
+		}
+		p, err := streamPprof(traceFile, programBinary, match)
+		if err == nil {
+			err = p.Write(os.Stdout)
+		}
+		if err != nil {
 			dief("failed to generate pprof: %v\n", err)
 		}
 		os.Exit(0)
 	}
-	if *pprofFlag != "" {
-		dief("unknown pprof type %s\n", *pprofFlag)
+	if *reportFlag != "" {
+		if err := mainTrace.writeReport(os.Stdout, *reportFlag); err != nil {
+			dief("failed to generate report: %v\n", err)
+		}
+		os.Exit(0)
+	}
+	if *perfettoFlag != "" {
+		f, err := os.Create(*perfettoFlag)
+		if err != nil {
+			dief("%v\n", err)
+		}
+		if err := mainTrace.writePerfettoTrace(f); err != nil {
+			dief("failed to write perfetto trace: %v\n", err)
+		}
+		if err := f.Close(); err != nil {
+			dief("%v\n", err)
+		}
+		os.Exit(0)
+	}
+	if *checkFlag != "" {
+		ok, err := mainTrace.runCheck(os.Stdout, *checkFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to check trace: %v\n", err)
+			os.Exit(2)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
 	ln, err := net.Listen("tcp", *httpFlag)
@@ -108,24 +227,29 @@ func main() {
 		dief("failed to create server socket: %v\n", err)
 	}
 
-	log.Print("Parsing trace...")
-	res, err := parseTrace()
-	if err != nil {
-		dief("%v\n", err)
-	}
-
 	if *debugFlag {
+		res, err := mainTrace.parseTrace()
+		if err != nil {
+			dief("%v\n", err)
+		}
 		trace.Print(res.Events)
 		os.Exit(0)
 	}
-	reportMemoryUsage("after parsing trace")
-	debug.FreeOSMemory()
 
-	log.Print("Splitting trace...")
-	ranges = splitTrace(res)
-	reportMemoryUsage("after spliting trace")
+	log.Print("Parsing and splitting trace...")
+	if _, err := mainTrace.open(); err != nil {
+		dief("%v\n", err)
+	}
+	reportMemoryUsage("after parsing and splitting trace")
 	debug.FreeOSMemory()
 
+	if *baseFlag != "" {
+		log.Print("Parsing base trace...")
+		if _, err := buildBaseReport(); err != nil {
+			dief("%v\n", err)
+		}
+	}
+
 	addr := "http://" + ln.Addr().String()
 	log.Printf("Opening browser. Trace viewer is listening on %s", addr)
 	//TODO don't have access to internal/browser package
@@ -137,47 +261,207 @@ func main() {
 	dief("failed to start http server: %v\n", err)
 }
 
-var ranges []Range
+// singleTraceMode reports whether the flags ask for a mode other than
+// the web UI, or for the comparison with a base trace, which need a
+// single trace rather than a directory.
+func singleTraceMode() bool {
+	return *pprofFlag != "" || *reportFlag != "" || *checkFlag != "" || *perfettoFlag != "" || *baseFlag != "" || *debugFlag
+}
+
+// traceState is a trace being analyzed and the caches of the analyses
+// of its events. The handlers get the state of the trace a request is
+// for with requestTrace.
+type traceState struct {
+	loader *traceLoader
+	ranges []Range
+
+	gsInit sync.Once
+	gs     map[uint64]*trace.GDesc
+
+	critPathInit sync.Once
+	critIndex    *critPathIndex
+
+	mmuLock  sync.Mutex
+	mmuCache map[trace.UtilFlags]*mmuCacheEntry
+
+	openInit sync.Once
+	meta     *traceMeta
+	openErr  error
+}
+
+func newTraceState(file, bin string) *traceState {
+	return &traceState{
+		loader:   &traceLoader{file: file, bin: bin},
+		mmuCache: make(map[trace.UtilFlags]*mmuCacheEntry),
+	}
+}
 
-var loader struct {
-	once sync.Once
-	res  trace.ParseResult
-	err  error
+// mainTrace is the trace given on the command line.
+var mainTrace = newTraceState("", "")
+
+type traceKey struct{}
+
+// withTrace returns r for the trace st.
+func withTrace(r *http.Request, st *traceState) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), traceKey{}, st))
+}
+
+// requestTrace returns the trace r is for, see withTrace, or mainTrace.
+func requestTrace(r *http.Request) *traceState {
+	if st, ok := r.Context().Value(traceKey{}).(*traceState); ok {
+		return st
+	}
+	return mainTrace
+}
+
+// traceLoader parses a trace file once, on first use.
+type traceLoader struct {
+	file  string      // trace file
+	bin   string      // program binary, for traces produced by Go 1.6 and below
+	index *traceIndex // read instead of the trace file if not nil
+
+	once   sync.Once
+	parsed atomic.Bool // once is done
+	res    trace.ParseResult
+	gs     map[uint64]*trace.GDesc // goroutine statistics, computed with the events
+	util   [][]trace.MutatorUtil   // utilization of reportMMUFlags, nil if read from the index
+	err    error
 }
 
 // parseEvents is a compatibility wrapper that returns only
 // the Events part of trace.ParseResult returned by parseTrace.
-func parseEvents() ([]*trace.Event, error) {
-	res, err := parseTrace()
+func (st *traceState) parseEvents() ([]*trace.Event, error) {
+	res, err := st.parseTrace()
 	if err != nil {
 		return nil, err
 	}
 	return res.Events, err
 }
 
-func parseTrace() (trace.ParseResult, error) {
-	loader.once.Do(func() {
-		tracef, err := os.Open(traceFile)
+func (st *traceState) parseTrace() (trace.ParseResult, error) {
+	return st.loader.parse()
+}
+
+func (l *traceLoader) parse() (trace.ParseResult, error) {
+	l.once.Do(func() {
+		if l.index != nil {
+			res, gs, err := l.index.load()
+			if err == nil {
+				l.res, l.gs = res, gs
+				return
+			}
+			log.Printf("Failed to read index, parsing the trace: %v", err)
+		}
+		tracef, err := os.Open(l.file)
 		if err != nil {
-			loader.err = fmt.Errorf("failed to open trace file: %v", err)
+			l.err = fmt.Errorf("failed to open trace file: %v", err)
 			return
 		}
 		defer tracef.Close()
 
 		// Parse and symbolize.
-		res, err := trace.Parse(bufio.NewReader(tracef), programBinary)
-		if err != nil {
-			loader.err = fmt.Errorf("failed to parse trace: %v", err)
-			return
+		if err := l.stream(bufio.NewReader(tracef)); err != nil {
+			l.err = fmt.Errorf("failed to parse trace: %v", err)
 		}
-		loader.res = res
 	})
-	return loader.res, loader.err
+	l.parsed.Store(true)
+	return l.res, l.err
+}
+
+// stream reads the events of the trace in r as a trace.Stream, and
+// computes the goroutine statistics and the mutator utilization of the
+// report from the chunks as they are read. The web UI and -perfetto
+// need every event, so they are kept; -pprof, -report and -check
+// stream the trace themselves without keeping them.
+func (l *traceLoader) stream(r io.Reader) error {
+	s, err := trace.NewStream(r, l.bin)
+	if err != nil {
+		return err
+	}
+	gb := trace.NewGoroutineStatsBuilder()
+	ub := trace.NewMutatorUtilBuilder(reportMMUFlags)
+	var events []*trace.Event
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		gb.Add(chunk)
+		ub.Add(chunk)
+		events = append(events, chunk...)
+	}
+	l.res = trace.ParseResult{Events: events, Stacks: s.Stacks()}
+	l.gs, l.util = gb.Finish(), ub.Finish()
+	return nil
+}
+
+// window returns the events with timestamps in [from, to]. With an
+// index, only the events of the window, and the events they link to,
+// are read.
+func (l *traceLoader) window(from, to int64) ([]*trace.Event, error) {
+	if l.index != nil {
+		return l.index.window(from, to)
+	}
+	res, err := l.parse()
+	if err != nil {
+		return nil, err
+	}
+	events := res.Events
+	i := sort.Search(len(events), func(i int) bool { return events[i].Ts >= from })
+	j := sort.Search(len(events), func(i int) bool { return events[i].Ts > to })
+	if j < i {
+		j = i
+	}
+	return events[i:j], nil
+}
+
+// bounds returns the timestamps of the first and last events. With an
+// index, the events are not read for them.
+func (l *traceLoader) bounds() (first, last int64, err error) {
+	if l.index != nil {
+		if blocks := l.index.meta.Blocks; len(blocks) > 0 {
+			return blocks[0].Start, blocks[len(blocks)-1].End, nil
+		}
+		return 0, 0, nil
+	}
+	res, err := l.parse()
+	if n := len(res.Events); n > 0 {
+		first, last = res.Events[0].Ts, res.Events[n-1].Ts
+	}
+	return first, last, err
+}
+
+// scan passes the events to add in order, up to to at least. With an
+// index, unless they are parsed already, they are read a block at a
+// time, so that the events not kept by add, and those after to, are not
+// held in memory.
+func (l *traceLoader) scan(to int64, add func([]*trace.Event)) error {
+	if l.index != nil && !l.parsed.Load() {
+		return l.index.scan(to, add)
+	}
+	res, err := l.parse()
+	if err != nil {
+		return err
+	}
+	add(res.Events)
+	return nil
 }
 
 // httpMain serves the starting page.
 func httpMain(w http.ResponseWriter, r *http.Request) {
-	if err := templMain.Execute(w, ranges); err != nil {
+	st := requestTrace(r)
+	data := struct {
+		Ranges []Range
+		Base   string
+		Trace  string // in directory mode
+	}{Ranges: st.ranges, Base: *baseFlag}
+	if tracesDir != nil {
+		data.Trace = filepath.Base(st.loader.file)
+	}
+	if err := templMain.Execute(w, data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -186,21 +470,111 @@ func httpMain(w http.ResponseWriter, r *http.Request) {
 var templMain = template.Must(template.New("").Parse(`
 <html>
 <body>
-{{if $}}
-	{{range $e := $}}
+{{if $.Trace}}
+	<p>{{$.Trace}} (<a href="/traces">all traces</a>)</p>
+	<form action="/traces/capture" method="post">
+	Capture a trace from the net/http/pprof server at <input type="text" name="url" size="30" placeholder="http://localhost:6060">
+	for <input type="number" name="seconds" value="5" min="1" style="width: 4em"> seconds
+	<input type="submit" value="Capture">
+	</form>
+{{end}}
+{{if $.Ranges}}
+	{{range $e := $.Ranges}}
 		<a href="/trace?start={{$e.Start}}&end={{$e.End}}">View trace ({{$e.Name}})</a><br>
 	{{end}}
 	<br>
 {{else}}
 	<a href="/trace">View trace</a><br>
 {{end}}
-<a href="/goroutines">Goroutine analysis</a><br>
-<a href="/io">Network blocking profile</a> (<a href="/io?raw=1" download="io.profile">⬇</a>)<br>
-<a href="/block">Synchronization blocking profile</a> (<a href="/block?raw=1" download="block.profile">⬇</a>)<br>
-<a href="/syscall">Syscall blocking profile</a> (<a href="/syscall?raw=1" download="syscall.profile">⬇</a>)<br>
-<a href="/sched">Scheduler latency profile</a> (<a href="/sche?raw=1" download="sched.profile">⬇</a>)<br>
-<a href="/usertasks">User-defined tasks</a><br>
-<a href="/userregions">User-defined regions</a><br>
+<p>
+<svg id="timeline" width="800" height="60" style="border: 1px solid #ccc; cursor: crosshair"></svg><br>
+<span id="window">Drag over the timeline of the running (red) and runnable (blue) goroutines to restrict the analyses marked * to a time window.</span>
+</p>
+<a href="/goroutines" class="windowed">Goroutine analysis</a>*<br>
+<a href="/goroutinetree">Goroutine creation tree</a><br>
+<a href="/leaks" class="windowed">Possibly leaked goroutines</a>*<br>
+<a href="/wakeups" class="windowed">Goroutine wakeup graph</a>*<br>
+<a href="/io" class="windowed">Network blocking profile</a>* (<a href="/io?raw=1" class="windowed" download="io.profile">⬇</a>)<br>
+<a href="/block" class="windowed">Synchronization blocking profile</a>* (<a href="/block?raw=1" class="windowed" download="block.profile">⬇</a>)<br>
+<a href="/contention" class="windowed">Lock and channel contention</a>*<br>
+<a href="/syscall" class="windowed">Syscall blocking profile</a>* (<a href="/syscall?raw=1" class="windowed" download="syscall.profile">⬇</a>)<br>
+<a href="/sched" class="windowed">Scheduler latency profile</a>* (<a href="/sche?raw=1" class="windowed" download="sched.profile">⬇</a>)<br>
+<a href="/assist" class="windowed">GC mark assist profile</a>* (<a href="/assist?raw=1" class="windowed" download="assist.profile">⬇</a>)<br>
+<a href="/mmu" class="windowed">Minimum mutator utilization</a>*<br>
+<a href="/gc" class="windowed">GC cycles</a>*<br>
+<a href="/procs" class="windowed">Processors</a>*<br>
+<a href="/usertasks" class="windowed">User-defined tasks</a>*<br>
+<a href="/userregions" class="windowed">User-defined regions</a>*<br>
+<a href="/perfetto" download="trace.perfetto-trace">Perfetto trace</a> (open in <a href="https://ui.perfetto.dev">ui.perfetto.dev</a>)<br>
+<a href="/api/v1/">JSON API</a><br>
+{{if $.Base}}
+<br>
+<a href="/diff">Comparison with base trace {{$.Base}}</a><br>
+{{end}}
+<script>
+` + seriesChartScript + `
+// Select the window of the analyses by dragging over the timeline.
+var timeline, brushStart;
+var svg = document.getElementById('timeline');
+var windowed = document.querySelectorAll('a.windowed');
+windowed.forEach(function(a) { a.dataset.href = a.getAttribute('href'); });
+fetch('/timeline').then(function(r) { return r.json(); }).then(function(t) {
+  timeline = t;
+  drawSeries('timeline', [[t.Running, '#d7191c'], [t.Runnable, '#2c7bb6']]);
+});
+function svgX(e) {
+  var x = e.clientX - svg.getBoundingClientRect().left;
+  return Math.max(0, Math.min(x, svg.getAttribute('width')));
+}
+function drawBrush(x0, x1) {
+  var b = document.getElementById('brush');
+  if (!b) {
+    b = document.createElementNS('http://www.w3.org/2000/svg', 'rect');
+    b.setAttribute('id', 'brush');
+    b.setAttribute('y', 0);
+    b.setAttribute('height', svg.getAttribute('height'));
+    b.setAttribute('fill', '#888');
+    b.setAttribute('fill-opacity', 0.3);
+    svg.appendChild(b);
+  }
+  b.setAttribute('x', Math.min(x0, x1));
+  b.setAttribute('width', Math.abs(x1 - x0));
+}
+function setWindow(x0, x1) {
+  var span = document.getElementById('window');
+  var w = svg.getAttribute('width'), d = timeline.End - timeline.Start;
+  var from = Math.round(timeline.Start + Math.min(x0, x1) * d / w);
+  var to = Math.round(timeline.Start + Math.max(x0, x1) * d / w);
+  var all = Math.abs(x1 - x0) < 2;
+  windowed.forEach(function(a) {
+    var u = new URL(a.dataset.href, window.location.href);
+    if (!all) {
+      u.searchParams.set('from', from);
+      u.searchParams.set('to', to);
+    }
+    a.href = u.toString();
+  });
+  if (all) {
+    drawBrush(0, 0);
+    span.textContent = 'Analyses of the whole trace.';
+    return;
+  }
+  span.textContent = 'Analyses from ' + ((from - timeline.Start) / 1e6).toFixed(3) + 'ms to ' + ((to - timeline.Start) / 1e6).toFixed(3) + 'ms of the trace.';
+}
+svg.addEventListener('mousedown', function(e) {
+  if (!timeline) return;
+  brushStart = svgX(e);
+  drawBrush(brushStart, brushStart);
+});
+svg.addEventListener('mousemove', function(e) {
+  if (brushStart !== undefined) drawBrush(brushStart, svgX(e));
+});
+window.addEventListener('mouseup', function(e) {
+  if (brushStart === undefined) return;
+  setWindow(brushStart, svgX(e));
+  brushStart = undefined;
+});
+</script>
 </body>
 </html>
 `))
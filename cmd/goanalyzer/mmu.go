@@ -32,6 +32,7 @@ import (
 	"log"
 	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -59,34 +60,90 @@ type mmuCacheEntry struct {
 	err      error
 }
 
-var mmuCache struct {
-	m    map[trace.UtilFlags]*mmuCacheEntry
-	lock sync.Mutex
+func (st *traceState) getMMUCurve(r *http.Request) ([][]trace.MutatorUtil, *trace.MMUCurve, error) {
+	var flags trace.UtilFlags
+	for _, flagStr := range strings.Split(r.FormValue("flags"), "|") {
+		flags |= utilFlagNames[flagStr]
+	}
+	win, err := parseWindow(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return st.getMMUCurveWindow(flags, win)
 }
 
-func init() {
-	mmuCache.m = make(map[trace.UtilFlags]*mmuCacheEntry)
+// getMMUCurveWindow returns the mutator utilization and MMU curve for
+// flags during the window w. Those of a window are computed from the
+// cached utilization of the whole trace, or with an index from the
+// events up to the end of the window only, read with loader.scan.
+func (st *traceState) getMMUCurveWindow(flags trace.UtilFlags, w traceWindow) ([][]trace.MutatorUtil, *trace.MMUCurve, error) {
+	if w == wholeTrace {
+		return st.getMMUCurveFlags(flags)
+	}
+	var util [][]trace.MutatorUtil
+	if st.loader.index != nil {
+		b := trace.NewMutatorUtilBuilder(flags)
+		if err := st.loader.scan(w.to, b.Add); err != nil {
+			return nil, nil, err
+		}
+		util = b.Finish()
+	} else {
+		var err error
+		if util, _, err = st.getMMUCurveFlags(flags); err != nil {
+			return nil, nil, err
+		}
+	}
+	util = clipMutatorUtil(util, w)
+	if len(util) == 0 {
+		return nil, nil, fmt.Errorf("no mutator utilization in the window %s", st.formatWindow(w))
+	}
+	return util, trace.NewMMUCurve(util), nil
 }
 
-func getMMUCurve(r *http.Request) ([][]trace.MutatorUtil, *trace.MMUCurve, error) {
-	var flags trace.UtilFlags
-	for _, flagStr := range strings.Split(r.FormValue("flags"), "|") {
-		flags |= utilFlagNames[flagStr]
+// clipMutatorUtil returns the mutator utilization functions of util
+// restricted to the window w, leaving out those with no time in it.
+func clipMutatorUtil(util [][]trace.MutatorUtil, w traceWindow) [][]trace.MutatorUtil {
+	var res [][]trace.MutatorUtil
+	for _, u := range util {
+		i := sort.Search(len(u), func(i int) bool { return u[i].Time > w.from })
+		j := sort.Search(len(u), func(i int) bool { return u[i].Time > w.to })
+		var c []trace.MutatorUtil
+		if i > 0 {
+			// The utilization at the start of the window.
+			c = append(c, trace.MutatorUtil{Time: w.from, Util: u[i-1].Util})
+		}
+		c = append(c, u[i:j]...)
+		if j < len(u) && len(c) > 0 {
+			c = append(c, trace.MutatorUtil{Time: w.to, Util: c[len(c)-1].Util})
+		}
+		if len(c) > 1 {
+			res = append(res, c)
+		}
 	}
+	return res
+}
 
-	mmuCache.lock.Lock()
-	c := mmuCache.m[flags]
+// getMMUCurveFlags returns the cached mutator utilization and MMU curve
+// for flags, computing them on first use.
+func (st *traceState) getMMUCurveFlags(flags trace.UtilFlags) ([][]trace.MutatorUtil, *trace.MMUCurve, error) {
+	st.mmuLock.Lock()
+	c := st.mmuCache[flags]
 	if c == nil {
 		c = new(mmuCacheEntry)
-		mmuCache.m[flags] = c
+		st.mmuCache[flags] = c
 	}
-	mmuCache.lock.Unlock()
+	st.mmuLock.Unlock()
 
 	c.init.Do(func() {
-		events, err := parseEvents()
-		if err != nil {
+		events, err := st.parseEvents()
+		switch {
+		case err != nil:
 			c.err = err
-		} else {
+		case flags == reportMMUFlags && st.loader.util != nil:
+			// Computed with the events.
+			c.util = st.loader.util
+			c.mmuCurve = trace.NewMMUCurve(c.util)
+		default:
 			c.util = trace.MutatorUtilization(events, flags)
 			c.mmuCurve = trace.NewMMUCurve(c.util)
 		}
@@ -101,7 +158,8 @@ func httpMMU(w http.ResponseWriter, r *http.Request) {
 
 // httpMMUPlot serves the JSON data for the MMU plot.
 func httpMMUPlot(w http.ResponseWriter, r *http.Request) {
-	mu, mmuCurve, err := getMMUCurve(r)
+	st := requestTrace(r)
+	mu, mmuCurve, err := st.getMMUCurve(r)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to parse events: %v", err), http.StatusInternalServerError)
 		return
@@ -168,6 +226,7 @@ var templMMU = `<!doctype html>
     <meta charset="utf-8">
     <script type="text/javascript" src="https://www.gstatic.com/charts/loader.js"></script>
     <script type="text/javascript" src="https://ajax.googleapis.com/ajax/libs/jquery/3.2.1/jquery.min.js"></script>
+    <script type="text/javascript">` + windowScript + `</script>
     <script type="text/javascript">
       google.charts.load('current', {'packages':['corechart']});
       var chartsReady = false;
@@ -202,7 +261,7 @@ var templMMU = `<!doctype html>
         container.css('opacity', '.5');
         refreshChart.count++;
         var seq = refreshChart.count;
-        $.getJSON('/mmuPlot?flags=' + mmuFlags())
+        $.getJSON('/mmuPlot?flags=' + mmuFlags() + windowQuery())
          .fail(function(xhr, status, error) {
            alert('failed to load plot: ' + status);
          })
@@ -275,7 +334,7 @@ var templMMU = `<!doctype html>
         var details = $('#details');
         details.empty();
         var windowNS = curve[items[0].row][0];
-        var url = '/mmuDetails?window=' + windowNS + '&flags=' + mmuFlags();
+        var url = '/mmuDetails?window=' + windowNS + '&flags=' + mmuFlags() + windowQuery();
         $.getJSON(url)
          .fail(function(xhr, status, error) {
             details.text(status + ': ' + url + ' could not be loaded');
@@ -359,7 +418,8 @@ var templMMU = `<!doctype html>
 
 // httpMMUDetails serves details of an MMU graph at a particular window.
 func httpMMUDetails(w http.ResponseWriter, r *http.Request) {
-	_, mmuCurve, err := getMMUCurve(r)
+	st := requestTrace(r)
+	_, mmuCurve, err := st.getMMUCurve(r)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("failed to parse events: %v", err), http.StatusInternalServerError)
 		return
@@ -376,7 +436,7 @@ func httpMMUDetails(w http.ResponseWriter, r *http.Request) {
 	// Construct a link for each window.
 	var links []linkedUtilWindow
 	for _, ui := range worst {
-		links = append(links, newLinkedUtilWindow(ui, time.Duration(window)))
+		links = append(links, st.newLinkedUtilWindow(ui, time.Duration(window)))
 	}
 
 	err = json.NewEncoder(w).Encode(links)
@@ -391,13 +451,19 @@ type linkedUtilWindow struct {
 	URL string
 }
 
-func newLinkedUtilWindow(ui trace.UtilWindow, window time.Duration) linkedUtilWindow {
+func (st *traceState) newLinkedUtilWindow(ui trace.UtilWindow, window time.Duration) linkedUtilWindow {
+	return linkedUtilWindow{ui, st.viewerURL(ui.Time, ui.Time+int64(window))}
+}
+
+// viewerURL returns the URL of the trace viewer showing the time from
+// start to end, in the range containing start.
+func (st *traceState) viewerURL(start, end int64) string {
 	// Find the range containing this window.
 	var r Range
-	for _, r = range ranges {
-		if r.EndTime > ui.Time {
+	for _, r = range st.ranges {
+		if r.EndTime > start {
 			break
 		}
 	}
-	return linkedUtilWindow{ui, fmt.Sprintf("%s#%v:%v", r.URL(), float64(ui.Time)/1e6, float64(ui.Time+int64(window))/1e6)}
+	return fmt.Sprintf("%s#%v:%v", r.URL(), float64(start)/1e6, float64(end)/1e6)
 }
@@ -0,0 +1,597 @@
+// Export of the whole trace in Perfetto's protobuf TrackEvent format,
+// for ui.perfetto.dev and trace_processor. Unlike the JSON served to
+// the trace viewer, the export is never split into ranges.
+//
+// The format is described at
+// https://perfetto.dev/docs/reference/synthetic-track-event
+// and the messages at
+// https://github.com/google/perfetto/tree/main/protos/perfetto/trace
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+)
+
+func init() {
+	http.HandleFunc("/perfetto", httpPerfetto)
+}
+
+// httpPerfetto serves the whole trace in Perfetto's format as a download.
+func httpPerfetto(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace.perfetto-trace"`)
+	if err := st.writePerfettoTrace(w); err != nil {
+		// The header is gone, so just log the error.
+		log.Printf("failed to write perfetto trace: %v", err)
+	}
+}
+
+// writePerfettoTrace writes the trace to w in Perfetto's format. It has
+// a track per P and per goroutine, the GC, network, timer and syscall
+// tracks, counters for the heap, goroutines and threads, flows for the
+// arrows of the trace viewer, and slices for user tasks and regions.
+// Stacks are not exported. The packets are written as they are made,
+// in a single pass over the trace.
+func (st *traceState) writePerfettoTrace(w io.Writer) error {
+	res, err := st.parseTrace()
+	if err != nil {
+		return err
+	}
+	gs, err := st.profileGoroutines()
+	if err != nil {
+		return err
+	}
+	annotations, err := st.analyzeAnnotations()
+	if err != nil {
+		return err
+	}
+	pw := newPerfettoWriter(w)
+
+	// The P-oriented view of the trace viewer, with the slices and
+	// instants of goroutines also on the tracks of the goroutine-oriented
+	// view.
+	params := &traceParams{parsed: res, endTime: math.MaxInt64}
+	if err := generateTrace(params, pw.consumer(gs)); err != nil {
+		return err
+	}
+	pw.addTasks(annotations.tasks)
+	pw.addRegions(annotations.regions)
+	return pw.flush()
+}
+
+// Field numbers and enum values of the Perfetto protos.
+const (
+	// Trace
+	pfTracePacket = 1
+
+	// TracePacket
+	pfPacketTimestamp       = 8
+	pfPacketSequenceID      = 10
+	pfPacketTrackEvent      = 11
+	pfPacketInternedData    = 12
+	pfPacketSequenceFlags   = 13
+	pfPacketTrackDescriptor = 60
+
+	pfSeqIncrementalStateCleared = 1
+	pfSeqNeedsIncrementalState   = 2
+
+	// InternedData, and its EventCategory and EventName entries
+	pfInternedCategory  = 1
+	pfInternedEventName = 2
+	pfInternedIID       = 1
+	pfInternedName      = 2
+
+	// TrackDescriptor
+	pfTrackUUID          = 1
+	pfTrackName          = 2
+	pfTrackParentUUID    = 5
+	pfTrackCounter       = 8
+	pfTrackChildOrdering = 11
+	pfTrackSiblingRank   = 12
+
+	pfChildOrderingExplicit = 3
+
+	// CounterDescriptor
+	pfCounterUnit = 3
+
+	pfUnitCount     = 2
+	pfUnitSizeBytes = 3
+
+	// TrackEvent
+	pfEventCategoryIID     = 3
+	pfEventDebugAnnotation = 4
+	pfEventType            = 9
+	pfEventNameIID         = 10
+	pfEventTrackUUID       = 11
+	pfEventCounterValue    = 30
+	pfEventFlowID          = 47
+	pfEventTerminatingFlow = 48
+
+	pfTypeSliceBegin = 1
+	pfTypeSliceEnd   = 2
+	pfTypeInstant    = 3
+	pfTypeCounter    = 4
+
+	// DebugAnnotation
+	pfAnnotationUint = 3
+	pfAnnotationName = 10
+)
+
+// pfSequenceID is the packet sequence of all packets; any non-zero
+// value works for a trace written by a single producer.
+const pfSequenceID = 1
+
+// protoBuf is a protobuf message being encoded.
+type protoBuf []byte
+
+func (b *protoBuf) key(field, wireType int) {
+	b.uvarint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (b *protoBuf) uvarint(v uint64) {
+	for v >= 0x80 {
+		*b = append(*b, byte(v)|0x80)
+		v >>= 7
+	}
+	*b = append(*b, byte(v))
+}
+
+func (b *protoBuf) varint(field int, v uint64) {
+	b.key(field, 0)
+	b.uvarint(v)
+}
+
+func (b *protoBuf) fixed64(field int, v uint64) {
+	b.key(field, 1)
+	for i := 0; i < 8; i++ {
+		*b = append(*b, byte(v>>(8*i)))
+	}
+}
+
+func (b *protoBuf) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.uvarint(uint64(len(data)))
+	*b = append(*b, data...)
+}
+
+func (b *protoBuf) string(field int, s string) {
+	b.bytes(field, []byte(s))
+}
+
+// perfettoWriter writes the tracks and events of a Perfetto trace as
+// they are added. The descriptor of a track is written before its first
+// event, so the name and rank of a track must be set before then. Event
+// names and categories are interned.
+type perfettoWriter struct {
+	w        *bufio.Writer
+	err      error // of the first failed write
+	started  bool  // whether a packet was written
+	byKey    map[perfettoTrackKey]*perfettoTrack
+	nextUUID uint64
+
+	// Interning ids of event names and categories.
+	names, categories map[string]uint64
+}
+
+// perfettoTrackKey identifies a track by its group and the row of the
+// trace viewer it shows, or by its group and name.
+type perfettoTrackKey struct {
+	group string
+	tid   uint64
+	name  string
+}
+
+type perfettoTrack struct {
+	uuid      uint64
+	parent    *perfettoTrack
+	name      string
+	rank      int32
+	unit      uint64 // counter unit, 0 for slice tracks
+	nchild    int    // number of child tracks
+	described bool   // whether the descriptor is written
+
+	// Slices must nest on a track, so a slice overlapping the end of
+	// an open one goes to another lane: lanes[0] is the track itself.
+	lanes []*perfettoTrack
+	open  []int64 // end times of the open slices, innermost last
+}
+
+func newPerfettoWriter(w io.Writer) *perfettoWriter {
+	return &perfettoWriter{
+		w:          bufio.NewWriter(w),
+		byKey:      make(map[perfettoTrackKey]*perfettoTrack),
+		names:      make(map[string]uint64),
+		categories: make(map[string]uint64),
+	}
+}
+
+// track returns the track for key, creating it with name and parent.
+func (pw *perfettoWriter) track(key perfettoTrackKey, name string, parent *perfettoTrack) *perfettoTrack {
+	if t := pw.byKey[key]; t != nil {
+		return t
+	}
+	t := pw.newTrack(name, parent)
+	pw.byKey[key] = t
+	return t
+}
+
+func (pw *perfettoWriter) newTrack(name string, parent *perfettoTrack) *perfettoTrack {
+	pw.nextUUID++
+	t := &perfettoTrack{uuid: pw.nextUUID, parent: parent, name: name}
+	t.lanes = []*perfettoTrack{t}
+	if parent != nil {
+		// Tracks are ordered as created unless ranked otherwise.
+		t.rank = int32(parent.nchild)
+		parent.nchild++
+	}
+	return t
+}
+
+// lane returns the lane of t on which a slice from start to end
+// nests within the open slices, and opens the slice on it.
+func (pw *perfettoWriter) lane(t *perfettoTrack, start, end int64) *perfettoTrack {
+	for _, l := range t.lanes {
+		for len(l.open) > 0 && l.open[len(l.open)-1] <= start {
+			l.open = l.open[:len(l.open)-1]
+		}
+		if len(l.open) == 0 || l.open[len(l.open)-1] >= end {
+			l.open = append(l.open, end)
+			return l
+		}
+	}
+	l := pw.newTrack(fmt.Sprintf("%s (%d)", t.name, len(t.lanes)+1), t.parent)
+	l.rank = t.rank
+	l.lanes = nil
+	l.open = []int64{end}
+	t.lanes = append(t.lanes, l)
+	return l
+}
+
+// describe writes the descriptors of t and its parents unless they are
+// written.
+func (pw *perfettoWriter) describe(t *perfettoTrack) {
+	if t.described {
+		return
+	}
+	t.described = true
+	var td protoBuf
+	td.varint(pfTrackUUID, t.uuid)
+	td.string(pfTrackName, t.name)
+	if t.parent != nil {
+		pw.describe(t.parent)
+		td.varint(pfTrackParentUUID, t.parent.uuid)
+		td.varint(pfTrackSiblingRank, uint64(int64(t.rank)))
+	} else {
+		// The groups, whose children are ranked.
+		td.varint(pfTrackChildOrdering, pfChildOrderingExplicit)
+	}
+	if t.unit != 0 {
+		var cd protoBuf
+		cd.varint(pfCounterUnit, t.unit)
+		td.bytes(pfTrackCounter, cd)
+	}
+	var p protoBuf
+	p.varint(pfPacketSequenceID, pfSequenceID)
+	if !pw.started {
+		p.varint(pfPacketSequenceFlags, pfSeqIncrementalStateCleared)
+	}
+	p.bytes(pfPacketTrackDescriptor, td)
+	pw.writePacket(p)
+}
+
+// perfettoEvent is the part of a TrackEvent that varies.
+type perfettoEvent struct {
+	typ         uint64
+	name        string
+	category    string
+	counter     int64
+	flows       []uint64
+	terminating []uint64
+	args        []perfettoArg
+}
+
+type perfettoArg struct {
+	name  string
+	value uint64
+}
+
+func (pw *perfettoWriter) emit(ts int64, t *perfettoTrack, e perfettoEvent) {
+	pw.describe(t)
+	var ev, interned protoBuf
+	ev.varint(pfEventType, e.typ)
+	ev.varint(pfEventTrackUUID, t.uuid)
+	if e.name != "" {
+		ev.varint(pfEventNameIID, pw.intern(&interned, pfInternedEventName, pw.names, e.name))
+	}
+	if e.category != "" {
+		ev.varint(pfEventCategoryIID, pw.intern(&interned, pfInternedCategory, pw.categories, e.category))
+	}
+	if e.typ == pfTypeCounter {
+		ev.varint(pfEventCounterValue, uint64(e.counter))
+	}
+	for _, id := range e.flows {
+		ev.fixed64(pfEventFlowID, id)
+	}
+	for _, id := range e.terminating {
+		ev.fixed64(pfEventTerminatingFlow, id)
+	}
+	for _, a := range e.args {
+		var arg protoBuf
+		arg.string(pfAnnotationName, a.name)
+		arg.varint(pfAnnotationUint, a.value)
+		ev.bytes(pfEventDebugAnnotation, arg)
+	}
+
+	var p protoBuf
+	p.varint(pfPacketTimestamp, uint64(ts))
+	p.varint(pfPacketSequenceID, pfSequenceID)
+	p.varint(pfPacketSequenceFlags, pfSeqNeedsIncrementalState)
+	if len(interned) > 0 {
+		p.bytes(pfPacketInternedData, interned)
+	}
+	p.bytes(pfPacketTrackEvent, ev)
+	pw.writePacket(p)
+}
+
+// intern returns the interning id of s in ids, adding s to the
+// interned data of the packet in field if it is new.
+func (pw *perfettoWriter) intern(interned *protoBuf, field int, ids map[string]uint64, s string) uint64 {
+	if iid, ok := ids[s]; ok {
+		return iid
+	}
+	iid := uint64(len(ids) + 1)
+	ids[s] = iid
+	var entry protoBuf
+	entry.varint(pfInternedIID, iid)
+	entry.string(pfInternedName, s)
+	interned.bytes(field, entry)
+	return iid
+}
+
+// slice emits a slice from start to end on a lane of t.
+func (pw *perfettoWriter) slice(t *perfettoTrack, start, end int64, begin perfettoEvent) {
+	if end < start {
+		end = start
+	}
+	l := pw.lane(t, start, end)
+	begin.typ = pfTypeSliceBegin
+	pw.emit(start, l, begin)
+	pw.emit(end, l, perfettoEvent{typ: pfTypeSliceEnd})
+}
+
+func (pw *perfettoWriter) writePacket(p protoBuf) {
+	if pw.err != nil {
+		return
+	}
+	var b protoBuf
+	b.bytes(pfTracePacket, p)
+	_, pw.err = pw.w.Write(b)
+	pw.started = true
+}
+
+// flush writes the buffered packets, and returns the error of the first
+// failed write.
+func (pw *perfettoWriter) flush() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+// perfettoRows are the rows of the P-oriented view other than the Ps,
+// which generateTrace only names at the end.
+var perfettoRows = []struct {
+	tid  uint64
+	name string
+	rank int32
+}{
+	{trace.GCP, "GC", -6},
+	{trace.NetpollP, "Network", -5},
+	{trace.TimerP, "Timers", -4},
+	{trace.SyscallP, "Syscalls", -3},
+}
+
+// consumer returns a traceConsumer adding the events of a P-oriented
+// generateTrace pass to the trace. The rows of the trace viewer are
+// tracks in the Procs group, and the slices, instants and flows of
+// goroutines are also on a track per goroutine in the Goroutines group,
+// named after gs, as in the goroutine-oriented view. The events of no
+// goroutine, like those of the Ps themselves, are only on the P tracks.
+func (pw *perfettoWriter) consumer(gs map[uint64]*trace.GDesc) traceConsumer {
+	procs := pw.track(perfettoTrackKey{name: "Procs"}, "Procs", nil)
+	goroutines := pw.track(perfettoTrackKey{name: "Goroutines"}, "Goroutines", nil)
+	procRow := func(tid uint64) *perfettoTrack {
+		key := perfettoTrackKey{group: "Procs", tid: tid}
+		if t := pw.byKey[key]; t != nil {
+			return t
+		}
+		t := pw.track(key, fmt.Sprintf("Proc %d", tid), procs)
+		if tid <= math.MaxInt32 {
+			t.rank = int32(tid)
+		}
+		return t
+	}
+	// The trace viewer has these rows even without events.
+	for _, r := range perfettoRows {
+		t := pw.track(perfettoTrackKey{group: "Procs", tid: r.tid}, r.name, procs)
+		t.rank = r.rank
+		pw.describe(t)
+	}
+	gRow := func(g uint64) *perfettoTrack {
+		key := perfettoTrackKey{group: "Goroutines", tid: g}
+		if t := pw.byKey[key]; t != nil {
+			return t
+		}
+		name := fmt.Sprintf("G%d", g)
+		if gd := gs[g]; gd != nil && gd.Name != "" {
+			name += " " + gd.Name
+		}
+		t := pw.track(key, name, goroutines)
+		if g <= math.MaxInt32 {
+			t.rank = int32(g)
+		}
+		return t
+	}
+	// onG reports whether v is also on the track of its goroutine.
+	onG := func(v *ViewerEvent) bool {
+		return v.Tid < trace.FakeP && v.G != 0
+	}
+	// The flows of the goroutine tracks have other ids than those of
+	// the P tracks.
+	const gFlows = 1 << 56
+	nanos := func(usec float64) int64 {
+		return int64(math.Round(usec * 1000))
+	}
+	var flowStart *ViewerEvent // of the arrow whose end comes next
+
+	return traceConsumer{
+		consumeTimeUnit: func(unit string) {},
+		consumeViewerEvent: func(v *ViewerEvent, required bool) {
+			switch v.Phase {
+			case "X":
+				start := nanos(v.Time)
+				e := perfettoEvent{name: v.Name, category: v.Category}
+				pw.slice(procRow(v.Tid), start, start+nanos(v.Dur), e)
+				if onG(v) {
+					pw.slice(gRow(v.G), start, start+nanos(v.Dur), e)
+				}
+			case "I":
+				// An instant nests within any open slice of the track.
+				e := perfettoEvent{typ: pfTypeInstant, name: v.Name, category: v.Category}
+				pw.emit(nanos(v.Time), procRow(v.Tid), e)
+				if onG(v) {
+					pw.emit(nanos(v.Time), gRow(v.G), e)
+				}
+			case "s":
+				flowStart = v
+			case "t":
+				// Flows connect events, so mark both ends with an instant.
+				// An arrow is on the goroutine tracks if both its ends are.
+				s := flowStart
+				if s == nil || s.ID != v.ID {
+					return
+				}
+				flowStart = nil
+				begin := perfettoEvent{typ: pfTypeInstant, name: s.Name, category: "flow", flows: []uint64{v.ID}}
+				end := perfettoEvent{typ: pfTypeInstant, name: v.Name, category: "flow", terminating: []uint64{v.ID}}
+				pw.emit(nanos(s.Time), procRow(s.Tid), begin)
+				pw.emit(nanos(v.Time), procRow(v.Tid), end)
+				if onG(s) && onG(v) {
+					begin.flows = []uint64{gFlows | v.ID}
+					end.terminating = []uint64{gFlows | v.ID}
+					pw.emit(nanos(s.Time), gRow(s.G), begin)
+					pw.emit(nanos(v.Time), gRow(v.G), end)
+				}
+			case "C":
+				pw.counters(v)
+			}
+		},
+		consumeViewerFrame: func(key string, f ViewerFrame) {},
+		flush:              func() {},
+	}
+}
+
+// counters emits a counter track per field of a counter event.
+func (pw *perfettoWriter) counters(v *ViewerEvent) {
+	type field struct {
+		name  string
+		value int64
+		unit  uint64
+	}
+	var fields []field
+	switch arg := v.Arg.(type) {
+	case *heapCountersArg:
+		fields = []field{
+			{"Allocated", int64(arg.Allocated), pfUnitSizeBytes},
+			{"NextGC", int64(arg.NextGC), pfUnitSizeBytes},
+		}
+	case *goroutineCountersArg:
+		fields = []field{
+			{"Running", int64(arg.Running), pfUnitCount},
+			{"Runnable", int64(arg.Runnable), pfUnitCount},
+			{"GCWaiting", int64(arg.GCWaiting), pfUnitCount},
+		}
+	case *threadCountersArg:
+		fields = []field{
+			{"Running", arg.Running, pfUnitCount},
+			{"InSyscall", arg.InSyscall, pfUnitCount},
+		}
+	default:
+		return
+	}
+	group := pw.track(perfettoTrackKey{name: "Stats"}, "Stats", nil)
+	for _, f := range fields {
+		name := v.Name + " " + f.name
+		t := pw.track(perfettoTrackKey{group: "Stats", name: name}, name, group)
+		t.unit = f.unit
+		pw.emit(int64(math.Round(v.Time*1000)), t, perfettoEvent{typ: pfTypeCounter, counter: f.value})
+	}
+}
+
+// addTasks adds a track per task type, with a slice per task and flows
+// from parent to child tasks.
+func (pw *perfettoWriter) addTasks(tasks allTasks) {
+	group := pw.track(perfettoTrackKey{name: "Tasks"}, "Tasks", nil)
+	sorted := make([]*taskDesc, 0, len(tasks))
+	for _, task := range tasks {
+		sorted = append(sorted, task)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		ti, tj := sorted[i], sorted[j]
+		if ti.firstTimestamp() != tj.firstTimestamp() {
+			return ti.firstTimestamp() < tj.firstTimestamp()
+		}
+		return ti.id < tj.id
+	})
+	// Parent and child tasks are linked by a flow with the child's id.
+	const taskFlows = 1 << 62
+	for _, task := range sorted {
+		t := pw.track(perfettoTrackKey{group: "Tasks", name: task.name}, task.name, group)
+		e := perfettoEvent{name: task.name, category: "task", args: []perfettoArg{{"id", task.id}}}
+		for _, child := range task.children {
+			e.flows = append(e.flows, taskFlows|child.id)
+		}
+		if task.parent != nil {
+			e.terminating = []uint64{taskFlows | task.id}
+		}
+		pw.slice(t, task.firstTimestamp(), task.lastTimestamp(), e)
+	}
+}
+
+// addRegions adds a track per goroutine with user regions, with a slice
+// per region.
+func (pw *perfettoWriter) addRegions(regions map[regionTypeID][]regionDesc) {
+	group := pw.track(perfettoTrackKey{name: "Regions"}, "Regions", nil)
+	var all []regionDesc
+	for _, rs := range regions {
+		for _, r := range rs {
+			if r.Name != "" {
+				all = append(all, r)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		si, sj := all[i].firstTimestamp(), all[j].firstTimestamp()
+		if si != sj {
+			return si < sj
+		}
+		// Enclosing regions first.
+		return all[i].lastTimestamp() > all[j].lastTimestamp()
+	})
+	for _, r := range all {
+		t := pw.track(perfettoTrackKey{group: "Regions", tid: r.G}, fmt.Sprintf("G%d", r.G), group)
+		if r.G <= math.MaxInt32 {
+			t.rank = int32(r.G)
+		}
+		pw.slice(t, r.firstTimestamp(), r.lastTimestamp(), perfettoEvent{name: r.Name, category: "region", args: []perfettoArg{{"taskid", r.TaskID}}})
+	}
+}
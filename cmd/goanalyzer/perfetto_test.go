@@ -0,0 +1,221 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// protoFields decodes the fields of a protobuf message. Varint and
+// fixed64 values are returned as uint64, and messages and strings as
+// []byte.
+func protoFields(b []byte) (map[int][]interface{}, error) {
+	fields := make(map[int][]interface{})
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("bad key")
+		}
+		b = b[n:]
+		field := int(key >> 3)
+		switch key & 7 {
+		case 0:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("bad varint in field %d", field)
+			}
+			fields[field] = append(fields[field], v)
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("short fixed64 in field %d", field)
+			}
+			fields[field] = append(fields[field], binary.LittleEndian.Uint64(b))
+			b = b[8:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, fmt.Errorf("bad length in field %d", field)
+			}
+			fields[field] = append(fields[field], b[n:n+int(l)])
+			b = b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d in field %d", key&7, field)
+		}
+	}
+	return fields, nil
+}
+
+func TestPerfetto(t *testing.T) {
+	if err := traceProgram(t, prog0, "TestPerfetto"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := mainTrace.writePerfettoTrace(&buf); err != nil {
+		t.Fatalf("failed to write perfetto trace: %v", err)
+	}
+	tr, err := protoFields(buf.Bytes())
+	if err != nil {
+		t.Fatalf("failed to decode trace: %v", err)
+	}
+
+	type event struct {
+		ts, typ uint64
+	}
+	names := make(map[uint64]string) // by track uuid
+	parents := make(map[uint64]uint64)
+	events := make(map[uint64][]event) // by track uuid
+	flows := make(map[uint64]bool)
+	var terminating []uint64
+	eventNames := make(map[uint64]string) // by interning id
+	for i, p := range tr[pfTracePacket] {
+		packet, err := protoFields(p.([]byte))
+		if err != nil {
+			t.Fatalf("failed to decode packet %d: %v", i, err)
+		}
+		if seq := packet[pfPacketSequenceID]; len(seq) != 1 || seq[0].(uint64) != pfSequenceID {
+			t.Fatalf("packet %d has sequence id %v", i, seq)
+		}
+		if td := packet[pfPacketTrackDescriptor]; td != nil {
+			desc, err := protoFields(td[0].([]byte))
+			if err != nil {
+				t.Fatalf("failed to decode track descriptor: %v", err)
+			}
+			uuid := desc[pfTrackUUID][0].(uint64)
+			names[uuid] = string(desc[pfTrackName][0].([]byte))
+			if parent := desc[pfTrackParentUUID]; parent != nil {
+				parents[uuid] = parent[0].(uint64)
+			}
+			continue
+		}
+		for _, data := range packet[pfPacketInternedData] {
+			interned, err := protoFields(data.([]byte))
+			if err != nil {
+				t.Fatalf("failed to decode interned data: %v", err)
+			}
+			for _, entry := range interned[pfInternedEventName] {
+				name, err := protoFields(entry.([]byte))
+				if err != nil {
+					t.Fatalf("failed to decode event name: %v", err)
+				}
+				eventNames[name[pfInternedIID][0].(uint64)] = string(name[pfInternedName][0].([]byte))
+			}
+		}
+		te, err := protoFields(packet[pfPacketTrackEvent][0].([]byte))
+		if err != nil {
+			t.Fatalf("failed to decode track event: %v", err)
+		}
+		for _, iid := range te[pfEventNameIID] {
+			if _, ok := eventNames[iid.(uint64)]; !ok {
+				t.Fatalf("event name %d used before it is interned", iid)
+			}
+		}
+		uuid := te[pfEventTrackUUID][0].(uint64)
+		if _, ok := names[uuid]; !ok {
+			t.Fatalf("event on track %d before its descriptor", uuid)
+		}
+		events[uuid] = append(events[uuid], event{packet[pfPacketTimestamp][0].(uint64), te[pfEventType][0].(uint64)})
+		for _, id := range te[pfEventFlowID] {
+			flows[id.(uint64)] = true
+		}
+		for _, id := range te[pfEventTerminatingFlow] {
+			terminating = append(terminating, id.(uint64))
+		}
+	}
+
+	path := func(uuid uint64) string {
+		s := names[uuid]
+		for p, ok := parents[uuid]; ok; p, ok = parents[p] {
+			s = names[p] + "/" + s
+		}
+		return s
+	}
+	found := make(map[string]bool)
+	for uuid := range names {
+		found[path(uuid)] = true
+	}
+	for _, want := range []string{"Procs/Proc 0", "Procs/GC", "Stats/Heap Allocated", "Stats/Goroutines Running", "Tasks/task0", "Tasks/task1"} {
+		if !found[want] {
+			t.Errorf("no track %s in %v", want, found)
+		}
+	}
+	var goroutines, regions int
+	for uuid := range names {
+		switch names[parents[uuid]] {
+		case "Goroutines":
+			goroutines++
+			if !strings.HasPrefix(names[uuid], "G") {
+				t.Errorf("goroutine track %s is not named after its goroutine", names[uuid])
+			}
+		case "Regions":
+			regions++
+		}
+	}
+	if goroutines == 0 || regions == 0 {
+		t.Errorf("found %d goroutine and %d region tracks, want some", goroutines, regions)
+	}
+	var regionSlice bool
+	for _, name := range eventNames {
+		regionSlice = regionSlice || name == "task0.region1"
+	}
+	if !regionSlice {
+		t.Errorf("no task0.region1 slice in %v", eventNames)
+	}
+
+	// Slices must nest on every track, as trace_processor sorts
+	// events by timestamp, keeping the order of equal ones.
+	for uuid, evs := range events {
+		sort.SliceStable(evs, func(i, j int) bool { return evs[i].ts < evs[j].ts })
+		depth := 0
+		for _, ev := range evs {
+			switch ev.typ {
+			case pfTypeSliceBegin:
+				depth++
+			case pfTypeSliceEnd:
+				depth--
+			}
+			if depth < 0 {
+				t.Fatalf("track %s: slice end at %d without a begin", path(uuid), ev.ts)
+			}
+		}
+		if depth != 0 {
+			t.Errorf("track %s: %d slices do not end", path(uuid), depth)
+		}
+	}
+	if len(terminating) == 0 {
+		t.Errorf("no flows")
+	}
+	for _, id := range terminating {
+		if !flows[id] {
+			t.Errorf("flow %x ends but does not start", id)
+		}
+	}
+
+	// The errors of the writes are returned.
+	if err := mainTrace.writePerfettoTrace(&failingWriter{n: buf.Len() / 2}); err != errFailingWriter {
+		t.Errorf("writing to a failing writer: got %v, want %v", err, errFailingWriter)
+	}
+}
+
+var errFailingWriter = errors.New("write failed")
+
+// failingWriter fails the writes after the first n bytes.
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errFailingWriter
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
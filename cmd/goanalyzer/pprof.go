@@ -37,15 +37,16 @@ func goCmd() string {
 }
 
 func init() {
-	http.HandleFunc("/io", serveSVGProfile(pprofByGoroutine(computePprofIO)))
-	http.HandleFunc("/block", serveSVGProfile(pprofByGoroutine(computePprofBlock)))
-	http.HandleFunc("/syscall", serveSVGProfile(pprofByGoroutine(computePprofSyscall)))
-	http.HandleFunc("/sched", serveSVGProfile(pprofByGoroutine(computePprofSched)))
-
-	http.HandleFunc("/regionio", serveSVGProfile(pprofByRegion(computePprofIO)))
-	http.HandleFunc("/regionblock", serveSVGProfile(pprofByRegion(computePprofBlock)))
-	http.HandleFunc("/regionsyscall", serveSVGProfile(pprofByRegion(computePprofSyscall)))
-	http.HandleFunc("/regionsched", serveSVGProfile(pprofByRegion(computePprofSched)))
+	http.HandleFunc("/io", serveProfile("Network blocking profile", pprofByGoroutine(pprofIOEvent)))
+	http.HandleFunc("/block", serveProfile("Synchronization blocking profile", pprofByGoroutine(pprofBlockEvent)))
+	http.HandleFunc("/syscall", serveProfile("Syscall blocking profile", pprofByGoroutine(pprofSyscallEvent)))
+	http.HandleFunc("/sched", serveProfile("Scheduler latency profile", pprofByGoroutine(pprofSchedEvent)))
+	http.HandleFunc("/assist", serveProfile("GC mark assist profile", pprofByGoroutine(pprofAssistEvent)))
+
+	http.HandleFunc("/regionio", serveProfile("Network blocking profile", pprofByRegion(computePprofIO)))
+	http.HandleFunc("/regionblock", serveProfile("Synchronization blocking profile", pprofByRegion(computePprofBlock)))
+	http.HandleFunc("/regionsyscall", serveProfile("Syscall blocking profile", pprofByRegion(computePprofSyscall)))
+	http.HandleFunc("/regionsched", serveProfile("Scheduler latency profile", pprofByRegion(computePprofSched)))
 }
 
 // Record represents one entry in pprof-like profiles.
@@ -60,41 +61,60 @@ type interval struct {
 	begin, end int64 // nanoseconds.
 }
 
-func pprofByGoroutine(compute func(io.Writer, map[uint64][]interval, []*trace.Event) error) func(w io.Writer, r *http.Request) error {
-	return func(w io.Writer, r *http.Request) error {
-		id := r.FormValue("id")
-		events, err := parseEvents()
+// profileFunc computes a pprof-like profile for a request.
+type profileFunc func(r *http.Request) (*profile.Profile, error)
+
+// profileCompute computes a pprof-like profile of the events of the
+// goroutines in gToIntervals, during their intervals.
+type profileCompute func(gToIntervals map[uint64][]interval, events []*trace.Event) *profile.Profile
+
+// pprofByGoroutine returns the profile of the events matching match of
+// the goroutine group of the id parameter, or of every goroutine, read
+// with scanPprof.
+func pprofByGoroutine(match func(*trace.Event) bool) profileFunc {
+	return func(r *http.Request) (*profile.Profile, error) {
+		st := requestTrace(r)
+		goroutines, err := st.profileGoroutines()
 		if err != nil {
-			return err
+			return nil, err
 		}
-		gToIntervals, err := pprofMatchingGoroutines(id, events)
+		gToIntervals, err := st.pprofMatchingGoroutines(r.FormValue("id"), goroutines)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		return compute(w, gToIntervals, events)
+		win, err := parseWindow(r)
+		if err != nil {
+			return nil, err
+		}
+		return st.scanPprof(win.clip(gToIntervals, goroutines), win.to, match)
 	}
 }
 
-func pprofByRegion(compute func(io.Writer, map[uint64][]interval, []*trace.Event) error) func(w io.Writer, r *http.Request) error {
-	return func(w io.Writer, r *http.Request) error {
+func pprofByRegion(compute profileCompute) profileFunc {
+	return func(r *http.Request) (*profile.Profile, error) {
+		st := requestTrace(r)
 		filter, err := newRegionFilter(r)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		gToIntervals, err := pprofMatchingRegions(filter)
+		gToIntervals, err := st.pprofMatchingRegions(filter)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		events, _ := parseEvents()
-
-		return compute(w, gToIntervals, events)
+		events, _ := st.parseEvents()
+		win, err := parseWindow(r)
+		if err != nil {
+			return nil, err
+		}
+		st.analyzeGoroutines(events)
+		return compute(win.clip(gToIntervals, st.gs), events), nil
 	}
 }
 
 // pprofMatchingGoroutines parses the goroutine type id string (i.e. pc)
-// and returns the ids of goroutines of the matching type and its interval.
-// If the id string is empty, returns nil without an error.
-func pprofMatchingGoroutines(id string, events []*trace.Event) (map[uint64][]interval, error) {
+// and returns the ids of goroutines of gs of the matching type and its
+// interval. If the id string is empty, returns nil without an error.
+func (st *traceState) pprofMatchingGoroutines(id string, gs map[uint64]*trace.GDesc) (map[uint64][]interval, error) {
 	if id == "" {
 		return nil, nil
 	}
@@ -102,7 +122,6 @@ func pprofMatchingGoroutines(id string, events []*trace.Event) (map[uint64][]int
 	if err != nil {
 		return nil, fmt.Errorf("invalid goroutine type: %v", id)
 	}
-	analyzeGoroutines(events)
 	var res map[uint64][]interval
 	for _, g := range gs {
 		if g.PC != pc {
@@ -113,7 +132,7 @@ func pprofMatchingGoroutines(id string, events []*trace.Event) (map[uint64][]int
 		}
 		endTime := g.EndTime
 		if g.EndTime == 0 {
-			endTime = lastTimestamp() // the trace doesn't include the goroutine end event. Use the trace end time.
+			endTime = st.lastTimestamp() // the trace doesn't include the goroutine end event. Use the trace end time.
 		}
 		res[g.ID] = []interval{{begin: g.StartTime, end: endTime}}
 	}
@@ -125,8 +144,8 @@ func pprofMatchingGoroutines(id string, events []*trace.Event) (map[uint64][]int
 
 // pprofMatchingRegions returns the time intervals of matching regions
 // grouped by the goroutine id. If the filter is nil, returns nil without an error.
-func pprofMatchingRegions(filter *regionFilter) (map[uint64][]interval, error) {
-	res, err := analyzeAnnotations()
+func (st *traceState) pprofMatchingRegions(filter *regionFilter) (map[uint64][]interval, error) {
+	res, err := st.analyzeAnnotations()
 	if err != nil {
 		return nil, err
 	}
@@ -171,91 +190,170 @@ func pprofMatchingRegions(filter *regionFilter) (map[uint64][]interval, error) {
 	return gToIntervals, nil
 }
 
+// pprofEvents are the events of the pprof-like profiles of -pprof, by
+// profile name.
+var pprofEvents = map[string]func(ev *trace.Event) bool{
+	"net":     pprofIOEvent,
+	"sync":    pprofBlockEvent,
+	"syscall": pprofSyscallEvent,
+	"sched":   pprofSchedEvent,
+	"assist":  pprofAssistEvent,
+}
+
 // computePprofIO generates IO pprof-like profile (time spent in IO wait, currently only network blocking event).
-func computePprofIO(w io.Writer, gToIntervals map[uint64][]interval, events []*trace.Event) error {
-	prof := make(map[uint64]Record)
-	for _, ev := range events {
-		if ev.Type != trace.EvGoBlockNet || ev.Link == nil || ev.StkID == 0 || len(ev.Stk) == 0 {
-			continue
-		}
-		overlapping := pprofOverlappingDuration(gToIntervals, ev)
-		if overlapping > 0 {
-			rec := prof[ev.StkID]
-			rec.stk = ev.Stk
-			rec.n++
-			rec.time += overlapping.Nanoseconds()
-			prof[ev.StkID] = rec
-		}
-	}
-	return buildProfile(prof).Write(w)
+func computePprofIO(gToIntervals map[uint64][]interval, events []*trace.Event) *profile.Profile {
+	return computePprof(gToIntervals, events, pprofIOEvent)
+}
+
+func pprofIOEvent(ev *trace.Event) bool {
+	return ev.Type == trace.EvGoBlockNet
 }
 
 // computePprofBlock generates blocking pprof-like profile (time spent blocked on synchronization primitives).
-func computePprofBlock(w io.Writer, gToIntervals map[uint64][]interval, events []*trace.Event) error {
-	prof := make(map[uint64]Record)
-	for _, ev := range events {
-		switch ev.Type {
-		case trace.EvGoBlockSend, trace.EvGoBlockRecv, trace.EvGoBlockSelect,
-			trace.EvGoBlockSync, trace.EvGoBlockCond, trace.EvGoBlockGC:
-			// TODO(hyangah): figure out why EvGoBlockGC should be here.
-			// EvGoBlockGC indicates the goroutine blocks on GC assist, not
-			// on synchronization primitives.
-		default:
-			continue
-		}
-		if ev.Link == nil || ev.StkID == 0 || len(ev.Stk) == 0 {
-			continue
-		}
-		overlapping := pprofOverlappingDuration(gToIntervals, ev)
-		if overlapping > 0 {
-			rec := prof[ev.StkID]
-			rec.stk = ev.Stk
-			rec.n++
-			rec.time += overlapping.Nanoseconds()
-			prof[ev.StkID] = rec
-		}
+func computePprofBlock(gToIntervals map[uint64][]interval, events []*trace.Event) *profile.Profile {
+	return computePprof(gToIntervals, events, pprofBlockEvent)
+}
+
+func pprofBlockEvent(ev *trace.Event) bool {
+	switch ev.Type {
+	case trace.EvGoBlockSend, trace.EvGoBlockRecv, trace.EvGoBlockSelect,
+		trace.EvGoBlockSync, trace.EvGoBlockCond, trace.EvGoBlockGC:
+		// TODO(hyangah): figure out why EvGoBlockGC should be here.
+		// EvGoBlockGC indicates the goroutine blocks on GC assist, not
+		// on synchronization primitives.
+		return true
 	}
-	return buildProfile(prof).Write(w)
+	return false
 }
 
 // computePprofSyscall generates syscall pprof-like profile (time spent blocked in syscalls).
-func computePprofSyscall(w io.Writer, gToIntervals map[uint64][]interval, events []*trace.Event) error {
-	prof := make(map[uint64]Record)
+func computePprofSyscall(gToIntervals map[uint64][]interval, events []*trace.Event) *profile.Profile {
+	return computePprof(gToIntervals, events, pprofSyscallEvent)
+}
+
+func pprofSyscallEvent(ev *trace.Event) bool {
+	return ev.Type == trace.EvGoSysCall
+}
+
+// computePprofSched generates scheduler latency pprof-like profile
+// (time between a goroutine become runnable and actually scheduled for execution).
+func computePprofSched(gToIntervals map[uint64][]interval, events []*trace.Event) *profile.Profile {
+	return computePprof(gToIntervals, events, pprofSchedEvent)
+}
+
+func pprofSchedEvent(ev *trace.Event) bool {
+	return ev.Type == trace.EvGoUnblock || ev.Type == trace.EvGoCreate
+}
+
+// computePprofAssist generates GC mark assist pprof-like profile (time
+// spent in mark assists, by the stack of the allocation that started them).
+func computePprofAssist(gToIntervals map[uint64][]interval, events []*trace.Event) *profile.Profile {
+	return computePprof(gToIntervals, events, pprofAssistEvent)
+}
+
+func pprofAssistEvent(ev *trace.Event) bool {
+	return ev.Type == trace.EvGCMarkAssistStart
+}
+
+// computePprof generates the pprof-like profile of the time from the
+// events matching match to their Link.
+func computePprof(gToIntervals map[uint64][]interval, events []*trace.Event, match func(*trace.Event) bool) *profile.Profile {
+	b := newPprofBuilder(gToIntervals, match)
+	b.add(events)
+	return b.finish()
+}
+
+// pprofBuilder generates the profile of computePprof incrementally,
+// from the chunks of events returned by a trace.Stream.
+type pprofBuilder struct {
+	gToIntervals map[uint64][]interval
+	match        func(*trace.Event) bool
+	prof         map[uint64]Record
+	pending      []*trace.Event // matching events whose Link is not known yet
+}
+
+func newPprofBuilder(gToIntervals map[uint64][]interval, match func(*trace.Event) bool) *pprofBuilder {
+	return &pprofBuilder{gToIntervals: gToIntervals, match: match, prof: make(map[uint64]Record)}
+}
+
+// add adds the next events of the trace.
+func (b *pprofBuilder) add(events []*trace.Event) {
+	pending := b.pending[:0]
+	for _, ev := range b.pending {
+		if ev.Link == nil {
+			pending = append(pending, ev)
+			continue
+		}
+		b.record(ev)
+	}
 	for _, ev := range events {
-		if ev.Type != trace.EvGoSysCall || ev.Link == nil || ev.StkID == 0 || len(ev.Stk) == 0 {
+		if !b.match(ev) || ev.StkID == 0 || len(ev.Stk) == 0 {
 			continue
 		}
-		overlapping := pprofOverlappingDuration(gToIntervals, ev)
-		if overlapping > 0 {
-			rec := prof[ev.StkID]
-			rec.stk = ev.Stk
-			rec.n++
-			rec.time += overlapping.Nanoseconds()
-			prof[ev.StkID] = rec
+		if ev.Link == nil {
+			pending = append(pending, ev)
+			continue
 		}
+		b.record(ev)
 	}
-	return buildProfile(prof).Write(w)
+	b.pending = pending
 }
 
-// computePprofSched generates scheduler latency pprof-like profile
-// (time between a goroutine become runnable and actually scheduled for execution).
-func computePprofSched(w io.Writer, gToIntervals map[uint64][]interval, events []*trace.Event) error {
-	prof := make(map[uint64]Record)
-	for _, ev := range events {
-		if (ev.Type != trace.EvGoUnblock && ev.Type != trace.EvGoCreate) ||
-			ev.Link == nil || ev.StkID == 0 || len(ev.Stk) == 0 {
-			continue
+func (b *pprofBuilder) record(ev *trace.Event) {
+	overlapping := pprofOverlappingDuration(b.gToIntervals, ev)
+	if overlapping > 0 {
+		rec := b.prof[ev.StkID]
+		rec.stk = ev.Stk
+		rec.n++
+		rec.time += overlapping.Nanoseconds()
+		b.prof[ev.StkID] = rec
+	}
+}
+
+// finish returns the profile of the events added. The events still
+// without a Link, which never ended, are left out.
+func (b *pprofBuilder) finish() *profile.Profile {
+	b.add(nil)
+	return buildProfile(b.prof)
+}
+
+// scanPprof generates the pprof-like profile of computePprof of the
+// events read with loader.scan up to to, so that with an index, only
+// the events in progress are held in memory.
+func (st *traceState) scanPprof(gToIntervals map[uint64][]interval, to int64, match func(*trace.Event) bool) (*profile.Profile, error) {
+	b := newPprofBuilder(gToIntervals, match)
+	if err := st.loader.scan(to, b.add); err != nil {
+		return nil, err
+	}
+	return b.finish(), nil
+}
+
+// streamPprof generates the pprof-like profile of the events matching
+// match of the whole trace file. It reads the trace incrementally,
+// keeping only the events in progress in memory, for traces too large
+// to parse at once.
+func streamPprof(file, bin string, match func(*trace.Event) bool) (*profile.Profile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %v", err)
+	}
+	defer f.Close()
+	s, err := trace.NewStream(f, bin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace: %v", err)
+	}
+	b := newPprofBuilder(nil, match)
+	for {
+		events, err := s.Next()
+		if err == io.EOF {
+			break
 		}
-		overlapping := pprofOverlappingDuration(gToIntervals, ev)
-		if overlapping > 0 {
-			rec := prof[ev.StkID]
-			rec.stk = ev.Stk
-			rec.n++
-			rec.time += overlapping.Nanoseconds()
-			prof[ev.StkID] = rec
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trace: %v", err)
 		}
+		b.add(events)
 	}
-	return buildProfile(prof).Write(w)
+	return b.finish(), nil
 }
 
 // pprofOverlappingDuration returns the overlapping duration between
@@ -279,52 +377,65 @@ func pprofOverlappingDuration(gToIntervals map[uint64][]interval, ev *trace.Even
 	return overlapping
 }
 
-// serveSVGProfile serves pprof-like profile generated by prof as svg.
-func serveSVGProfile(prof func(w io.Writer, r *http.Request) error) http.HandlerFunc {
+// serveProfile serves the pprof-like profile generated by prof as a
+// flame graph and a table of the top functions. With raw=1, it serves
+// the profile itself, and with svg=1 the graph rendered by go tool
+// pprof, which requires a Go toolchain and Graphviz.
+func serveProfile(title string, prof profileFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-
-		if r.FormValue("raw") != "" {
+		p, err := prof(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to generate profile: %v", err), http.StatusInternalServerError)
+			return
+		}
+		switch {
+		case r.FormValue("raw") != "":
 			w.Header().Set("Content-Type", "application/octet-stream")
-			if err := prof(w, r); err != nil {
+			if err := p.Write(w); err != nil {
 				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 				w.Header().Set("X-Go-Pprof", "1")
 				http.Error(w, fmt.Sprintf("failed to get profile: %v", err), http.StatusInternalServerError)
-				return
 			}
-			return
+		case r.FormValue("svg") != "":
+			serveSVGProfile(w, r, p)
+		default:
+			serveProfileHTML(w, r, title, p)
 		}
+	}
+}
 
-		blockf, err := ioutil.TempFile("", "block")
-		if err != nil {
-			http.Error(w, fmt.Sprintf("failed to create temp file: %v", err), http.StatusInternalServerError)
-			return
-		}
-		defer func() {
-			blockf.Close()
-			os.Remove(blockf.Name())
-		}()
-		blockb := bufio.NewWriter(blockf)
-		if err := prof(blockb, r); err != nil {
-			http.Error(w, fmt.Sprintf("failed to generate profile: %v", err), http.StatusInternalServerError)
-			return
-		}
-		if err := blockb.Flush(); err != nil {
-			http.Error(w, fmt.Sprintf("failed to flush temp file: %v", err), http.StatusInternalServerError)
-			return
-		}
-		if err := blockf.Close(); err != nil {
-			http.Error(w, fmt.Sprintf("failed to close temp file: %v", err), http.StatusInternalServerError)
-			return
-		}
-		svgFilename := blockf.Name() + ".svg"
-		if output, err := exec.Command(goCmd(), "tool", "pprof", "-svg", "-output", svgFilename, blockf.Name()).CombinedOutput(); err != nil {
-			http.Error(w, fmt.Sprintf("failed to execute go tool pprof: %v\n%s", err, output), http.StatusInternalServerError)
-			return
-		}
-		defer os.Remove(svgFilename)
-		w.Header().Set("Content-Type", "image/svg+xml")
-		http.ServeFile(w, r, svgFilename)
+// serveSVGProfile serves the profile as svg rendered by go tool pprof.
+func serveSVGProfile(w http.ResponseWriter, r *http.Request, p *profile.Profile) {
+	blockf, err := ioutil.TempFile("", "block")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to create temp file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		blockf.Close()
+		os.Remove(blockf.Name())
+	}()
+	blockb := bufio.NewWriter(blockf)
+	if err := p.Write(blockb); err != nil {
+		http.Error(w, fmt.Sprintf("failed to write profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := blockb.Flush(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to flush temp file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := blockf.Close(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to close temp file: %v", err), http.StatusInternalServerError)
+		return
+	}
+	svgFilename := blockf.Name() + ".svg"
+	if output, err := exec.Command(goCmd(), "tool", "pprof", "-svg", "-output", svgFilename, blockf.Name()).CombinedOutput(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute go tool pprof: %v\n%s", err, output), http.StatusInternalServerError)
+		return
 	}
+	defer os.Remove(svgFilename)
+	w.Header().Set("Content-Type", "image/svg+xml")
+	http.ServeFile(w, r, svgFilename)
 }
 
 func buildProfile(prof map[uint64]Record) *profile.Profile {
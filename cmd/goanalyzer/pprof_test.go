@@ -0,0 +1,63 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"runtime/trace"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	traceparser "github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+)
+
+func TestStreamPprof(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := trace.Start(buf); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	c := make(chan int)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go contendedRecv(c, &wg)
+	}
+	time.Sleep(time.Millisecond)
+	close(c)
+	wg.Wait()
+	trace.Stop()
+	file := filepath.Join(t.TempDir(), "test.trace")
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err := traceparser.Parse(bytes.NewReader(buf.Bytes()), "")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+
+	total := func(p *profile.Profile) (n, d int64) {
+		for _, s := range p.Sample {
+			n += s.Value[0]
+			d += s.Value[1]
+		}
+		return n, d
+	}
+	for name, match := range pprofEvents {
+		p, err := streamPprof(file, "", match)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		gotN, gotD := total(p)
+		wantN, wantD := total(computePprof(nil, res.Events, match))
+		if gotN != wantN || gotD != wantD {
+			t.Errorf("%s: streamed profile has %d events for %d ns, want %d for %d ns", name, gotN, gotD, wantN, wantD)
+		}
+		if name == "sync" && gotN == 0 {
+			t.Errorf("sync: no blocking events")
+		}
+	}
+}
@@ -0,0 +1,512 @@
+// Processor utilization.
+
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/procs", httpProcs)
+}
+
+// procSeriesBuckets is the number of points of the time series of the
+// processor page.
+const procSeriesBuckets = 200
+
+// procStat is the time a P spent running goroutines. Durations are
+// encoded in nanoseconds in JSON.
+type procStat struct {
+	P       int
+	Busy    time.Duration // running goroutines, outside of GC workers and syscalls
+	GC      time.Duration // running GC workers
+	Syscall time.Duration // in syscalls of the goroutines it ran, until they exit or lose the P
+	Idle    time.Duration
+	Held    time.Duration // by a thread, from ProcStart to ProcStop, including while idle
+	Starts  int           // goroutines started
+}
+
+// gomaxprocsChange is a change of GOMAXPROCS, the first one being its
+// value when the trace started.
+type gomaxprocsChange struct {
+	Time  time.Duration
+	Procs int
+	URL   string `json:"-"` // in the trace viewer
+}
+
+// procSummary is the utilization of the Ps of a trace. Times are
+// relative to the start of the trace.
+type procSummary struct {
+	Duration   time.Duration
+	Procs      []procStat
+	Gomaxprocs []gomaxprocsChange
+	// Time with as many goroutines running as GOMAXPROCS, time with
+	// goroutines waiting for a P on top of that, and time with no
+	// goroutine running.
+	Saturated, Starved, Idle time.Duration
+	// Imbalance is how much longer the most used P ran goroutines than
+	// the average P, as a fraction of the average.
+	Imbalance float64
+	// Averages over equal intervals of the trace of the number of
+	// running goroutines, runnable goroutines and GOMAXPROCS.
+	Running, Runnable, MaxProcs []float64
+}
+
+// goroutineTransition returns the goroutine whose state ev changes, and
+// its new state.
+func goroutineTransition(ev *trace.Event) (g uint64, state gState, ok bool) {
+	switch ev.Type {
+	case trace.EvGoCreate:
+		return ev.Args[0], gRunnable, true
+	case trace.EvGoStart, trace.EvGoStartLabel:
+		return ev.G, gRunning, true
+	case trace.EvGoEnd:
+		return ev.G, gDead, true
+	case trace.EvGoSched, trace.EvGoPreempt:
+		return ev.G, gRunnable, true
+	case trace.EvGoStop, trace.EvGoSleep, trace.EvGoBlock, trace.EvGoBlockSend, trace.EvGoBlockRecv,
+		trace.EvGoBlockSelect, trace.EvGoBlockSync, trace.EvGoBlockCond, trace.EvGoBlockNet,
+		trace.EvGoWaiting:
+		return ev.G, gWaiting, true
+	case trace.EvGoBlockGC:
+		return ev.G, gWaitingGC, true
+	case trace.EvGoSysBlock, trace.EvGoInSyscall:
+		return ev.G, gSyscall, true
+	case trace.EvGoUnblock:
+		return ev.Args[0], gRunnable, true
+	case trace.EvGoSysExit:
+		return ev.G, gRunnable, true
+	}
+	return 0, 0, false
+}
+
+// stepSeries averages a value that changes in steps over the equal
+// buckets of a time interval.
+type stepSeries struct {
+	start, end int64
+	sums       []float64 // integral of the value over each bucket
+	last       int64
+	value      float64
+}
+
+func newStepSeries(start, end int64, n int) *stepSeries {
+	if end <= start {
+		end = start + 1
+	}
+	return &stepSeries{start: start, end: end, sums: make([]float64, n), last: start}
+}
+
+// set changes the value at time ts, which does not decrease between
+// calls.
+func (s *stepSeries) set(ts int64, v float64) {
+	if ts > s.end {
+		ts = s.end
+	}
+	for s.last < ts {
+		i := s.bucket(s.last)
+		next := s.bound(i + 1)
+		if next > ts {
+			next = ts
+		}
+		s.sums[i] += s.value * float64(next-s.last)
+		s.last = next
+	}
+	s.value = v
+}
+
+// bound returns the start of bucket i, or the end for i == len(s.sums).
+func (s *stepSeries) bound(i int) int64 {
+	return s.start + int64(i)*(s.end-s.start)/int64(len(s.sums))
+}
+
+// bucket returns the bucket of time t.
+func (s *stepSeries) bucket(t int64) int {
+	i := int((t - s.start) * int64(len(s.sums)) / (s.end - s.start))
+	for i+1 < len(s.sums) && s.bound(i+1) <= t {
+		i++
+	}
+	if i >= len(s.sums) {
+		i = len(s.sums) - 1
+	}
+	return i
+}
+
+// averages returns the average value of each bucket.
+func (s *stepSeries) averages() []float64 {
+	s.set(s.end, s.value)
+	avg := make([]float64, len(s.sums))
+	for i := range avg {
+		if d := s.bound(i+1) - s.bound(i); d > 0 {
+			avg[i] = s.sums[i] / float64(d)
+		}
+	}
+	return avg
+}
+
+// seriesChartScript defines drawSeries(id, lines), which draws lines,
+// pairs of the values of a time series and a color, in the svg element
+// id, scaled to the largest value.
+const seriesChartScript = `
+function drawSeries(id, lines) {
+  var svg = document.getElementById(id);
+  var w = svg.getAttribute('width'), h = svg.getAttribute('height') - 10;
+  var max = 1;
+  lines.forEach(function(l) { (l[0] || []).forEach(function(v) { max = Math.max(max, v); }); });
+  lines.forEach(function(l) {
+    var values = l[0] || [];
+    var points = values.map(function(v, i) {
+      return (i * w / values.length).toFixed(1) + ',' + (5 + h - v * h / max).toFixed(1);
+    });
+    var p = document.createElementNS('http://www.w3.org/2000/svg', 'polyline');
+    p.setAttribute('points', points.join(' '));
+    p.setAttribute('stroke', l[1]);
+    p.setAttribute('fill', 'none');
+    svg.appendChild(p);
+  });
+  var t = document.createElementNS('http://www.w3.org/2000/svg', 'text');
+  t.setAttribute('x', 2);
+  t.setAttribute('y', 14);
+  t.setAttribute('font-size', 11);
+  t.textContent = max.toFixed(0);
+  svg.appendChild(t);
+}
+`
+
+// isGCWorker reports whether the goroutine start ev is the start of a GC
+// mark worker.
+func isGCWorker(ev *trace.Event) bool {
+	return ev.Type == trace.EvGoStartLabel && len(ev.SArgs) > 0 && strings.HasPrefix(ev.SArgs[0], "GC ")
+}
+
+// computeProcs returns the utilization of the Ps of the non-empty
+// trace during the window. The events before the window only set the
+// state of the goroutines and Ps when it starts.
+func (st *traceState) computeProcs(events []*trace.Event, w traceWindow) *procSummary {
+	base, last := events[0].Ts, events[len(events)-1].Ts
+	if w.from > base {
+		base = w.from
+	}
+	if w.to < last {
+		last = w.to
+	}
+	if last < base {
+		last = base
+	}
+	s := &procSummary{Duration: time.Duration(last - base)}
+	// during returns the part of [start, end] in the window.
+	during := func(start, end int64) time.Duration {
+		if start < base {
+			start = base
+		}
+		if end > last {
+			end = last
+		}
+		if end < start {
+			return 0
+		}
+		return time.Duration(end - start)
+	}
+	var procs []procStat
+	proc := func(p int) *procStat {
+		for len(procs) <= p {
+			procs = append(procs, procStat{P: len(procs)})
+		}
+		return &procs[p]
+	}
+	running, runnable, gomaxprocs := 0, 0, 0
+	// account accounts the time since the previous event.
+	prev := events[0].Ts
+	account := func(ts int64) {
+		if d := during(prev, ts); d > 0 {
+			switch {
+			case running == 0:
+				s.Idle += d
+			case gomaxprocs > 0 && running >= gomaxprocs:
+				s.Saturated += d
+				if runnable > 0 {
+					s.Starved += d
+				}
+			}
+		}
+		prev = ts
+	}
+	// begin records GOMAXPROCS when the window starts.
+	started := false
+	begin := func() {
+		started = true
+		if gomaxprocs > 0 {
+			s.Gomaxprocs = append(s.Gomaxprocs, gomaxprocsChange{Procs: gomaxprocs, URL: st.viewerURL(base, base+int64(time.Millisecond))})
+		}
+	}
+	series := [3]*stepSeries{}
+	for i := range series {
+		series[i] = newStepSeries(base, last, procSeriesBuckets)
+	}
+	states := make(map[uint64]gState)
+	runEnd := make(map[uint64]int64) // end of the current execution of running goroutines
+	held := make(map[int]int64)      // start of the Ps held by a thread
+	for _, ev := range events {
+		if ev.Ts > last {
+			break
+		}
+		if !started && ev.Ts >= base {
+			begin()
+		}
+		account(ev.Ts)
+
+		switch ev.Type {
+		case trace.EvProcStart:
+			held[ev.P] = ev.Ts
+		case trace.EvProcStop:
+			if start, ok := held[ev.P]; ok {
+				proc(ev.P).Held += during(start, ev.Ts)
+				delete(held, ev.P)
+			}
+		case trace.EvGomaxprocs:
+			if n := int(ev.Args[0]); n != gomaxprocs {
+				gomaxprocs = n
+				if started {
+					s.Gomaxprocs = append(s.Gomaxprocs, gomaxprocsChange{Time: time.Duration(ev.Ts - base), Procs: gomaxprocs, URL: st.viewerURL(ev.Ts, ev.Ts+int64(time.Millisecond))})
+				}
+				series[2].set(ev.Ts, float64(gomaxprocs))
+			}
+		case trace.EvGoStart, trace.EvGoStartLabel:
+			end := events[len(events)-1].Ts
+			if ev.Link != nil {
+				end = ev.Link.Ts
+			}
+			runEnd[ev.G] = end
+			if ev.P >= 0 && ev.P < trace.FakeP {
+				p := proc(ev.P)
+				if ev.Ts >= base {
+					p.Starts++
+				}
+				if isGCWorker(ev) {
+					p.GC += during(ev.Ts, end)
+				} else {
+					p.Busy += during(ev.Ts, end)
+				}
+			}
+		case trace.EvGoSysCall:
+			// The syscall holds the P until it returns, or until the P
+			// is taken from it and the goroutine blocks.
+			end, ok := runEnd[ev.G]
+			if !ok || ev.P < 0 || ev.P >= trace.FakeP {
+				break
+			}
+			if ev.Link != nil && ev.Link.Ts < end {
+				end = ev.Link.Ts
+			}
+			if d := during(ev.Ts, end); d > 0 {
+				p := proc(ev.P)
+				p.Syscall += d
+				p.Busy -= d
+			}
+		}
+
+		g, state, ok := goroutineTransition(ev)
+		if !ok {
+			continue
+		}
+		switch states[g] {
+		case gRunning:
+			running--
+			if state != gRunning {
+				delete(runEnd, g)
+			}
+		case gRunnable:
+			runnable--
+		}
+		switch state {
+		case gRunning:
+			running++
+		case gRunnable:
+			runnable++
+		}
+		if state == gDead {
+			delete(states, g)
+		} else {
+			states[g] = state
+		}
+		series[0].set(ev.Ts, float64(running))
+		series[1].set(ev.Ts, float64(runnable))
+	}
+	if !started {
+		begin()
+	}
+	account(last)
+
+	for p, start := range held {
+		proc(p).Held += during(start, last)
+	}
+	// Ps that never ran a goroutine are idle all along.
+	for _, c := range s.Gomaxprocs {
+		if c.Procs > 0 {
+			proc(c.Procs - 1)
+		}
+	}
+	var total, max time.Duration
+	for i := range procs {
+		p := &procs[i]
+		p.Idle = s.Duration - p.Busy - p.GC - p.Syscall
+		if p.Idle < 0 {
+			p.Idle = 0
+		}
+		used := p.Busy + p.GC + p.Syscall
+		total += used
+		if used > max {
+			max = used
+		}
+	}
+	if total > 0 {
+		s.Imbalance = float64(max)*float64(len(procs))/float64(total) - 1
+	}
+	s.Procs = procs
+	s.Running, s.Runnable, s.MaxProcs = series[0].averages(), series[1].averages(), series[2].averages()
+	return s
+}
+
+// httpProcs serves the utilization of the Ps.
+func httpProcs(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(events) == 0 {
+		http.Error(w, "empty trace", http.StatusInternalServerError)
+		return
+	}
+	s := st.computeProcs(events, win)
+	if serveExport(w, r, "procs", func() interface{} { return s.Procs }) {
+		return
+	}
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	if err := templProcs.Execute(w, s); err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templProcs = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+	"percent": func(d, total time.Duration) string {
+		if total == 0 {
+			return ""
+		}
+		return fmt.Sprintf("%.1f%%", float64(d)*100/float64(total))
+	},
+	"fraction": func(f float64) string {
+		return fmt.Sprintf("%.1f%%", f*100)
+	},
+}).Parse(`
+<!DOCTYPE html>
+<title>Processors</title>
+<style>
+body {
+  font-family: sans-serif;
+}
+th {
+  background-color: #050505;
+  color: #fff;
+}
+table {
+  border-collapse: collapse;
+}
+.details td {
+  text-align: right;
+  border: 1px solid #000;
+  padding: 0.2em 0.5em;
+}
+.stacked-bar-graph {
+  width: 300px;
+  height: 10px;
+  white-space: nowrap;
+  font-size: 5px;
+}
+.stacked-bar-graph span {
+  display: inline-block;
+  height: 100%;
+  box-sizing: border-box;
+  float: left;
+}
+.busy-time { background-color: #d7191c; }
+.gc-time { background-color: #1a9641; }
+.syscall-time { background-color: #7b3294; }
+.idle-time { background-color: #ddd; }
+#chart polyline {
+  fill: none;
+  stroke-width: 1.5;
+}
+</style>
+<body>
+<h2>Processors</h2>
+<p>Over {{niceDuration .Duration}}, goroutines used every P for {{niceDuration .Saturated}} ({{percent .Saturated .Duration}}),
+of which {{niceDuration .Starved}} ({{percent .Starved .Duration}}) with runnable goroutines waiting for a P,
+and no P for {{niceDuration .Idle}} ({{percent .Idle .Duration}}).
+Idle Ps held by a thread are looking for work.
+A program starved for Ps is CPU-bound; one that leaves Ps idle while goroutines wait elsewhere is not.
+The most used P ran goroutines {{fraction .Imbalance}} longer than the average P.</p>
+
+<p>GOMAXPROCS:
+{{range $i, $c := .Gomaxprocs}}{{if $i}}, {{end}}<a href="{{$c.URL}}">{{$c.Procs}} at {{niceDuration $c.Time}}</a>{{else}}unknown{{end}}.</p>
+
+<h3>Goroutines over time</h3>
+<p>Running goroutines (<span style="color: #d7191c">red</span>), runnable goroutines waiting for a P
+(<span style="color: #2c7bb6">blue</span>) and GOMAXPROCS (<span style="color: #000">black</span>), averaged over
+intervals of the trace. Runnable goroutines include the global and the per-P run queues, which the trace does not tell apart.</p>
+<svg id="chart" width="900" height="200"></svg>
+
+<h3>Ps</h3>
+<p>Download as <a href="/procs?format=csv">CSV</a> or <a href="/procs?format=json">JSON</a>.</p>
+<table class="details">
+<tr>
+<th> P </th>
+<th></th>
+<th class="busy-time"> Running goroutines </th>
+<th class="gc-time"> GC workers </th>
+<th class="syscall-time"> Syscalls </th>
+<th> Idle </th>
+<th> Held by a thread </th>
+<th> Goroutines started </th>
+</tr>
+{{range .Procs}}
+<tr>
+<td>{{.P}}</td>
+<td>
+<div class="stacked-bar-graph">
+<span style="width: {{percent .Busy $.Duration}}" class="busy-time">&nbsp;</span>
+<span style="width: {{percent .GC $.Duration}}" class="gc-time">&nbsp;</span>
+<span style="width: {{percent .Syscall $.Duration}}" class="syscall-time">&nbsp;</span>
+<span style="width: {{percent .Idle $.Duration}}" class="idle-time">&nbsp;</span>
+</div>
+</td>
+<td>{{niceDuration .Busy}} {{percent .Busy $.Duration}}</td>
+<td>{{niceDuration .GC}} {{percent .GC $.Duration}}</td>
+<td>{{niceDuration .Syscall}} {{percent .Syscall $.Duration}}</td>
+<td>{{niceDuration .Idle}} {{percent .Idle $.Duration}}</td>
+<td>{{niceDuration .Held}} {{percent .Held $.Duration}}</td>
+<td>{{.Starts}}</td>
+</tr>
+{{end}}
+</table>
+<script>
+'use strict';
+` + seriesChartScript + `
+drawSeries('chart', [[{{.Running}}, '#d7191c'], [{{.Runnable}}, '#2c7bb6'], [{{.MaxProcs}}, '#000']]);
+` + windowScript + `
+</script>
+</body>
+</html>
+`))
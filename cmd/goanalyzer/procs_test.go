@@ -0,0 +1,93 @@
+// +build !js
+
+package main
+
+import (
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// spin runs for d.
+func spin(d time.Duration, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
+
+func TestComputeProcs(t *testing.T) {
+	procs := runtime.GOMAXPROCS(2)
+	defer runtime.GOMAXPROCS(procs)
+	prog := func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go spin(20*time.Millisecond, &wg)
+		}
+		wg.Wait()
+		runtime.GOMAXPROCS(1)
+		runtime.GC()
+	}
+	if err := traceProgram(t, prog, "TestComputeProcs"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+	s := mainTrace.computeProcs(events, wholeTrace)
+	if len(s.Gomaxprocs) != 2 || s.Gomaxprocs[0].Procs != 2 || s.Gomaxprocs[1].Procs != 1 {
+		t.Errorf("GOMAXPROCS changes %+v, want 2 then 1", s.Gomaxprocs)
+	}
+	if len(s.Procs) != 2 {
+		t.Fatalf("%d Ps, want 2", len(s.Procs))
+	}
+	// The goroutines spin by the wall clock, so they keep both Ps busy
+	// for about 20ms, less the time it takes to start them.
+	for _, p := range s.Procs {
+		if p.Busy < 10*time.Millisecond {
+			t.Errorf("P %d busy for %v, want at least 10ms", p.P, p.Busy)
+		}
+		if p.Held < p.Busy {
+			t.Errorf("P %d held for %v, less than it ran goroutines (%v)", p.P, p.Held, p.Busy)
+		}
+		if p.Busy+p.GC+p.Syscall+p.Idle > s.Duration {
+			t.Errorf("P %d times add up to more than the %v trace: %+v", p.P, s.Duration, p)
+		}
+	}
+	// Four goroutines spinning on two Ps leave two waiting.
+	if s.Starved < 10*time.Millisecond {
+		t.Errorf("starved for %v, want at least 10ms", s.Starved)
+	}
+	var max float64
+	for _, v := range s.Runnable {
+		if v > max {
+			max = v
+		}
+	}
+	if len(s.Runnable) != procSeriesBuckets || max < 1 {
+		t.Errorf("%d runnable buckets up to %v, want %d up to at least 1", len(s.Runnable), max, procSeriesBuckets)
+	}
+
+	w := httptest.NewRecorder()
+	httpProcs(w, httptest.NewRequest("GET", "/procs", nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "Processors") {
+		t.Errorf("/procs: status %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestStepSeries(t *testing.T) {
+	s := newStepSeries(0, 100, 4)
+	s.set(10, 2)
+	s.set(60, 0)
+	s.set(70, 4)
+	want := []float64{1.2, 2, 1.6, 4}
+	for i, v := range s.averages() {
+		if v < want[i]-1e-9 || v > want[i]+1e-9 {
+			t.Errorf("bucket %d: average %v, want %v", i, v, want[i])
+		}
+	}
+}
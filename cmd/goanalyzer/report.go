@@ -0,0 +1,461 @@
+// Headless summary of the goroutine, user annotation and GC analyses.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// reportFormats are the output formats supported by -report.
+var reportFormats = map[string]func(io.Writer, *report) error{
+	"text":     writeReportText,
+	"json":     writeReportJSON,
+	"markdown": writeReportMarkdown,
+}
+
+// reportMMUWindows are the window sizes the minimum mutator utilization
+// is reported for.
+var reportMMUWindows = []time.Duration{time.Millisecond, 10 * time.Millisecond, 100 * time.Millisecond, time.Second}
+
+// reportMMUFlags matches the default view of the MMU page.
+const reportMMUFlags = trace.UtilSTW | trace.UtilBackground | trace.UtilAssist
+
+// report is the summary written by -report.
+// Durations are encoded in nanoseconds in JSON.
+type report struct {
+	Duration   time.Duration // of the whole trace
+	Goroutines []reportGroup // sorted by decreasing execution time
+	Tasks      []reportLatency
+	Regions    []reportLatency
+	GC         reportGC
+	Leaks      []reportLeak // possibly leaked goroutines, largest groups first
+
+	mmuCurve *trace.MMUCurve // for the MMU of windows checked by rules
+}
+
+// reportGroup summarizes a goroutine group, see gtype.
+type reportGroup struct {
+	Name           string
+	PC             uint64 // start PC of the goroutines
+	N              int
+	TotalTime      reportStat
+	ExecTime       reportStat
+	IOTime         reportStat
+	BlockTime      reportStat
+	SyscallTime    reportStat
+	SchedWaitTime  reportStat
+	SweepTime      reportStat
+	GCTime         reportStat
+	MarkAssistTime reportStat
+}
+
+// reportStat summarizes a trace.GExecutionStatEntry.
+type reportStat struct {
+	Count                int64
+	Total, Min, Avg, Max time.Duration
+	Stddev               time.Duration
+	P50, P90, P99, P999  time.Duration
+}
+
+// reportLatency summarizes the durations of a user task or region type.
+type reportLatency struct {
+	Type     string
+	Func     string `json:",omitempty"` // function starting the region
+	Count    int    // complete and incomplete
+	Complete int
+	// Statistics of the complete tasks or regions.
+	Mean, Stddev                 time.Duration
+	Min, P50, P90, P95, P99, Max time.Duration
+}
+
+// reportLeak summarizes a group of possibly leaked goroutines, see leakGroup.
+type reportLeak struct {
+	Goroutine string // start function
+	Count     int
+	Kind      string // blocking reason
+	BlockedAt string // innermost frame of the blocking stack outside of package runtime
+	CreatedAt string
+	Since     time.Duration // relative to the start of the trace
+}
+
+type reportGC struct {
+	Count    int           // GC cycles
+	Time     time.Duration // wall time with a GC in progress
+	STWCount int
+	STWTime  time.Duration
+	STWMax   time.Duration
+	MMU      []reportMMU
+}
+
+type reportMMU struct {
+	Window time.Duration
+	MMU    float64
+}
+
+// writeReport writes the summary report of the trace to w in format.
+func (st *traceState) writeReport(w io.Writer, format string) error {
+	write, ok := reportFormats[format]
+	if !ok {
+		return fmt.Errorf("unknown report format %q (want text, json or markdown)", format)
+	}
+	rep, err := st.summaryReport()
+	if err != nil {
+		return err
+	}
+	return write(w, rep)
+}
+
+// summaryReport is buildReport for the batch modes: unless the events
+// are parsed or indexed already, the trace is streamed without keeping
+// them.
+func (st *traceState) summaryReport() (*report, error) {
+	if l := st.loader; l.index == nil && !l.parsed.Load() {
+		return streamReport(l.file, l.bin)
+	}
+	return st.buildReport()
+}
+
+// buildReport runs the goroutine, annotation and GC analyses.
+func (st *traceState) buildReport() (*report, error) {
+	events, err := st.parseEvents()
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("empty trace")
+	}
+	st.analyzeGoroutines(events)
+	annotations, err := st.analyzeAnnotations()
+	if err != nil {
+		return nil, err
+	}
+	_, mmuCurve, err := st.getMMUCurveFlags(reportMMUFlags)
+	if err != nil {
+		return nil, err
+	}
+	return newReport(events, st.gs, annotations, mmuCurve), nil
+}
+
+// streamReport builds the report of the trace file from a trace.Stream.
+// Of the events, it keeps only the first and the last, for the bounds
+// of the trace, and those of user tasks and the GC; the goroutine
+// statistics and the mutator utilization are computed from the chunks.
+// For traces written by Go 1.22 and later, memory is bounded by the
+// number of goroutines, regions, tasks and GC cycles rather than by the
+// size of the trace.
+func streamReport(file, bin string) (*report, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trace file: %v", err)
+	}
+	defer f.Close()
+	s, err := trace.NewStream(f, bin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse trace: %v", err)
+	}
+	gb := trace.NewGoroutineStatsBuilder()
+	ub := trace.NewMutatorUtilBuilder(reportMMUFlags)
+	var events []*trace.Event
+	var last *trace.Event
+	for {
+		chunk, err := s.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse trace: %v", err)
+		}
+		gb.Add(chunk)
+		ub.Add(chunk)
+		for _, ev := range chunk {
+			switch ev.Type {
+			case trace.EvUserTaskCreate, trace.EvUserTaskEnd, trace.EvUserLog, trace.EvGCStart, trace.EvGCSTWStart:
+				events = append(events, ev)
+			default:
+				if len(events) == 0 {
+					events = append(events, ev)
+				}
+			}
+		}
+		if len(chunk) > 0 {
+			last = chunk[len(chunk)-1]
+		}
+	}
+	if last == nil {
+		return nil, fmt.Errorf("empty trace")
+	}
+	if events[len(events)-1] != last {
+		events = append(events, last)
+	}
+	gs := gb.Finish()
+	return newReport(events, gs, analyzeAnnotationsOf(events, gs), trace.NewMMUCurve(ub.Finish())), nil
+}
+
+// newReport summarizes the analyses of a non-empty trace.
+func newReport(events []*trace.Event, gs map[uint64]*trace.GDesc, annotations annotationAnalysisResult, mmuCurve *trace.MMUCurve) *report {
+	rep := &report{Duration: time.Duration(events[len(events)-1].Ts - events[0].Ts), mmuCurve: mmuCurve}
+
+	glist, _ := groupGoroutines(gs)
+	sortGoroutineGroups(glist, "ExecTime")
+	for _, g := range glist {
+		rep.Goroutines = append(rep.Goroutines, reportGroup{
+			Name:           g.Name,
+			PC:             g.ID,
+			N:              g.N,
+			TotalTime:      newReportStat(g.TotalTime),
+			ExecTime:       newReportStat(g.ExecTime),
+			IOTime:         newReportStat(g.IOTime),
+			BlockTime:      newReportStat(g.BlockTime),
+			SyscallTime:    newReportStat(g.SyscallTime),
+			SchedWaitTime:  newReportStat(g.SchedWaitTime),
+			SweepTime:      newReportStat(g.SweepTime),
+			GCTime:         newReportStat(g.GCTime),
+			MarkAssistTime: newReportStat(g.MarkAssistTime),
+		})
+	}
+
+	for _, s := range summarizeTasks(annotations.tasks) {
+		l := newReportLatency(s.durations)
+		l.Type = s.Type
+		l.Count = s.Count
+		rep.Tasks = append(rep.Tasks, l)
+	}
+	for _, s := range summarizeRegions(annotations.regions) {
+		l := newReportLatency(s.durations)
+		l.Type = s.Type
+		l.Func = s.Frame.Fn
+		l.Count = s.Histogram.Count
+		rep.Regions = append(rep.Regions, l)
+	}
+
+	for _, ev := range events {
+		switch ev.Type {
+		case trace.EvGCStart:
+			rep.GC.Count++
+			if ev.Link != nil {
+				rep.GC.Time += time.Duration(ev.Link.Ts - ev.Ts)
+			}
+		case trace.EvGCSTWStart:
+			if ev.Link != nil {
+				d := time.Duration(ev.Link.Ts - ev.Ts)
+				rep.GC.STWCount++
+				rep.GC.STWTime += d
+				if d > rep.GC.STWMax {
+					rep.GC.STWMax = d
+				}
+			}
+		}
+	}
+	for _, window := range reportMMUWindows {
+		if window > rep.Duration {
+			break
+		}
+		rep.GC.MMU = append(rep.GC.MMU, reportMMU{Window: window, MMU: mmuCurve.MMU(window)})
+	}
+
+	for _, l := range findLeaks(events, gs, leakTail(events)) {
+		f := l.Frame()
+		rep.Leaks = append(rep.Leaks, reportLeak{
+			Goroutine: l.Name,
+			Count:     len(l.GIDS),
+			Kind:      l.Kind,
+			BlockedAt: fmt.Sprintf("%s %s:%d", f.Fn, f.File, f.Line),
+			CreatedAt: l.Site,
+			Since:     l.Since,
+		})
+	}
+	return rep
+}
+
+func newReportStat(s trace.GExecutionStatEntry) reportStat {
+	r := reportStat{Count: s.Count, Total: time.Duration(s.Total)}
+	if s.Count == 0 {
+		return r
+	}
+	r.Min = time.Duration(s.Min)
+	r.Avg = time.Duration(s.Total / s.Count)
+	r.Max = time.Duration(s.Max)
+	r.Stddev = time.Duration(s.Stddev())
+	r.P50 = time.Duration(s.Percentile(50))
+	r.P90 = time.Duration(s.Percentile(90))
+	r.P99 = time.Duration(s.Percentile(99))
+	r.P999 = time.Duration(s.Percentile(99.9))
+	return r
+}
+
+func (s reportStat) String() string {
+	if s.Count == 0 {
+		return niceDuration(s.Total)
+	}
+	return fmt.Sprintf("%s [%s/%s/%s] p99:%s", niceDuration(s.Total), niceDuration(s.Min), niceDuration(s.Avg), niceDuration(s.Max), niceDuration(s.P99))
+}
+
+func newReportLatency(durations []time.Duration) reportLatency {
+	l := reportLatency{Complete: len(durations)}
+	if len(durations) == 0 {
+		return l
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	l.Min = sorted[0]
+	l.P50 = durationQuantile(sorted, 0.50)
+	l.P90 = durationQuantile(sorted, 0.90)
+	l.P95 = durationQuantile(sorted, 0.95)
+	l.P99 = durationQuantile(sorted, 0.99)
+	l.Max = sorted[len(sorted)-1]
+	var sum float64
+	for _, d := range sorted {
+		sum += float64(d)
+	}
+	mean := sum / float64(len(sorted))
+	l.Mean = time.Duration(mean)
+	if len(sorted) > 1 {
+		var sq float64
+		for _, d := range sorted {
+			sq += (float64(d) - mean) * (float64(d) - mean)
+		}
+		l.Stddev = time.Duration(math.Sqrt(sq / float64(len(sorted)-1)))
+	}
+	return l
+}
+
+// durationQuantile returns the q-quantile of the sorted durations
+// using the nearest-rank method.
+func durationQuantile(sorted []time.Duration, q float64) time.Duration {
+	i := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
+
+// reportTable is a section of the text and Markdown reports.
+type reportTable struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+func (rep *report) tables() []reportTable {
+	groups := reportTable{
+		title:  "Goroutines",
+		header: []string{"Goroutine", "Count", "Total", "Execution", "Network wait", "Sync block", "Blocking syscall", "Scheduler wait", "GC sweeping", "GC pause", "GC mark assist"},
+	}
+	for _, g := range rep.Goroutines {
+		row := []string{g.Name, fmt.Sprint(g.N)}
+		for _, s := range []reportStat{g.TotalTime, g.ExecTime, g.IOTime, g.BlockTime, g.SyscallTime, g.SchedWaitTime, g.SweepTime, g.GCTime, g.MarkAssistTime} {
+			row = append(row, s.String())
+		}
+		groups.rows = append(groups.rows, row)
+	}
+
+	latencyHeader := []string{"Count", "Complete", "Min", "p50", "p90", "p95", "p99", "Max"}
+	latencyRow := func(l reportLatency) []string {
+		row := []string{fmt.Sprint(l.Count), fmt.Sprint(l.Complete)}
+		for _, d := range []time.Duration{l.Min, l.P50, l.P90, l.P95, l.P99, l.Max} {
+			row = append(row, niceDuration(d))
+		}
+		return row
+	}
+	tasks := reportTable{
+		title:  "User tasks",
+		header: append([]string{"Task type"}, latencyHeader...),
+	}
+	for _, l := range rep.Tasks {
+		tasks.rows = append(tasks.rows, append([]string{l.Type}, latencyRow(l)...))
+	}
+	regions := reportTable{
+		title:  "User regions",
+		header: append([]string{"Region type", "Function"}, latencyHeader...),
+	}
+	for _, l := range rep.Regions {
+		regions.rows = append(regions.rows, append([]string{l.Type, l.Func}, latencyRow(l)...))
+	}
+
+	gc := reportTable{
+		title:  "GC",
+		header: []string{"Metric", "Value"},
+		rows: [][]string{
+			{"GC cycles", fmt.Sprint(rep.GC.Count)},
+			{"GC time", fmt.Sprintf("%s (%.1f%% of trace)", niceDuration(rep.GC.Time), percentOf(rep.GC.Time, rep.Duration))},
+			{"STW pauses", fmt.Sprint(rep.GC.STWCount)},
+			{"STW total", niceDuration(rep.GC.STWTime)},
+			{"STW max", niceDuration(rep.GC.STWMax)},
+		},
+	}
+	for _, m := range rep.GC.MMU {
+		gc.rows = append(gc.rows, []string{"MMU " + m.Window.String(), fmt.Sprintf("%.3f", m.MMU)})
+	}
+
+	leaks := reportTable{
+		title:  "Possibly leaked goroutines",
+		header: []string{"Goroutine", "Count", "Blocked on", "Blocked at", "Created at", "First blocked at"},
+	}
+	for _, l := range rep.Leaks {
+		leaks.rows = append(leaks.rows, []string{l.Goroutine, fmt.Sprint(l.Count), l.Kind, l.BlockedAt, l.CreatedAt, niceDuration(l.Since)})
+	}
+	return []reportTable{groups, tasks, regions, gc, leaks}
+}
+
+func percentOf(d, total time.Duration) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(d) / float64(total) * 100
+}
+
+func writeReportText(w io.Writer, rep *report) error {
+	fmt.Fprintf(w, "Trace duration: %s\n", niceDuration(rep.Duration))
+	for _, t := range rep.tables() {
+		fmt.Fprintf(w, "\n%s\n%s\n", t.title, strings.Repeat("=", len(t.title)))
+		if len(t.rows) == 0 {
+			fmt.Fprintf(w, "(none)\n")
+			continue
+		}
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeReportMarkdown(w io.Writer, rep *report) error {
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	fmt.Fprintf(w, "Trace duration: %s\n", niceDuration(rep.Duration))
+	for _, t := range rep.tables() {
+		fmt.Fprintf(w, "\n## %s\n\n", t.title)
+		if len(t.rows) == 0 {
+			fmt.Fprintf(w, "(none)\n")
+			continue
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(t.header, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(t.header)))
+		for _, row := range t.rows {
+			for i := range row {
+				row[i] = cell.Replace(row[i])
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+	return nil
+}
+
+func writeReportJSON(w io.Writer, rep *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
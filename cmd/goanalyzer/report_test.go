@@ -0,0 +1,104 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	rtrace "runtime/trace"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	if err := traceProgram(t, prog0, "TestReport"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := mainTrace.writeReport(&buf, "json"); err != nil {
+		t.Fatalf("failed to write report: %v", err)
+	}
+	var rep report
+	if err := json.Unmarshal(buf.Bytes(), &rep); err != nil {
+		t.Fatalf("failed to decode report: %v\n%s", err, buf.String())
+	}
+	if len(rep.Goroutines) == 0 {
+		t.Errorf("report has no goroutine groups")
+	}
+	tasks := make(map[string]reportLatency)
+	for _, l := range rep.Tasks {
+		tasks[l.Type] = l
+	}
+	for _, name := range []string{"task0", "task1"} {
+		if l := tasks[name]; l.Count != 1 || l.Complete != 1 || l.Max < l.Min {
+			t.Errorf("task %s: got %+v, want one complete task", name, l)
+		}
+	}
+	var region bool
+	for _, l := range rep.Regions {
+		if l.Type == "task0.region1" && l.Complete == 1 && l.P99 > 0 {
+			region = true
+		}
+	}
+	if !region {
+		t.Errorf("report has no complete task0.region1: %+v", rep.Regions)
+	}
+
+	for _, test := range []struct {
+		format string
+		want   []string
+	}{
+		{"text", []string{"Goroutines\n==========", "User tasks", "task0", "GC cycles"}},
+		{"markdown", []string{"## Goroutines", "| Task type | Count |", "| task0 | 1 | 1 |", "## GC"}},
+	} {
+		buf.Reset()
+		if err := mainTrace.writeReport(&buf, test.format); err != nil {
+			t.Fatalf("failed to write %s report: %v", test.format, err)
+		}
+		for _, want := range test.want {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("%s report does not contain %q:\n%s", test.format, want, buf.String())
+			}
+		}
+	}
+
+	if err := mainTrace.writeReport(&buf, "xml"); err == nil {
+		t.Errorf("no error for unknown report format")
+	}
+}
+
+func TestStreamReport(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := rtrace.Start(buf); err != nil {
+		t.Fatal(err)
+	}
+	prog0()
+	runtime.GC()
+	rtrace.Stop()
+	file := filepath.Join(t.TempDir(), "prog0.trace")
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := streamReport(file, "")
+	if err != nil {
+		t.Fatalf("streamReport: %v", err)
+	}
+	want, err := newTraceState(file, "").buildReport()
+	if err != nil {
+		t.Fatalf("buildReport: %v", err)
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !reflect.DeepEqual(gotJSON, wantJSON) {
+		t.Errorf("streamed report differs from the report of all events:\ngot  %s\nwant %s", gotJSON, wantJSON)
+	}
+	if len(got.Tasks) == 0 || got.GC.Count == 0 {
+		t.Errorf("streamed report has no tasks or GC cycles: %s", gotJSON)
+	}
+}
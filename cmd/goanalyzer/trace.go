@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
 	"io"
+	"io/fs"
 	"log"
 	"math"
 	"net/http"
+	"os"
 	"path/filepath"
 	"runtime"
 	"runtime/debug"
@@ -25,11 +27,13 @@ func init() {
 	http.HandleFunc("/trace", httpTrace)
 	http.HandleFunc("/jsontrace", httpJsonTrace)
 	http.HandleFunc("/trace_viewer_html", httpTraceViewerHTML)
+	http.HandleFunc("/static/", httpTraceViewerStatic)
 }
 
 // httpTrace serves either whole trace (goid==0) or trace for goid goroutine.
 func httpTrace(w http.ResponseWriter, r *http.Request) {
-	_, err := parseTrace()
+	st := requestTrace(r)
+	_, err := st.parseTrace()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -43,14 +47,27 @@ func httpTrace(w http.ResponseWriter, r *http.Request) {
 
 }
 
-// See https://github.com/catapult-project/catapult/blob/master/tracing/docs/embedding-trace-viewer.md
+// See https://chromium.googlesource.com/catapult/+/9508452e18f130c98499cb4c4f1e1efaedee8962/tracing/docs/embedding-trace-viewer.md
 // This is almost verbatim copy of:
-// https://github.com/catapult-project/catapult/blob/master/tracing/bin/index.html
-// on revision 5f9e4c3eaa555bdef18218a89f38c768303b7b6e.
+// https://chromium-review.googlesource.com/c/catapult/+/2062938/2/tracing/bin/index.html
+// The webcomponents polyfill provides HTML imports, which browsers dropped.
 var templTrace = `
 <html>
 <head>
-<link href="/trace_viewer_html" rel="import">
+<script src="/static/webcomponents.min.js"></script>
+<script>
+'use strict';
+
+function onTraceViewerImportFail() {
+  document.addEventListener('DOMContentLoaded', function() {
+    document.body.textContent =
+    '/static/trace_viewer_full.html is missing: the binary was built without the trace viewer and it is not in GOROOT';
+  });
+}
+</script>
+
+<link rel="import" href="/static/trace_viewer_full.html"
+      onerror="onTraceViewerImportFail(event)">
 <style type="text/css">
   html, body {
     box-sizing: border-box;
@@ -160,17 +177,57 @@ var templTrace = `
 `
 
 // httpTraceViewerHTML serves static part of trace-viewer.
-// This URL is queried from templTrace HTML.
+// It is kept for pages that still link to it, templTrace uses /static/.
 func httpTraceViewerHTML(w http.ResponseWriter, r *http.Request) {
-	http.ServeFile(w, r, filepath.Join(runtime.GOROOT(), "misc", "trace", "trace_viewer_full.html"))
+	serveTraceViewerFile(w, r, "trace_viewer_full.html")
+}
+
+// httpTraceViewerStatic serves the trace viewer and the webcomponents
+// polyfill it needs, queried from templTrace HTML.
+func httpTraceViewerStatic(w http.ResponseWriter, r *http.Request) {
+	serveTraceViewerFile(w, r, strings.TrimPrefix(r.URL.Path, "/static/"))
+}
+
+// traceViewerFS holds the trace viewer files embedded in the binary,
+// or is nil if the binary is built with the noembed tag.
+var traceViewerFS fs.FS
+
+// traceViewerGOROOTDirs are the directories of GOROOT where Go releases
+// keep the trace viewer files, newest first.
+var traceViewerGOROOTDirs = []string{
+	"src/internal/trace/traceviewer/static", // Go 1.22 and later
+	"src/cmd/trace/static",                  // Go 1.19 to 1.21
+	"misc/trace",                            // Go 1.18 and earlier
+}
+
+// serveTraceViewerFile serves a trace viewer file from the binary, or
+// else from GOROOT.
+func serveTraceViewerFile(w http.ResponseWriter, r *http.Request, name string) {
+	if name != "trace_viewer_full.html" && name != "webcomponents.min.js" {
+		http.NotFound(w, r)
+		return
+	}
+	if traceViewerFS != nil {
+		http.ServeFileFS(w, r, traceViewerFS, name)
+		return
+	}
+	for _, dir := range traceViewerGOROOTDirs {
+		path := filepath.Join(runtime.GOROOT(), filepath.FromSlash(dir), name)
+		if _, err := os.Stat(path); err == nil {
+			http.ServeFile(w, r, path)
+			return
+		}
+	}
+	http.NotFound(w, r)
 }
 
 // httpJsonTrace serves json trace, requested from within templTrace HTML.
 func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
 	defer debug.FreeOSMemory()
 	defer reportMemoryUsage("after httpJsonTrace")
 	// This is an AJAX handler, so instead of http.Error we use log.Printf to log errors.
-	res, err := parseTrace()
+	res, err := st.parseTrace()
 	if err != nil {
 		log.Printf("failed to parse trace: %v", err)
 		return
@@ -185,9 +242,11 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 
 		gids := strings.Split(goids, ",")
 
-		analyzeGoroutines(res.Events)
+		st.analyzeGoroutines(res.Events)
 
 		gmap := make(map[uint64]bool)
+		pcs := make(map[uint64]bool)
+		params.groupNames = make(map[uint64]string)
 
 		for _, goids0 := range gids {
 			// If goid argument is present, we are rendering a trace for this particular goroutine(s)
@@ -196,7 +255,7 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 				log.Printf("failed to parse goid parameter %q: %v", goids, err)
 				return
 			}
-			g, ok := gs[goid]
+			g, ok := st.gs[goid]
 			if !ok {
 				log.Printf("failed to find goroutine %d", goid)
 				return
@@ -206,13 +265,16 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 			if g.EndTime != 0 {
 				params.endTime = g.EndTime
 			} else { // The goroutine didn't end.
-				params.endTime = lastTimestamp()
+				params.endTime = st.lastTimestamp()
 			}
 
 			if len(gids) == 1 {
 				params.maing = goid
 			}
 
+			pcs[g.PC] = true
+			params.groupNames[g.PC] = g.Name
+
 			gmap0 := trace.RelatedGoroutines(res.Events, goid)
 			for k, v := range gmap0 {
 				if v == true {
@@ -221,13 +283,14 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 		params.gs = gmap
+		params.groups = st.newGroupStates(pcs)
 	} else if taskids := r.FormValue("taskid"); taskids != "" {
 		taskid, err := strconv.ParseUint(taskids, 10, 64)
 		if err != nil {
 			log.Printf("failed to parse taskid parameter %q: %v", taskids, err)
 			return
 		}
-		annotRes, _ := analyzeAnnotations()
+		annotRes, _ := st.analyzeAnnotations()
 		task, ok := annotRes.tasks[taskid]
 		if !ok || len(task.events) == 0 {
 			log.Printf("failed to find task with id %d", taskid)
@@ -246,6 +309,15 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 				gs[k] = v
 			}
 		}
+		if r.FormValue("critpath") != "" {
+			st.analyzeCritPaths(res.Events)
+			if p := st.critIndex.taskCritPath(task); p != nil {
+				params.critPath = p
+				for k := range p.Goroutines() {
+					gs[k] = true
+				}
+			}
+		}
 		params.gs = gs
 	} else if taskids := r.FormValue("focustask"); taskids != "" {
 		taskid, err := strconv.ParseUint(taskids, 10, 64)
@@ -253,7 +325,7 @@ func httpJsonTrace(w http.ResponseWriter, r *http.Request) {
 			log.Printf("failed to parse focustask parameter %q: %v", taskids, err)
 			return
 		}
-		annotRes, _ := analyzeAnnotations()
+		annotRes, _ := st.analyzeAnnotations()
 		task, ok := annotRes.tasks[taskid]
 		if !ok || len(task.events) == 0 {
 			log.Printf("failed to find task with id %d", taskid)
@@ -364,6 +436,7 @@ func splittingTraceConsumer(max int) (*splitter, traceConsumer) {
 
 			// Then calculate size of each individual event
 			// and group them into ranges.
+			var ranges []Range
 			sum := minSize
 			start := 0
 			for i, ev := range sizes {
@@ -419,6 +492,12 @@ type traceParams struct {
 	maing     uint64          // for goroutine-oriented view, place this goroutine on the top row
 	gs        map[uint64]bool // Goroutines to be displayed for goroutine-oriented or task-oriented view
 	tasks     []*taskDesc     // Tasks to be displayed. tasks[0] is the top-most task
+	critPath  *critPath       // Critical path of tasks[0] to be displayed, if any
+
+	// Goroutine groups, by start PC, whose state counts are displayed
+	// for goroutine-oriented view, with their names.
+	groups     *groupStates
+	groupNames map[uint64]string
 }
 
 type traceviewMode uint
@@ -467,6 +546,7 @@ const (
 	gRunning
 	gWaiting
 	gWaitingGC
+	gSyscall // blocked in a syscall, outside of the viewer, which counts it as gWaiting
 
 	gStateCount
 )
@@ -502,6 +582,10 @@ type ViewerEvent struct {
 	Arg      interface{} `json:"args,omitempty"`
 	Cname    string      `json:"cname,omitempty"`
 	Category string      `json:"cat,omitempty"`
+
+	// G is the goroutine of a slice, instant or arrow end on a P, so
+	// that the Perfetto export gets both views from one pass.
+	G uint64 `json:"-"`
 }
 
 type ViewerFrame struct {
@@ -603,12 +687,12 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 				return fmt.Errorf("duplicate go create event for go id=%d detected at offset %d", newG, ev.Off)
 			}
 
-			stk, ok := stacks[ev.Args[1]]
-			if !ok || len(stk) == 0 {
-				return fmt.Errorf("invalid go create event: missing stack information for go id=%d at offset %d", newG, ev.Off)
+			// Goroutines that never stop while a Go 1.22+ trace is
+			// recorded may have no known start function.
+			var fname string
+			if stk := stacks[ev.Args[1]]; len(stk) > 0 {
+				fname = stk[0].Fn
 			}
-
-			fname := stk[0].Fn
 			info.name = fmt.Sprintf("G%v %s", newG, fname)
 			info.isSystemG = isSystemGoroutine(fname)
 
@@ -663,6 +747,11 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 		if setGStateErr != nil {
 			return setGStateErr
 		}
+		if ctx.groups != nil {
+			if pc, ok := ctx.groups.update(ev); ok {
+				ctx.emitGroupCounters(ev, pc)
+			}
+		}
 		if ctx.gstates[gRunnable] < 0 || ctx.gstates[gRunning] < 0 || ctx.threadStats.insyscall < 0 || ctx.threadStats.insyscallRuntime < 0 {
 			return fmt.Errorf("invalid state after processing %v: runnable=%d running=%d insyscall=%d insyscallRuntime=%d", ev, ctx.gstates[gRunnable], ctx.gstates[gRunning], ctx.threadStats.insyscall, ctx.threadStats.insyscallRuntime)
 		}
@@ -757,6 +846,11 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 			ctx.emitInstant(ev, "task start", "user event")
 		case trace.EvUserTaskEnd:
 			ctx.emitInstant(ev, "task end", "user event")
+		case trace.EvCPUSample:
+			if ev.P >= 0 {
+				// only show in this UI when there's an associated P
+				ctx.emitInstant(ev, "CPU profile sample", "")
+			}
 		}
 		// Emit any counter updates.
 		ctx.emitThreadCounters(ev)
@@ -811,6 +905,9 @@ func generateTrace(params *traceParams, consumer traceConsumer) error {
 			return ti.firstTimestamp() < tj.firstTimestamp()
 		})
 
+		if ctx.critPath != nil {
+			ctx.emitCritPath(ctx.critPath, ctx.tasks[0].id)
+		}
 		for i, task := range sortedTask {
 			ctx.emitTask(task, i)
 
@@ -897,6 +994,7 @@ func (ctx *traceContext) makeSlice(ev *trace.Event, name string) *ViewerEvent {
 		Tid:      ctx.proc(ev),
 		Stack:    ctx.stack(ev.Stk),
 		EndStack: ctx.stack(ev.Link.Stk),
+		G:        ev.G,
 	}
 
 	// grey out non-overlapping events if the event is not a global event (ev.G == 0)
@@ -1043,6 +1141,22 @@ func (ctx *traceContext) emitGoroutineCounters(ev *trace.Event) {
 	ctx.prevGstates = ctx.gstates
 }
 
+type groupCountersArg struct {
+	Running  int64
+	Runnable int64
+	Blocked  int64
+	Syscall  int64
+}
+
+func (ctx *traceContext) emitGroupCounters(ev *trace.Event, pc uint64) {
+	if !tsWithinRange(ev.Ts, ctx.startTime, ctx.endTime) {
+		return
+	}
+	c := ctx.groups.counts[pc]
+	name := fmt.Sprintf("Goroutines (%s)", ctx.groupNames[pc])
+	ctx.emit(&ViewerEvent{Name: name, Phase: "C", Time: ctx.time(ev), Pid: 1, Arg: &groupCountersArg{c.Running, c.Runnable, c.Blocked, c.Syscall}})
+}
+
 type threadCountersArg struct {
 	Running   int64
 	InSyscall int64
@@ -1100,7 +1214,8 @@ func (ctx *traceContext) emitInstant(ev *trace.Event, name, category string) {
 		Tid:      ctx.proc(ev),
 		Stack:    ctx.stack(ev.Stk),
 		Cname:    cname,
-		Arg:      arg})
+		Arg:      arg,
+		G:        ev.G})
 }
 
 func (ctx *traceContext) emitArrow(ev *trace.Event, name string) {
@@ -1135,8 +1250,8 @@ func (ctx *traceContext) emitArrow(ev *trace.Event, name string) {
 	}
 
 	ctx.arrowSeq++
-	ctx.emit(&ViewerEvent{Name: name, Phase: "s", Tid: ctx.proc(ev), ID: ctx.arrowSeq, Time: ctx.time(ev), Stack: ctx.stack(ev.Stk), Cname: color})
-	ctx.emit(&ViewerEvent{Name: name, Phase: "t", Tid: ctx.proc(ev.Link), ID: ctx.arrowSeq, Time: ctx.time(ev.Link), Cname: color})
+	ctx.emit(&ViewerEvent{Name: name, Phase: "s", Tid: ctx.proc(ev), ID: ctx.arrowSeq, Time: ctx.time(ev), Stack: ctx.stack(ev.Stk), Cname: color, G: ev.G})
+	ctx.emit(&ViewerEvent{Name: name, Phase: "t", Tid: ctx.proc(ev.Link), ID: ctx.arrowSeq, Time: ctx.time(ev.Link), Cname: color, G: ev.Link.G})
 }
 
 func (ctx *traceContext) stack(stk []*trace.Frame) int {
@@ -1170,21 +1285,15 @@ func isSystemGoroutine(entryFn string) bool {
 }
 
 // firstTimestamp returns the timestamp of the first event record.
-func firstTimestamp() int64 {
-	res, _ := parseTrace()
-	if len(res.Events) > 0 {
-		return res.Events[0].Ts
-	}
-	return 0
+func (st *traceState) firstTimestamp() int64 {
+	first, _, _ := st.loader.bounds()
+	return first
 }
 
 // lastTimestamp returns the timestamp of the last event record.
-func lastTimestamp() int64 {
-	res, _ := parseTrace()
-	if n := len(res.Events); n > 1 {
-		return res.Events[n-1].Ts
-	}
-	return 0
+func (st *traceState) lastTimestamp() int64 {
+	_, last, _ := st.loader.bounds()
+	return last
 }
 
 type jsonWriter struct {
@@ -8,11 +8,15 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
 	"io/ioutil"
+	"net/http/httptest"
 	rtrace "runtime/trace"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // stacks is a fake stack map populated for test.
@@ -187,11 +191,11 @@ func TestFoo(t *testing.T) {
 	if err := traceProgram(t, prog0, "TestFoo"); err != nil {
 		t.Fatalf("failed to trace the program: %v", err)
 	}
-	res, err := parseTrace()
+	res, err := mainTrace.parseTrace()
 	if err != nil {
 		t.Fatalf("failed to parse the trace: %v", err)
 	}
-	annotRes, _ := analyzeAnnotations()
+	annotRes, _ := mainTrace.analyzeAnnotations()
 	var task *taskDesc
 	for _, t := range annotRes.tasks {
 		if t.name == "ohHappyDay" {
@@ -233,3 +237,93 @@ func TestFoo(t *testing.T) {
 	}
 
 }
+
+func TestTraceViewerStatic(t *testing.T) {
+	for _, test := range []struct {
+		path string
+		code int
+		want string
+	}{
+		{"/static/webcomponents.min.js", 200, "webcomponents"},
+		{"/static/trace_viewer_full.html", 200, "tr-ui-timeline-view"},
+		{"/trace_viewer_html", 200, "tr-ui-timeline-view"},
+		{"/static/README.md", 404, ""},
+		{"/static/../main.go", 404, ""},
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", test.path, nil)
+		if strings.HasPrefix(test.path, "/static/") {
+			httpTraceViewerStatic(w, r)
+		} else {
+			httpTraceViewerHTML(w, r)
+		}
+		if w.Code != test.code {
+			t.Errorf("%s: got status %d, want %d", test.path, w.Code, test.code)
+			continue
+		}
+		if !strings.Contains(w.Body.String(), test.want) {
+			t.Errorf("%s: body does not contain %q", test.path, test.want)
+		}
+	}
+}
+
+// groupWorker waits for c to be closed.
+func groupWorker(c chan int, wg *sync.WaitGroup) {
+	defer wg.Done()
+	<-c
+}
+
+func TestGroupCounters(t *testing.T) {
+	prog := func() {
+		var wg sync.WaitGroup
+		c := make(chan int)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go groupWorker(c, &wg)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(c)
+		wg.Wait()
+	}
+	if err := traceProgram(t, prog, "TestGroupCounters"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	events, err := mainTrace.parseEvents()
+	if err != nil {
+		t.Fatalf("failed to parse events: %v", err)
+	}
+	mainTrace.analyzeGoroutines(events)
+	var worker *trace.GDesc
+	for _, g := range mainTrace.gs {
+		if strings.HasSuffix(g.Name, ".groupWorker") {
+			worker = g
+			break
+		}
+	}
+	if worker == nil {
+		t.Fatalf("no groupWorker goroutine")
+	}
+
+	series := mainTrace.computeGroupSeries(events, worker.PC)
+	var maxBlocked float64
+	for _, v := range series.Blocked {
+		if v > maxBlocked {
+			maxBlocked = v
+		}
+	}
+	if len(series.Running) != procSeriesBuckets || maxBlocked < 4 || maxBlocked > 5 {
+		t.Errorf("%d buckets with at most %v blocked goroutines, want %d with about 5", len(series.Running), maxBlocked, procSeriesBuckets)
+	}
+
+	// The trace of a goroutine shows the counts of its group.
+	w := httptest.NewRecorder()
+	httpJsonTrace(w, httptest.NewRequest("GET", fmt.Sprintf("/jsontrace?goid=%d", worker.ID), nil))
+	if name := fmt.Sprintf(`"Goroutines (%s)"`, worker.Name); !strings.Contains(w.Body.String(), name) {
+		t.Errorf("goroutine trace has no %s counter", name)
+	}
+	w = httptest.NewRecorder()
+	httpGoroutine(w, httptest.NewRequest("GET", fmt.Sprintf("/goroutine?id=%d", worker.PC), nil))
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "drawSeries('chart'") {
+		t.Errorf("/goroutine: status %d: %s", w.Code, w.Body)
+	}
+}
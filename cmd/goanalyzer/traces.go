@@ -0,0 +1,527 @@
+// Serving of the traces in a directory.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// traceCookie selects the trace of a request in directory mode.
+const traceCookie = "goanalyzer-trace"
+
+// tracesDir is the directory of traces being served, nil when serving
+// a single trace.
+var tracesDir *traceDir
+
+// traceDir serves the traces in a directory, including the traces
+// uploaded to it. Requests are for the trace selected by traceCookie,
+// whose state the handlers get with requestTrace.
+type traceDir struct {
+	dir       string
+	maxMem    uint64 // heap size above which parsed traces are evicted
+	maxUpload int64  // size of the largest trace that can be uploaded
+	def       string // trace of the requests without traceCookie, if any
+
+	mu     sync.Mutex
+	traces map[string]*traceEntry // by file name
+}
+
+// traceEntry is a trace file of a traceDir.
+type traceEntry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+	Version string // of Go, like 1.22
+	// Metadata, set when the trace is first parsed.
+	Meta *traceMeta
+	Err  string // parse error
+
+	state    *traceState // nil unless opened and not evicted
+	lastUsed time.Time
+}
+
+type traceMeta struct {
+	Duration   time.Duration
+	GOMAXPROCS int // at the end of the trace
+	Events     int
+}
+
+func newTraceDir(dir string, maxMem uint64) *traceDir {
+	return &traceDir{dir: dir, maxMem: maxMem, maxUpload: maxUploadSize, traces: make(map[string]*traceEntry)}
+}
+
+// maxUploadSize is the default traceDir.maxUpload.
+const maxUploadSize = 1 << 30
+
+// serveTraceDir serves the traces in dir on the -http address, with
+// the trace def, if not empty, open in browsers that have not opened
+// another.
+func serveTraceDir(dir, def string) {
+	tracesDir = newTraceDir(dir, uint64(*maxMemFlag)<<20)
+	tracesDir.def = def
+	tracesDir.mu.Lock()
+	err := tracesDir.scan()
+	tracesDir.mu.Unlock()
+	if err != nil {
+		dief("%v\n", err)
+	}
+	if def != "" {
+		// Parse the trace before serving, as for a single trace, so
+		// that the first page is fast and a bad trace fails at once.
+		e := tracesDir.traces[def]
+		if e == nil {
+			dief("%s is not a trace\n", filepath.Join(dir, def))
+		}
+		if _, err := tracesDir.load(e); err != nil {
+			dief("%v\n", err)
+		}
+	}
+	ln, err := net.Listen("tcp", *httpFlag)
+	if err != nil {
+		dief("failed to create server socket: %v\n", err)
+	}
+	log.Printf("Serving the %d traces in %s on http://%s", len(tracesDir.traces), dir, ln.Addr())
+	http.HandleFunc("/", httpMain)
+	err = http.Serve(ln, tracesDir)
+	dief("failed to start http server: %v\n", err)
+}
+
+// scan updates the trace files from the directory. d.mu must be held.
+func (d *traceDir) scan() error {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read trace directory: %v", err)
+	}
+	seen := make(map[string]bool)
+	for _, f := range files {
+		if !f.Type().IsRegular() {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+		name := f.Name()
+		if e := d.traces[name]; e != nil && e.Size == info.Size() && e.ModTime.Equal(info.ModTime()) {
+			seen[name] = true
+			continue
+		}
+		version, err := traceVersion(filepath.Join(d.dir, name))
+		if err != nil {
+			continue // not a trace
+		}
+		d.traces[name] = &traceEntry{Name: name, Size: info.Size(), ModTime: info.ModTime(), Version: version}
+		seen[name] = true
+	}
+	for name := range d.traces {
+		if !seen[name] {
+			delete(d.traces, name)
+		}
+	}
+	return nil
+}
+
+// traceVersion returns the version of Go that produced the trace file.
+func traceVersion(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	ver, err := trace.ReadVersion(f)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", ver/1000, ver%1000), nil
+}
+
+// load returns the state of the trace of e, parsing the trace unless it
+// is already parsed. The requests for the other traces are served while
+// it is parsed.
+func (d *traceDir) load(e *traceEntry) (*traceState, error) {
+	d.mu.Lock()
+	st := e.state
+	if st == nil {
+		log.Printf("Parsing trace %s...", e.Name)
+		st = newTraceState(filepath.Join(d.dir, e.Name), "")
+		e.state = st
+	}
+	e.lastUsed = time.Now()
+	d.mu.Unlock()
+
+	m, err := st.open()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err != nil {
+		e.Err = err.Error()
+		if e.state == st {
+			e.state = nil // parse it again when opened next
+		}
+		return nil, err
+	}
+	e.Err = ""
+	if e.Meta == nil {
+		e.Meta = m
+	}
+	d.evict(e)
+	return st, nil
+}
+
+func newTraceMeta(events []*trace.Event) *traceMeta {
+	m := &traceMeta{Events: len(events)}
+	if n := len(events); n > 0 {
+		m.Duration = time.Duration(events[n-1].Ts - events[0].Ts)
+	}
+	for _, ev := range events {
+		if ev.Type == trace.EvGomaxprocs {
+			m.GOMAXPROCS = int(ev.Args[0])
+		}
+	}
+	return m
+}
+
+// evict drops the least recently used parsed traces other than cur
+// while the heap is larger than d.maxMem. d.mu must be held.
+func (d *traceDir) evict(cur *traceEntry) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	evicted := false
+	for ms.HeapAlloc > d.maxMem {
+		var lru *traceEntry
+		for _, e := range d.traces {
+			if e != cur && e.state != nil && (lru == nil || e.lastUsed.Before(lru.lastUsed)) {
+				lru = e
+			}
+		}
+		if lru == nil {
+			break
+		}
+		log.Printf("Evicting trace %s", lru.Name)
+		lru.state = nil
+		evicted = true
+		runtime.GC()
+		runtime.ReadMemStats(&ms)
+	}
+	if evicted {
+		debug.FreeOSMemory()
+	}
+}
+
+func (d *traceDir) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/traces":
+		d.httpIndex(w, r)
+		return
+	case "/traces/open":
+		d.httpOpen(w, r)
+		return
+	case "/traces/upload":
+		d.httpUpload(w, r)
+		return
+	case "/traces/capture":
+		d.httpCapture(w, r)
+		return
+	}
+
+	d.mu.Lock()
+	var e *traceEntry
+	if c, err := r.Cookie(traceCookie); err == nil {
+		e = d.traces[c.Value]
+	} else if d.def != "" {
+		e = d.traces[d.def]
+	}
+	d.mu.Unlock()
+	if e == nil {
+		http.Redirect(w, r, "/traces", http.StatusFound)
+		return
+	}
+	st, err := d.load(e)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.DefaultServeMux.ServeHTTP(w, withTrace(r, st))
+}
+
+// open selects the trace name for the following requests of the
+// browser.
+func (d *traceDir) open(w http.ResponseWriter, r *http.Request, name string) {
+	http.SetCookie(w, &http.Cookie{Name: traceCookie, Value: name, Path: "/"})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func (d *traceDir) httpOpen(w http.ResponseWriter, r *http.Request) {
+	name := r.FormValue("name")
+	d.mu.Lock()
+	err := d.scan()
+	e := d.traces[name]
+	d.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if e == nil {
+		http.Error(w, fmt.Sprintf("no trace %q", name), http.StatusNotFound)
+		return
+	}
+	d.open(w, r, name)
+}
+
+// sameOrigin reports whether r comes from a page of this server, or
+// from a client other than a browser. The forms of the index change the
+// directory and make the server fetch URLs, so other sites open in the
+// browser must not be able to post them.
+func sameOrigin(r *http.Request) bool {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" {
+		return site == "same-origin" || site == "none"
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // not sent by browsers predating Sec-Fetch-Site either
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// httpUpload stores the uploaded trace in the directory, under a new
+// name if its name is taken, and opens it.
+func (d *traceDir) httpUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "upload with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin upload", http.StatusForbidden)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, d.maxUpload)
+	file, header, err := r.FormFile("trace")
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, fmt.Sprintf("failed to read upload: %v", err), status)
+		return
+	}
+	defer file.Close()
+	if _, err := trace.ReadVersion(file); err != nil {
+		http.Error(w, fmt.Sprintf("%s is not a trace: %v", header.Filename, err), http.StatusBadRequest)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	base := filepath.Base(header.Filename)
+	if base == "." || base == string(filepath.Separator) || strings.HasPrefix(base, ".") {
+		base = "upload.trace"
+	}
+	f, name, err := createTrace(d.dir, base)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to store upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	_, err = io.Copy(f, file)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		http.Error(w, fmt.Sprintf("failed to store upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if err := d.scan(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Stored uploaded trace %s", name)
+	d.open(w, r, name)
+}
+
+// createTrace creates the file base in dir, or base with a numeric
+// suffix if the name is taken, and returns it with its name.
+func createTrace(dir, base string) (*os.File, string, error) {
+	ext := filepath.Ext(base)
+	name := base
+	for i := 1; ; i++ {
+		f, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if !os.IsExist(err) {
+			return f, name, err
+		}
+		name = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(base, ext), i, ext)
+	}
+}
+
+// httpCapture captures a trace from the server of the form, stores it
+// in the directory and opens it.
+func (d *traceDir) httpCapture(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "capture with POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if !sameOrigin(r) {
+		http.Error(w, "cross-origin capture", http.StatusForbidden)
+		return
+	}
+	target := r.FormValue("url")
+	if target == "" {
+		http.Error(w, "no url to capture from", http.StatusBadRequest)
+		return
+	}
+	seconds, err := strconv.Atoi(r.FormValue("seconds"))
+	if err != nil || seconds <= 0 {
+		http.Error(w, fmt.Sprintf("bad seconds %q", r.FormValue("seconds")), http.StatusBadRequest)
+		return
+	}
+	// Other requests are served meanwhile: the trace is only added to
+	// d.traces by the scan.
+	file, err := captureTrace(target, seconds, d.dir)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to capture trace: %v", err), http.StatusBadGateway)
+		return
+	}
+	d.mu.Lock()
+	err = d.scan()
+	d.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("Stored captured trace %s", file)
+	d.open(w, r, filepath.Base(file))
+}
+
+// httpIndex serves the list of traces.
+func (d *traceDir) httpIndex(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	err := d.scan()
+	var list []traceEntry
+	for _, e := range d.traces {
+		list = append(list, *e)
+	}
+	d.mu.Unlock()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ModTime.After(list[j].ModTime) })
+	cur := d.def
+	if c, err := r.Cookie(traceCookie); err == nil {
+		cur = c.Value
+	}
+	err = templTraces.Execute(w, struct {
+		Dir     string
+		Current string
+		Traces  []traceEntry
+	}{d.dir, cur, list})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// niceSize formats n bytes for display.
+func niceSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f kB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+var templTraces = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+	"loaded":       func(e traceEntry) bool { return e.state != nil },
+	"size":         niceSize,
+}).Parse(`
+<!DOCTYPE html>
+<title>Traces</title>
+<style>
+th {
+  background-color: #050505;
+  color: #fff;
+}
+table {
+  border-collapse: collapse;
+}
+.details tr:hover {
+  background-color: #f2f2f2;
+}
+.details td {
+  border: 1px solid black;
+  padding: 0.2em 0.5em;
+}
+.current {
+  font-weight: bold;
+}
+</style>
+<body>
+<h2>Traces in {{.Dir}}</h2>
+<form action="/traces/upload" method="post" enctype="multipart/form-data">
+Upload a trace: <input type="file" name="trace"> <input type="submit" value="Upload">
+</form>
+<form action="/traces/capture" method="post">
+Capture a trace from the net/http/pprof server at <input type="text" name="url" size="30" placeholder="http://localhost:6060">
+for <input type="number" name="seconds" value="5" min="1" style="width: 4em"> seconds
+<input type="submit" value="Capture">
+</form>
+<p>Duration, GOMAXPROCS and event count are known once a trace has been opened. Parsed traces
+are dropped, least recently used first, when memory runs low, and parsed again when opened.</p>
+<table class="details">
+<tr>
+<th> Trace</th>
+<th> Size</th>
+<th> Modified</th>
+<th> Go</th>
+<th> Duration</th>
+<th> GOMAXPROCS</th>
+<th> Events</th>
+<th> Parsed</th>
+</tr>
+{{range .Traces}}
+  <tr{{if eq .Name $.Current}} class="current"{{end}}>
+    <td><a href="/traces/open?name={{.Name}}">{{.Name}}</a></td>
+    <td>{{size .Size}}</td>
+    <td>{{.ModTime.Format "2006-01-02 15:04:05"}}</td>
+    <td>{{.Version}}</td>
+    {{if .Meta}}
+    <td>{{niceDuration .Meta.Duration}}</td>
+    <td>{{.Meta.GOMAXPROCS}}</td>
+    <td>{{.Meta.Events}}</td>
+    {{else}}
+    <td colspan="3">{{.Err}}</td>
+    {{end}}
+    <td>{{if loaded .}}yes{{end}}</td>
+  </tr>
+{{end}}
+</table>
+</body>
+</html>
+`))
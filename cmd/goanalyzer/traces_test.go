@@ -0,0 +1,180 @@
+// +build !js
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime/trace"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// traceDirWorker is a goroutine only the second trace has.
+func traceDirWorker(done chan bool) {
+	done <- true
+}
+
+func TestTraceDir(t *testing.T) {
+	defer func(st *traceState) { mainTrace = st }(mainTrace)
+
+	dir := t.TempDir()
+	record := func(f func()) []byte {
+		buf := new(bytes.Buffer)
+		if err := trace.Start(buf); err != nil {
+			t.Fatal(err)
+		}
+		f()
+		trace.Stop()
+		return buf.Bytes()
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.trace"), record(prog0), 0644); err != nil {
+		t.Fatal(err)
+	}
+	worker := record(func() {
+		done := make(chan bool)
+		go traceDirWorker(done)
+		<-done
+	})
+	if err := ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a trace"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newTraceDir(dir, 0) // evict every other trace
+	var cookie *http.Cookie
+	get := func(req *http.Request) *httptest.ResponseRecorder {
+		t.Helper()
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, req)
+		for _, c := range w.Result().Cookies() {
+			if c.Name == traceCookie {
+				cookie = c
+			}
+		}
+		return w
+	}
+
+	if w := get(httptest.NewRequest("GET", "/goroutines", nil)); w.Code != http.StatusFound {
+		t.Errorf("no trace selected: status %d, want a redirect to the index", w.Code)
+	}
+	w := get(httptest.NewRequest("GET", "/traces", nil))
+	if body := w.Body.String(); !strings.Contains(body, "a.trace") || strings.Contains(body, "notes.txt") {
+		t.Errorf("index does not list only the traces:\n%s", body)
+	}
+
+	get(httptest.NewRequest("GET", "/traces/open?name=a.trace", nil))
+	if w := get(httptest.NewRequest("GET", "/goroutines", nil)); !strings.Contains(w.Body.String(), "prog0") {
+		t.Errorf("goroutines of a.trace do not include prog0:\n%s", w.Body)
+	}
+
+	// Upload the second trace, which opens it.
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("trace", "a.trace")
+	fw.Write(worker)
+	mw.Close()
+	upload := body.Bytes()
+	req := httptest.NewRequest("POST", "/traces/upload", bytes.NewReader(upload))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if w := get(req); w.Code != http.StatusFound {
+		t.Fatalf("upload: status %d: %s", w.Code, w.Body)
+	}
+	if cookie.Value != "a-1.trace" {
+		t.Errorf("uploaded trace stored as %s, want a-1.trace", cookie.Value)
+	}
+	w = get(httptest.NewRequest("GET", "/goroutines", nil))
+	if body := w.Body.String(); !strings.Contains(body, "traceDirWorker") || strings.Contains(body, "prog0") {
+		t.Errorf("goroutines of the uploaded trace:\n%s", body)
+	}
+
+	d.mu.Lock()
+	a, b := d.traces["a.trace"], d.traces["a-1.trace"]
+	d.mu.Unlock()
+	if a.state != nil || b.state == nil {
+		t.Errorf("a.trace parsed %v, a-1.trace parsed %v, want only the current trace parsed", a.state != nil, b.state != nil)
+	}
+	if a.Meta == nil || a.Meta.Events == 0 || a.Meta.GOMAXPROCS == 0 || a.Version == "" {
+		t.Errorf("a.trace metadata %+v, version %q", a.Meta, a.Version)
+	}
+
+	// Back to the evicted trace, parsed again.
+	get(httptest.NewRequest("GET", "/traces/open?name=a.trace", nil))
+	if w := get(httptest.NewRequest("GET", "/goroutines", nil)); !strings.Contains(w.Body.String(), "prog0") {
+		t.Errorf("goroutines of a.trace after eviction do not include prog0:\n%s", w.Body)
+	}
+
+	// Requests for different traces are served concurrently, each with
+	// the state of its own trace.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for _, tc := range []struct{ name, want string }{{"a.trace", "prog0"}, {"a-1.trace", "traceDirWorker"}} {
+			wg.Add(1)
+			go func(name, want string) {
+				defer wg.Done()
+				req := httptest.NewRequest("GET", "/goroutines", nil)
+				req.AddCookie(&http.Cookie{Name: traceCookie, Value: name})
+				w := httptest.NewRecorder()
+				d.ServeHTTP(w, req)
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("goroutines of %s do not include %s:\n%s", name, want, w.Body)
+				}
+			}(tc.name, tc.want)
+		}
+	}
+	wg.Wait()
+
+	req = httptest.NewRequest("POST", "/traces/upload", strings.NewReader(""))
+	if w := get(req); w.Code != http.StatusBadRequest {
+		t.Errorf("upload without a file: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest("POST", "/traces/upload", bytes.NewReader(upload))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set("Origin", "http://elsewhere.example")
+	if w := get(req); w.Code != http.StatusForbidden {
+		t.Errorf("cross-origin upload: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	d.maxUpload = int64(len(worker)) / 2
+	req = httptest.NewRequest("POST", "/traces/upload", bytes.NewReader(upload))
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if w := get(req); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("upload of a trace over the limit: status %d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	for _, tc := range []struct {
+		site, origin string
+		want         bool
+	}{
+		{"", "", true},
+		{"same-origin", "", true},
+		{"none", "", true},
+		{"cross-site", "", false},
+		{"same-site", "http://example.com", false},
+		{"", "http://example.com", true},
+		{"", "http://example.com:8080", false},
+		{"", "http://elsewhere.example", false},
+		{"", "null", false},
+	} {
+		req := httptest.NewRequest("POST", "http://example.com/traces/upload", nil)
+		if tc.site != "" {
+			req.Header.Set("Sec-Fetch-Site", tc.site)
+		}
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		if got := sameOrigin(req); got != tc.want {
+			t.Errorf("sameOrigin with Sec-Fetch-Site %q, Origin %q = %v, want %v", tc.site, tc.origin, got, tc.want)
+		}
+	}
+}
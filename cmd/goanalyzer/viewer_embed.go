@@ -0,0 +1,24 @@
+//go:build !noembed
+// +build !noembed
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The trace viewer files are copied from
+// $GOROOT/src/internal/trace/traceviewer/static, see static/README.md.
+// Build with -tags noembed for a smaller binary serving them from GOROOT.
+//
+//go:embed static/trace_viewer_full.html static/webcomponents.min.js
+var embeddedTraceViewer embed.FS
+
+func init() {
+	sub, err := fs.Sub(embeddedTraceViewer, "static")
+	if err != nil {
+		panic(err)
+	}
+	traceViewerFS = sub
+}
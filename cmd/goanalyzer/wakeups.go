@@ -0,0 +1,404 @@
+// Wakeup graph between goroutine groups.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"html/template"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/wakeups", httpWakeups)
+}
+
+// wakeupEdgesDefault is the number of edges shown on the graph page.
+const wakeupEdgesDefault = 50
+
+// wakeupGraph is the aggregated unblock relation between goroutine
+// groups: an edge from group A to group B means that goroutines of A
+// unblocked goroutines of B. Durations are encoded in nanoseconds in
+// JSON.
+type wakeupGraph struct {
+	Nodes []*wakeupNode
+	Edges []*wakeupEdge // sorted by decreasing blocked time
+}
+
+// wakeupNode is a goroutine group, by start PC as in gtype, or a
+// source of unblocks that is not a goroutine, such as the network
+// poller.
+type wakeupNode struct {
+	ID   string
+	Name string
+	N    int // goroutines, 0 for sources that are not goroutines
+}
+
+type wakeupEdge struct {
+	From, To string // node IDs
+	Count    int    // unblocks
+	// Time the unblocked goroutines had been blocked for, as far as
+	// it is known from the trace, and the longest of these waits.
+	BlockedTime time.Duration
+	MaxBlocked  time.Duration
+}
+
+// buildWakeupGraph aggregates the unblock events of the window by the
+// groups of the unblocking and the unblocked goroutines. The groups
+// count the goroutines that existed during the window, and the blocked
+// time of a wakeup is counted from the start of the window at most.
+func buildWakeupGraph(events []*trace.Event, gs map[uint64]*trace.GDesc, w traceWindow) *wakeupGraph {
+	nodes := make(map[string]*wakeupNode)
+	node := func(g uint64, p int) *wakeupNode {
+		var id, name string
+		if d := gs[g]; g != 0 && d != nil {
+			id, name = fmt.Sprintf("g%d", d.PC), d.Name
+			if name == "" {
+				name = fmt.Sprint("PC:", d.PC)
+			}
+		} else {
+			switch p {
+			case trace.NetpollP:
+				id, name = "netpoll", "Network poller"
+			case trace.TimerP:
+				id, name = "timers", "Timers"
+			case trace.SyscallP:
+				id, name = "syscalls", "Syscalls"
+			default:
+				id, name = "runtime", "Runtime"
+			}
+		}
+		n := nodes[id]
+		if n == nil {
+			n = &wakeupNode{ID: id, Name: name}
+			nodes[id] = n
+		}
+		return n
+	}
+	for _, g := range gs {
+		if g.CreationTime > w.to || g.EndTime != 0 && g.EndTime < w.from {
+			continue
+		}
+		node(g.ID, 0).N++
+	}
+
+	type key struct{ from, to string }
+	edges := make(map[key]*wakeupEdge)
+	blockTs := make(map[uint64]int64) // by goroutine, while blocked
+	for _, ev := range events {
+		if ev.Ts > w.to {
+			break
+		}
+		switch ev.Type {
+		case trace.EvGoBlockSend, trace.EvGoBlockRecv, trace.EvGoBlockSelect, trace.EvGoBlockSync,
+			trace.EvGoBlockCond, trace.EvGoBlockNet, trace.EvGoBlockGC, trace.EvGoSleep, trace.EvGoBlock, trace.EvGoWaiting:
+			blockTs[ev.G] = ev.Ts
+		case trace.EvGoUnblock:
+			target := ev.Args[0]
+			if ev.Ts < w.from {
+				delete(blockTs, target)
+				break
+			}
+			k := key{node(ev.G, ev.P).ID, node(target, 0).ID}
+			e := edges[k]
+			if e == nil {
+				e = &wakeupEdge{From: k.from, To: k.to}
+				edges[k] = e
+			}
+			e.Count++
+			if ts, ok := blockTs[target]; ok {
+				if ts < w.from {
+					ts = w.from
+				}
+				d := time.Duration(ev.Ts - ts)
+				e.BlockedTime += d
+				if d > e.MaxBlocked {
+					e.MaxBlocked = d
+				}
+				delete(blockTs, target)
+			}
+		}
+	}
+
+	g := &wakeupGraph{}
+	for _, e := range edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		ei, ej := g.Edges[i], g.Edges[j]
+		if ei.BlockedTime != ej.BlockedTime {
+			return ei.BlockedTime > ej.BlockedTime
+		}
+		if ei.Count != ej.Count {
+			return ei.Count > ej.Count
+		}
+		if ei.From != ej.From {
+			return ei.From < ej.From
+		}
+		return ei.To < ej.To
+	})
+	// Only the nodes with edges.
+	for _, e := range g.Edges {
+		for _, id := range []string{e.From, e.To} {
+			if n := nodes[id]; n != nil {
+				g.Nodes = append(g.Nodes, n)
+				delete(nodes, id)
+			}
+		}
+	}
+	return g
+}
+
+// top returns the graph of the n edges with the most blocked time.
+func (g *wakeupGraph) top(n int) *wakeupGraph {
+	if n <= 0 || n >= len(g.Edges) {
+		return g
+	}
+	t := &wakeupGraph{Edges: g.Edges[:n]}
+	used := make(map[string]bool)
+	for _, e := range t.Edges {
+		used[e.From], used[e.To] = true, true
+	}
+	for _, node := range g.Nodes {
+		if used[node.ID] {
+			t.Nodes = append(t.Nodes, node)
+		}
+	}
+	return t
+}
+
+// writeDOT writes the graph in Graphviz's DOT language, with edges
+// labeled by count and blocked time.
+func (g *wakeupGraph) writeDOT(w io.Writer) error {
+	fmt.Fprintf(w, "digraph wakeups {\n")
+	fmt.Fprintf(w, "\tnode [shape=box];\n")
+	for _, n := range g.Nodes {
+		label := n.Name
+		if n.N > 0 {
+			label += fmt.Sprintf("\n%d goroutines", n.N)
+		}
+		fmt.Fprintf(w, "\t%s [label=%s];\n", strconv.Quote(n.ID), strconv.Quote(label))
+	}
+	var max time.Duration
+	for _, e := range g.Edges {
+		if e.BlockedTime > max {
+			max = e.BlockedTime
+		}
+	}
+	for _, e := range g.Edges {
+		width := 1.0
+		if max > 0 {
+			width += 4 * float64(e.BlockedTime) / float64(max)
+		}
+		label := fmt.Sprintf("%d×, %s blocked", e.Count, niceDuration(e.BlockedTime))
+		fmt.Fprintf(w, "\t%s -> %s [label=%s, penwidth=%.1f];\n", strconv.Quote(e.From), strconv.Quote(e.To), strconv.Quote(label), width)
+	}
+	_, err := fmt.Fprintf(w, "}\n")
+	return err
+}
+
+// httpWakeups serves the wakeup graph as a page, or with format=dot or
+// format=json for download. The n parameter limits the page to the
+// edges with the most blocked time.
+func httpWakeups(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	win, err := parseWindow(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := st.parseEvents()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	st.analyzeGoroutines(events)
+	g := buildWakeupGraph(events, st.gs, win)
+
+	switch format := r.FormValue("format"); format {
+	case "dot":
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		g.writeDOT(w)
+		return
+	case "json":
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(g)
+		return
+	case "":
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	n := wakeupEdgesDefault
+	if s := r.FormValue("n"); s != "" {
+		n, err = strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to parse n parameter '%v': %v", s, err), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "text/html;charset=utf-8")
+	err = templWakeups.Execute(w, struct {
+		Edges int
+		Graph *wakeupGraph
+	}{
+		Edges: len(g.Edges),
+		Graph: g.top(n),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to execute template: %v", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+var templWakeups = template.Must(template.New("").Funcs(template.FuncMap{
+	"niceDuration": niceDuration,
+}).Parse(`
+<!DOCTYPE html>
+<title>Goroutine wakeup graph</title>
+<style>
+body {
+  font-family: sans-serif;
+}
+th {
+  background-color: #050505;
+  color: #fff;
+}
+table {
+  border-collapse: collapse;
+}
+td {
+  border: 1px solid black;
+  padding: 0.2em 0.5em;
+  text-align: right;
+}
+td.name {
+  text-align: left;
+}
+tr.dim {
+  color: #bbb;
+}
+#graph text {
+  font-size: 11px;
+  cursor: pointer;
+}
+#graph path {
+  fill: none;
+  stroke: #d01c8b;
+  stroke-opacity: 0.6;
+}
+#graph .dim {
+  opacity: 0.1;
+}
+</style>
+<body>
+<h2>Goroutine wakeup graph</h2>
+<p>An arrow from group A to group B means goroutines of A unblocked goroutines of B,
+on a channel, mutex, select, condition variable or otherwise. The arrow width shows how
+long the goroutines of B had been blocked. Hover over a group to show only its arrows.
+Showing {{len .Graph.Edges}} of {{.Edges}} edges.
+Download as <a href="/wakeups?format=dot" download="wakeups.dot">DOT</a> or
+<a href="/wakeups?format=json" download="wakeups.json">JSON</a>.</p>
+<svg id="graph" width="900" height="900"></svg>
+<table id="edges">
+<tr><th>From</th><th>To</th><th>Unblocks</th><th>Blocked time</th><th>Longest block</th></tr>
+{{range .Graph.Edges}}
+<tr data-from="{{.From}}" data-to="{{.To}}">
+<td class="name" data-id="{{.From}}"></td>
+<td class="name" data-id="{{.To}}"></td>
+<td>{{.Count}}</td>
+<td>{{niceDuration .BlockedTime}}</td>
+<td>{{niceDuration .MaxBlocked}}</td>
+</tr>
+{{end}}
+</table>
+<script>
+'use strict';
+` + windowScript + `
+var graph = {{.Graph}};
+var svgNS = 'http://www.w3.org/2000/svg';
+
+function el(name, attrs, parent) {
+  var e = document.createElementNS(svgNS, name);
+  for (var k in attrs) e.setAttribute(k, attrs[k]);
+  parent.appendChild(e);
+  return e;
+}
+
+function duration(ns) {
+  if (ns < 1e4) return ns + 'ns';
+  if (ns < 1e7) return (ns / 1e3).toFixed(1) + 'µs';
+  if (ns < 1e10) return (ns / 1e6).toFixed(1) + 'ms';
+  return (ns / 1e9).toFixed(1) + 's';
+}
+
+(function() {
+  var svg = document.getElementById('graph');
+  var nodes = graph.Nodes || [], edges = graph.Edges || [];
+  var cx = 450, cy = 450, r = 300;
+  var pos = {}, names = {};
+  var defs = el('defs', {}, svg);
+  var marker = el('marker', {id: 'arrow', viewBox: '0 0 10 10', refX: 10, refY: 5, markerWidth: 6, markerHeight: 6, orient: 'auto'}, defs);
+  el('path', {d: 'M0,0 L10,5 L0,10 z', style: 'fill: #d01c8b; stroke: none'}, marker);
+
+  // Groups on a circle, in the order of their heaviest edge.
+  nodes.forEach(function(n, i) {
+    var a = 2 * Math.PI * i / nodes.length;
+    pos[n.ID] = {x: cx + r * Math.cos(a), y: cy + r * Math.sin(a), a: a};
+    names[n.ID] = n.Name + (n.N ? ' (' + n.N + ')' : '');
+  });
+  var max = 0;
+  edges.forEach(function(e) { max = Math.max(max, e.BlockedTime); });
+
+  var edgeEls = [];
+  edges.forEach(function(e) {
+    var p = pos[e.From], q = pos[e.To];
+    var d;
+    if (e.From === e.To) {
+      // A loop outside the circle.
+      var ox = 40 * Math.cos(p.a), oy = 40 * Math.sin(p.a);
+      d = 'M' + p.x + ',' + p.y + ' C' + (p.x + ox - oy) + ',' + (p.y + oy + ox) + ' ' + (p.x + ox + oy) + ',' + (p.y + oy - ox) + ' ' + p.x + ',' + p.y;
+    } else {
+      // Curve towards the center, so opposite edges do not overlap.
+      var mx = (p.x + q.x) / 2, my = (p.y + q.y) / 2;
+      d = 'M' + p.x + ',' + p.y + ' Q' + (mx + (cx - mx) * 0.3 + (q.y - p.y) * 0.1) + ',' + (my + (cy - my) * 0.3 - (q.x - p.x) * 0.1) + ' ' + q.x + ',' + q.y;
+    }
+    var path = el('path', {d: d, 'marker-end': 'url(#arrow)', 'stroke-width': 1 + (max ? 7 * e.BlockedTime / max : 0)}, svg);
+    el('title', {}, path).textContent = names[e.From] + ' → ' + names[e.To] + '\n' + e.Count + ' unblocks, ' + duration(e.BlockedTime) + ' blocked';
+    edgeEls.push({e: e, el: path});
+  });
+
+  var rows = document.querySelectorAll('#edges tr[data-from]');
+  function highlight(id) {
+    edgeEls.forEach(function(x) {
+      x.el.classList.toggle('dim', id !== null && x.e.From !== id && x.e.To !== id);
+    });
+    rows.forEach(function(row) {
+      row.classList.toggle('dim', id !== null && row.dataset.from !== id && row.dataset.to !== id);
+    });
+  }
+  nodes.forEach(function(n) {
+    var p = pos[n.ID];
+    el('circle', {cx: p.x, cy: p.y, r: 4, fill: '#2c7bb6'}, svg);
+    var left = Math.cos(p.a) < 0;
+    var t = el('text', {x: p.x + (left ? -8 : 8), y: p.y + 4, 'text-anchor': left ? 'end' : 'start'}, svg);
+    t.textContent = names[n.ID];
+    t.onmouseover = function() { highlight(n.ID); };
+    t.onmouseout = function() { highlight(null); };
+  });
+  document.querySelectorAll('#edges td[data-id]').forEach(function(td) {
+    td.textContent = names[td.dataset.id];
+  });
+})();
+</script>
+</body>
+</html>
+`))
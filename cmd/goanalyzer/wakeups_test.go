@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"strings"
+	"testing"
+)
+
+func TestWakeupGraph(t *testing.T) {
+	gs := map[uint64]*trace.GDesc{
+		1: {ID: 1, Name: "main.producer", PC: 100},
+		2: {ID: 2, Name: "main.consumer", PC: 200},
+		3: {ID: 3, Name: "main.consumer", PC: 200},
+		4: {ID: 4, Name: "main.server", PC: 300},
+	}
+	ev := func(ts int64, typ byte, g uint64, p int, args ...uint64) *trace.Event {
+		e := &trace.Event{Ts: ts, Type: typ, G: g, P: p}
+		copy(e.Args[:], args)
+		return e
+	}
+	events := []*trace.Event{
+		ev(0, trace.EvGoWaiting, 4, 0),
+		ev(10, trace.EvGoBlockRecv, 2, 0),
+		ev(15, trace.EvGoBlockRecv, 3, 1),
+		ev(20, trace.EvGoUnblock, 1, 0, 2),
+		ev(35, trace.EvGoUnblock, 1, 0, 3),
+		ev(40, trace.EvGoUnblock, 0, trace.NetpollP, 4),
+		ev(50, trace.EvGoBlockSend, 1, 0),
+		ev(80, trace.EvGoUnblock, 2, 1, 1),
+	}
+	g := buildWakeupGraph(events, gs, wholeTrace)
+	var edges []string
+	for _, e := range g.Edges {
+		edges = append(edges, fmt.Sprintf("%s->%s/%d/%d/%d", e.From, e.To, e.Count, e.BlockedTime, e.MaxBlocked))
+	}
+	want := "[netpoll->g300/1/40/40 g100->g200/2/30/20 g200->g100/1/30/30]"
+	if got := fmt.Sprint(edges); got != want {
+		t.Errorf("edges\n%s\nwant\n%s", got, want)
+	}
+	var nodes []string
+	for _, n := range g.Nodes {
+		nodes = append(nodes, fmt.Sprintf("%s:%s/%d", n.ID, n.Name, n.N))
+	}
+	if got, want := fmt.Sprint(nodes), "[netpoll:Network poller/0 g300:main.server/1 g100:main.producer/1 g200:main.consumer/2]"; got != want {
+		t.Errorf("nodes\n%s\nwant\n%s", got, want)
+	}
+
+	if top := g.top(1); len(top.Edges) != 1 || len(top.Nodes) != 2 {
+		t.Errorf("top edge graph has %d edges and %d nodes, want 1 and 2", len(top.Edges), len(top.Nodes))
+	}
+	var b strings.Builder
+	if err := g.writeDOT(&b); err != nil {
+		t.Fatal(err)
+	}
+	if dot := b.String(); !strings.Contains(dot, `"g100" -> "g200" [label="2×, 30ns blocked"`) {
+		t.Errorf("DOT output lacks the producer to consumer edge:\n%s", dot)
+	}
+
+	// In [18, 45], the waits are counted from 18 and the last wakeup
+	// is left out.
+	edges = nil
+	for _, e := range buildWakeupGraph(events, gs, traceWindow{18, 45}).Edges {
+		edges = append(edges, fmt.Sprintf("%s->%s/%d/%d/%d", e.From, e.To, e.Count, e.BlockedTime, e.MaxBlocked))
+	}
+	want = "[netpoll->g300/1/22/22 g100->g200/2/19/17]"
+	if got := fmt.Sprint(edges); got != want {
+		t.Errorf("edges of the window\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+// Time windows of the analyses.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func init() {
+	http.HandleFunc("/timeline", httpTimeline)
+}
+
+// traceWindow is the part of the trace an analysis is restricted to, the
+// timestamps in [from, to].
+type traceWindow struct {
+	from, to int64
+}
+
+// wholeTrace is the window of the analyses without from and to
+// parameters.
+var wholeTrace = traceWindow{math.MinInt64, math.MaxInt64}
+
+// parseWindow returns the window of the from and to parameters of r.
+// Either may be a timestamp in nanoseconds, as in the trace viewer and
+// the JSON API, or a duration, which is an offset from the start of
+// the trace, or from its end if negative: from=-10s analyzes the last
+// 10 seconds.
+func parseWindow(r *http.Request) (traceWindow, error) {
+	st := requestTrace(r)
+	w := wholeTrace
+	for _, p := range []struct {
+		name string
+		ts   *int64
+	}{{"from", &w.from}, {"to", &w.to}} {
+		s := r.FormValue(p.name)
+		if s == "" {
+			continue
+		}
+		ts, err := st.parseTimestamp(s)
+		if err != nil {
+			return w, fmt.Errorf("invalid %s parameter %q", p.name, s)
+		}
+		*p.ts = ts
+	}
+	if w.to < w.from {
+		return w, fmt.Errorf("empty window: from %d is after to %d", w.from, w.to)
+	}
+	return w, nil
+}
+
+// parseTimestamp parses a bound of a window.
+func (st *traceState) parseTimestamp(s string) (int64, error) {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		if ts < 0 {
+			return 0, fmt.Errorf("negative timestamp %d", ts)
+		}
+		return ts, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return st.lastTimestamp() + int64(d), nil
+	}
+	return st.firstTimestamp() + int64(d), nil
+}
+
+// formatWindow returns w as offsets from the start of the trace.
+func (st *traceState) formatWindow(w traceWindow) string {
+	bound := func(ts int64, open string) string {
+		if ts == math.MinInt64 || ts == math.MaxInt64 {
+			return open
+		}
+		return time.Duration(ts - st.firstTimestamp()).String()
+	}
+	return fmt.Sprintf("[%s, %s]", bound(w.from, "start"), bound(w.to, "end"))
+}
+
+// contains reports whether ts is in the window.
+func (w traceWindow) contains(ts int64) bool {
+	return w.from <= ts && ts <= w.to
+}
+
+// clip returns the intervals of gToIntervals during the window. If
+// gToIntervals is nil, which stands for the whole lifetime of every
+// goroutine, it returns the window for every goroutine of gs.
+func (w traceWindow) clip(gToIntervals map[uint64][]interval, gs map[uint64]*trace.GDesc) map[uint64][]interval {
+	if w == wholeTrace {
+		return gToIntervals
+	}
+	res := make(map[uint64][]interval)
+	if gToIntervals == nil {
+		for id := range gs {
+			res[id] = []interval{{begin: w.from, end: w.to}}
+		}
+		return res
+	}
+	for g, intervals := range gToIntervals {
+		for _, i := range intervals {
+			if i.begin < w.from {
+				i.begin = w.from
+			}
+			if i.end > w.to {
+				i.end = w.to
+			}
+			if i.begin < i.end {
+				res[g] = append(res[g], i)
+			}
+		}
+	}
+	return res
+}
+
+// goroutineStats returns the statistics of the goroutines during the
+// window: those of gs for the whole trace. Those of a window are
+// computed from the events up to its end only, read with loader.scan.
+func (st *traceState) goroutineStats(w traceWindow) (map[uint64]*trace.GDesc, error) {
+	if w == wholeTrace {
+		events, err := st.parseEvents()
+		if err != nil {
+			return nil, err
+		}
+		st.analyzeGoroutines(events)
+		return st.gs, nil
+	}
+	b := trace.NewGoroutineStatsWindowBuilder(w.from, w.to)
+	if err := st.loader.scan(w.to, b.Add); err != nil {
+		return nil, err
+	}
+	res := b.Finish()
+	if st.loader.index != nil {
+		// The goroutines may start or end after the events read.
+		for _, ig := range st.loader.index.meta.Goroutines {
+			if g := res[ig.G.ID]; g != nil {
+				g.PC, g.Name, g.StartTime, g.EndTime = ig.G.PC, ig.G.Name, ig.G.StartTime, ig.G.EndTime
+			}
+		}
+	}
+	return res, nil
+}
+
+// profileGoroutines returns the goroutine statistics of the whole trace
+// the goroutines of the profiles are selected with. With an index, they
+// are read without the events.
+func (st *traceState) profileGoroutines() (map[uint64]*trace.GDesc, error) {
+	if st.loader.index != nil {
+		return st.loader.index.goroutines(), nil
+	}
+	events, err := st.parseEvents()
+	if err != nil {
+		return nil, err
+	}
+	st.analyzeGoroutines(events)
+	return st.gs, nil
+}
+
+// timeline is the activity of the trace the window of the analyses is
+// selected on.
+type timeline struct {
+	Start, End int64 // timestamps of the first and last events
+	// Averages over equal intervals of the trace of the number of
+	// running and runnable goroutines.
+	Running, Runnable []float64
+}
+
+// newTimeline returns the timeline of events.
+func (st *traceState) newTimeline(events []*trace.Event) timeline {
+	if len(events) == 0 {
+		return timeline{}
+	}
+	s := st.computeProcs(events, wholeTrace)
+	return timeline{Start: events[0].Ts, End: events[len(events)-1].Ts, Running: s.Running, Runnable: s.Runnable}
+}
+
+// httpTimeline serves the timeline of the main page as JSON. The index
+// of the trace has it, so the events are not read for it.
+func httpTimeline(w http.ResponseWriter, r *http.Request) {
+	st := requestTrace(r)
+	var t timeline
+	if st.loader.index != nil {
+		t = st.loader.index.meta.Timeline
+	} else {
+		events, err := st.parseEvents()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		t = st.newTimeline(events)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(t); err != nil {
+		log.Printf("failed to serialize timeline: %v", err)
+	}
+}
+
+// windowScript defines windowQuery(), which returns the from and to
+// parameters of the page to append to a query, and adds them to the
+// links of the page to the analyses that honor them, so that they keep
+// its window.
+const windowScript = `
+var windowedPaths = [
+  '/goroutines', '/goroutine', '/io', '/block', '/syscall', '/sched', '/assist',
+  '/regionio', '/regionblock', '/regionsyscall', '/regionsched',
+  '/usertasks', '/usertask', '/userregions', '/userregion', '/mmu', '/gc', '/procs', '/leaks',
+  '/contention', '/wakeups', '/api/v1/',
+];
+function isWindowed(path) {
+  return windowedPaths.some(function(p) {
+    return p.endsWith('/') ? path.startsWith(p) : path == p;
+  });
+}
+function windowQuery() {
+  var params = new URLSearchParams(window.location.search), q = '';
+  ['from', 'to'].forEach(function(k) {
+    if (params.get(k)) q += '&' + k + '=' + encodeURIComponent(params.get(k));
+  });
+  return q;
+}
+document.addEventListener('DOMContentLoaded', function() {
+  var q = windowQuery();
+  if (q == '') return;
+  var p = document.createElement('p');
+  p.textContent = 'Time window: ' + q.substring(1).replace('&', ', ') + ' (';
+  var all = document.createElement('a');
+  var params = new URLSearchParams(window.location.search);
+  params.delete('from');
+  params.delete('to');
+  all.href = window.location.pathname + '?' + params.toString();
+  all.textContent = 'whole trace';
+  p.appendChild(all);
+  p.appendChild(document.createTextNode(')'));
+  document.body.insertBefore(p, document.body.firstChild);
+  document.querySelectorAll('a[href^="/"]').forEach(function(a) {
+    var u = new URL(a.href);
+    if (a === all || !isWindowed(u.pathname) || u.searchParams.has('from') || u.searchParams.has('to')) return;
+    new URLSearchParams(q).forEach(function(v, k) { u.searchParams.set(k, v); });
+    a.href = u.toString();
+  });
+});
+`
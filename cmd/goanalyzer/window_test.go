@@ -0,0 +1,169 @@
+// +build !js
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/robaho/goanalyzer/cmd/goanalyzer/internal/trace"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func windowWarmup(wg *sync.WaitGroup) { spin(20*time.Millisecond, wg) }
+
+func windowSteady(wg *sync.WaitGroup) { spin(20*time.Millisecond, wg) }
+
+func TestParseWindow(t *testing.T) {
+	prog := func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go spin(10*time.Millisecond, &wg)
+		wg.Wait()
+	}
+	if err := traceProgram(t, prog, "TestParseWindow"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	first, last := mainTrace.firstTimestamp(), mainTrace.lastTimestamp()
+	for _, tc := range []struct {
+		query string
+		want  traceWindow
+	}{
+		{"", wholeTrace},
+		{"from=1000&to=2000", traceWindow{1000, 2000}},
+		{"from=1ms", traceWindow{first + 1e6, wholeTrace.to}},
+		{"from=-1ms&to=-0.5ms", traceWindow{last - 1e6, last - 5e5}},
+		{"to=2ms", traceWindow{wholeTrace.from, first + 2e6}},
+	} {
+		w, err := parseWindow(httptest.NewRequest("GET", "/goroutines?"+tc.query, nil))
+		if err != nil || w != tc.want {
+			t.Errorf("parseWindow(%q) = %v, %v, want %v", tc.query, w, err, tc.want)
+		}
+	}
+	for _, query := range []string{"from=x", "to=-5", "from=2000&to=1000"} {
+		if w, err := parseWindow(httptest.NewRequest("GET", "/goroutines?"+query, nil)); err == nil {
+			t.Errorf("parseWindow(%q) = %v, want an error", query, w)
+		}
+	}
+}
+
+func TestWindowClip(t *testing.T) {
+	gs := map[uint64]*trace.GDesc{1: {ID: 1}, 2: {ID: 2}}
+	w := traceWindow{100, 200}
+	if got, want := w.clip(nil, gs), map[uint64][]interval{1: {{100, 200}}, 2: {{100, 200}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clip of the whole goroutines = %v, want %v", got, want)
+	}
+	gToIntervals := map[uint64][]interval{
+		1: {{50, 80}, {90, 150}, {180, 250}},
+		2: {{300, 400}},
+	}
+	if got, want := w.clip(gToIntervals, gs), map[uint64][]interval{1: {{100, 150}, {180, 200}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("clip = %v, want %v", got, want)
+	}
+	if got := wholeTrace.clip(gToIntervals, gs); !reflect.DeepEqual(got, gToIntervals) {
+		t.Errorf("clip to the whole trace = %v, want the intervals unchanged", got)
+	}
+}
+
+func TestClipMutatorUtil(t *testing.T) {
+	u := func(ts int64, util float64) trace.MutatorUtil { return trace.MutatorUtil{Time: ts, Util: util} }
+	util := [][]trace.MutatorUtil{
+		{u(0, 1), u(100, 0), u(150, 1), u(300, 1)},
+		{u(250, 0.5), u(300, 0.5)},
+	}
+	got := clipMutatorUtil(util, traceWindow{120, 200})
+	want := [][]trace.MutatorUtil{{u(120, 0), u(150, 1), u(200, 1)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("clipMutatorUtil = %v, want %v", got, want)
+	}
+	if mmu := trace.NewMMUCurve(got).MMU(50); mmu != 0.4 {
+		t.Errorf("MMU of 50ns in the window = %v, want 0.4", mmu)
+	}
+}
+
+func TestWindowedAnalyses(t *testing.T) {
+	prog := func() {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go windowWarmup(&wg)
+		wg.Wait()
+		wg.Add(1)
+		go windowSteady(&wg)
+		wg.Wait()
+	}
+	if err := traceProgram(t, prog, "TestWindowedAnalyses"); err != nil {
+		t.Fatalf("failed to trace the program: %v", err)
+	}
+	whole, err := mainTrace.goroutineStats(wholeTrace)
+	if err != nil {
+		t.Fatalf("failed to compute goroutine statistics: %v", err)
+	}
+	var steady *trace.GDesc
+	for _, g := range whole {
+		if strings.HasSuffix(g.Name, ".windowSteady") {
+			steady = g
+		}
+	}
+	if steady == nil {
+		t.Fatalf("no windowSteady goroutine")
+	}
+
+	// The window of the steady state leaves the warmup out.
+	win := traceWindow{steady.StartTime, mainTrace.lastTimestamp()}
+	gs, err := mainTrace.goroutineStats(win)
+	if err != nil {
+		t.Fatalf("failed to compute goroutine statistics of the window: %v", err)
+	}
+	for _, g := range gs {
+		if strings.HasSuffix(g.Name, ".windowWarmup") {
+			t.Errorf("warmup goroutine %d in the window", g.ID)
+		}
+	}
+	if g := gs[steady.ID]; g == nil || g.ExecTime.Total != steady.ExecTime.Total {
+		t.Errorf("steady goroutine in the window: %+v, want the execution time of the whole trace, %d", g, steady.ExecTime.Total)
+	}
+
+	query := fmt.Sprintf("from=%d&to=%d", win.from, win.to)
+	for _, tc := range []struct {
+		url    string
+		status int
+	}{
+		{"/goroutines?" + query, http.StatusOK},
+		{"/goroutines?from=x", http.StatusBadRequest},
+		{"/usertasks?" + query, http.StatusOK},
+		{"/userregions?to=-1ms", http.StatusOK},
+		{"/mmuPlot?flags=mut|stw&" + query, http.StatusOK},
+		{"/gc?" + query, http.StatusOK},
+		{"/procs?" + query, http.StatusOK},
+		{"/procs?from=x", http.StatusBadRequest},
+		{"/leaks?" + query, http.StatusOK},
+		{"/contention?" + query, http.StatusOK},
+		{"/wakeups?" + query, http.StatusOK},
+		{"/wakeups?format=json&" + query, http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		http.DefaultServeMux.ServeHTTP(w, httptest.NewRequest("GET", tc.url, nil))
+		if w.Code != tc.status {
+			t.Errorf("%s: status %d, want %d: %s", tc.url, w.Code, tc.status, w.Body)
+		} else if tc.status == http.StatusOK && strings.Contains(w.Body.String(), "windowWarmup") {
+			t.Errorf("%s shows the warmup goroutine", tc.url)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	httpTimeline(w, httptest.NewRequest("GET", "/timeline", nil))
+	var tl timeline
+	if err := json.NewDecoder(w.Body).Decode(&tl); err != nil || len(tl.Running) != procSeriesBuckets || tl.End <= tl.Start {
+		t.Errorf("timeline: %+v, %v, want %d running averages", tl, err, procSeriesBuckets)
+	}
+	w = httptest.NewRecorder()
+	httpMain(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), `<svg id="timeline"`) {
+		t.Errorf("main page without the timeline")
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/robaho/goanalyzer
+
+go 1.25.0
+
+require (
+	github.com/google/pprof v0.0.0-20260115054156-294ebfa9ad83
+	golang.org/x/exp v0.0.0-20260611194520-c48552f49976
+)